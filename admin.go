@@ -0,0 +1,326 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	ErrUnknownModule = errors.New("unknown module")
+	ErrUnknownLimit  = errors.New("unknown limit")
+)
+
+type Limit struct {
+	Name  string
+	Min   int64
+	Max   int64
+	value atomic.Int64
+}
+
+func (l *Limit) Load() int64 {
+	return l.value.Load()
+}
+
+func (l *Limit) Store(value int64) error {
+	switch {
+	case value < l.Min:
+		return fmt.Errorf("%s must be at least %d", l.Name, l.Min)
+	case l.Max > 0 && value > l.Max:
+		return fmt.Errorf("%s must be no greater than %d", l.Name, l.Max)
+	}
+
+	l.value.Store(value)
+
+	return nil
+}
+
+var (
+	batchItemLimit = &Limit{Name: "max-batch-items", Min: 1}
+	diceRollLimit  = &Limit{Name: "max-dice-rolls", Min: 1}
+	diceSideLimit  = &Limit{Name: "max-dice-sides", Min: 1}
+	dohRateLimit   = &Limit{Name: "doh-rate", Min: 1}
+	qrSizeLimit    = &Limit{Name: "qr-size", Min: 256, Max: 2048}
+	streamLimit    = &Limit{Name: "max-streams", Min: 1}
+
+	limits = []*Limit{
+		batchItemLimit,
+		diceRollLimit,
+		diceSideLimit,
+		dohRateLimit,
+		qrSizeLimit,
+		streamLimit,
+	}
+)
+
+func initializeLimits() error {
+	for limit, value := range map[*Limit]int{
+		batchItemLimit: maxBatchItems,
+		diceRollLimit:  maxDiceRolls,
+		diceSideLimit:  maxDiceSides,
+		dohRateLimit:   dohRate,
+		qrSizeLimit:    qrSize,
+		streamLimit:    maxStreams,
+	} {
+		err := limit.Store(int64(value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type Modules struct {
+	states sync.Map
+}
+
+func (m *Modules) Register(module string, enabled bool) {
+	state := &atomic.Bool{}
+
+	state.Store(enabled)
+
+	m.states.Store(module, state)
+}
+
+func (m *Modules) Has(module string) bool {
+	_, ok := m.states.Load(module)
+
+	return ok
+}
+
+func (m *Modules) Enabled(module string) bool {
+	state, ok := m.states.Load(module)
+	if !ok {
+		return true
+	}
+
+	return state.(*atomic.Bool).Load()
+}
+
+func (m *Modules) Set(module string, enabled bool) error {
+	state, ok := m.states.Load(module)
+	if !ok {
+		return ErrUnknownModule
+	}
+
+	state.(*atomic.Bool).Store(enabled)
+
+	return nil
+}
+
+func (m *Modules) List() []string {
+	var list []string
+
+	m.states.Range(func(key, value any) bool {
+		status := "disabled"
+		if value.(*atomic.Bool).Load() {
+			status = "enabled"
+		}
+
+		list = append(list, fmt.Sprintf("%s: %s", key, status))
+
+		return true
+	})
+
+	slices.Sort(list)
+
+	return list
+}
+
+// moduleAliases maps path prefixes served by a module other than the one
+// sharing their name, such as the DNS-over-HTTPS endpoint, which is fixed
+// by RFC 8484.
+var moduleAliases = map[string]string{
+	"dns-query": "dns",
+}
+
+// Gate rejects requests for modules that have been disabled at runtime.
+// Every module serves its routes under a path prefix matching its name, or
+// one listed in moduleAliases.
+func (m *Modules) Gate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		module, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+		if alias, ok := moduleAliases[module]; ok {
+			module = alias
+		}
+
+		if !m.Enabled(module) {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			securityHeaders(w)
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			w.Write([]byte(localizer(r).Sprintf("Module disabled") + "\n"))
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+func serveAdmin(handler func(*http.Request, httprouter.Params) (int, string, error), errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		w.Header().Set("Cache-Control", "no-store")
+
+		securityHeaders(w)
+
+		if !authorized(r) {
+			if verbose {
+				fmt.Printf("%s | %s => %s (Unauthorized)\n",
+					startTime.Format(timeFormats["RFC3339"]),
+					realIP(r, true),
+					r.RequestURI)
+			}
+
+			w.Header().Set("WWW-Authenticate", "Bearer")
+
+			w.WriteHeader(http.StatusUnauthorized)
+
+			_, err := w.Write([]byte("Unauthorized\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		status, output, err := handler(r, p)
+		if err != nil {
+			w.WriteHeader(status)
+
+			_, err = w.Write([]byte(err.Error() + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+
+		_, err = w.Write([]byte(output))
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		}
+	}
+}
+
+func listModules(modules *Modules) func(*http.Request, httprouter.Params) (int, string, error) {
+	return func(r *http.Request, p httprouter.Params) (int, string, error) {
+		return http.StatusOK, strings.Join(modules.List(), "\n") + "\n", nil
+	}
+}
+
+func setModule(modules *Modules, enabled bool) func(*http.Request, httprouter.Params) (int, string, error) {
+	return func(r *http.Request, p httprouter.Params) (int, string, error) {
+		module := p.ByName("module")
+
+		err := modules.Set(module, enabled)
+		if err != nil {
+			return http.StatusNotFound, "", fmt.Errorf("%w %q", err, module)
+		}
+
+		return http.StatusOK, strings.Join(modules.List(), "\n") + "\n", nil
+	}
+}
+
+func listLimits(r *http.Request, p httprouter.Params) (int, string, error) {
+	var output strings.Builder
+
+	for _, limit := range limits {
+		output.WriteString(fmt.Sprintf("%s: %d\n", limit.Name, limit.Load()))
+	}
+
+	return http.StatusOK, output.String(), nil
+}
+
+func setLimit(r *http.Request, p httprouter.Params) (int, string, error) {
+	name := p.ByName("limit")
+
+	i := slices.IndexFunc(limits, func(l *Limit) bool {
+		return l.Name == name
+	})
+	if i == -1 {
+		return http.StatusNotFound, "", fmt.Errorf("%w %q", ErrUnknownLimit, name)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+	if err != nil {
+		return http.StatusBadRequest, "", err
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, "", fmt.Errorf("invalid value for %s", name)
+	}
+
+	err = limits[i].Store(value)
+	if err != nil {
+		return http.StatusBadRequest, "", err
+	}
+
+	return listLimits(r, p)
+}
+
+func reloadOUIDatabase(r *http.Request, p httprouter.Params) (int, string, error) {
+	startTime := time.Now()
+
+	count, err := reloadOUIs()
+	if err != nil {
+		return http.StatusInternalServerError, "", fmt.Errorf("failed to reload OUI database: %w", err)
+	}
+
+	return http.StatusOK, fmt.Sprintf("Loaded %d OUI entries in %dms\n", count, time.Since(startTime).Milliseconds()), nil
+}
+
+func showASNClient(r *http.Request, p httprouter.Params) (int, string, error) {
+	return http.StatusOK, cymruBulk.Stats().String(), nil
+}
+
+func registerAdmin(mux *httprouter.Router, modules *Modules, errorChannel chan<- Error) {
+	mux.GET("/admin/modules", serveAdmin(listModules(modules), errorChannel))
+	mux.POST("/admin/modules/:module/enable", serveAdmin(setModule(modules, true), errorChannel))
+	mux.POST("/admin/modules/:module/disable", serveAdmin(setModule(modules, false), errorChannel))
+
+	mux.GET("/admin/limits", serveAdmin(listLimits, errorChannel))
+	mux.POST("/admin/limits/:limit", serveAdmin(setLimit, errorChannel))
+
+	mux.POST("/admin/reload/oui", serveAdmin(reloadOUIDatabase, errorChannel))
+
+	mux.GET("/admin/asn", serveAdmin(showASNClient, errorChannel))
+}
@@ -0,0 +1,368 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
+)
+
+const (
+	maxAggregateBodySize = 1 << 20
+	maxAggregateEntries  = 65536
+)
+
+var (
+	ErrInvalidEntry       = errors.New("invalid entry")
+	ErrNoEntries          = errors.New("no entries provided")
+	ErrTooManyEntries     = errors.New("too many entries")
+	ErrInvalidMaxPrefixes = errors.New("max prefix count must be a positive integer")
+)
+
+type Aggregate struct {
+	Count     int      `json:"count"`
+	Addresses string   `json:"addresses"`
+	Extra     string   `json:"extra_addresses,omitempty"`
+	Prefixes  []string `json:"prefixes"`
+}
+
+type RangePrefixes struct {
+	First     string   `json:"first"`
+	Last      string   `json:"last"`
+	Count     int      `json:"count"`
+	Addresses string   `json:"addresses"`
+	Prefixes  []string `json:"prefixes"`
+}
+
+// parseSetEntry adds a single address, prefix, or start-end range to the set.
+func parseSetEntry(set *PrefixSet, entry string) error {
+	if start, end, found := strings.Cut(entry, "-"); found {
+		first, err := netip.ParseAddr(strings.TrimSpace(start))
+		if err != nil || first.Zone() != "" {
+			return fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+		}
+
+		last, err := netip.ParseAddr(strings.TrimSpace(end))
+		if err != nil || last.Zone() != "" {
+			return fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+		}
+
+		err = set.AddRange(first, last)
+		if err != nil {
+			return fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+		}
+
+		return nil
+	}
+
+	if strings.Contains(entry, "/") {
+		p, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+		}
+
+		set.AddPrefix(p)
+
+		return nil
+	}
+
+	a, err := netip.ParseAddr(entry)
+	if err != nil || a.Zone() != "" {
+		return fmt.Errorf("%w %q", ErrInvalidEntry, entry)
+	}
+
+	set.AddAddr(a)
+
+	return nil
+}
+
+// readEntries reads a list of entries from the request body, either as a JSON
+// array of strings or as plain text. Plain text entries are separated by
+// whitespace or commas, and anything following a # is ignored.
+func readEntries(body io.Reader, contentType string) ([]string, error) {
+	if strings.HasPrefix(contentType, "application/json") {
+		var entries []string
+
+		err := json.NewDecoder(body).Decode(&entries)
+		if err != nil {
+			return nil, err
+		}
+
+		return entries, nil
+	}
+
+	var entries []string
+
+	scanner := bufio.NewScanner(body)
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		// Ranges may be written with spaces around the hyphen, so those are
+		// removed before splitting the line into entries.
+		line = strings.ReplaceAll(line, " - ", "-")
+
+		entries = append(entries, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+
+	return entries, scanner.Err()
+}
+
+func aggregate(entries []string, maxPrefixes int) (Aggregate, error) {
+	switch {
+	case len(entries) == 0:
+		return Aggregate{}, ErrNoEntries
+	case len(entries) > maxAggregateEntries:
+		return Aggregate{}, ErrTooManyEntries
+	}
+
+	var set PrefixSet
+
+	for _, entry := range entries {
+		err := parseSetEntry(&set, strings.TrimSpace(entry))
+		if err != nil {
+			return Aggregate{}, err
+		}
+	}
+
+	result := Aggregate{
+		Addresses: set.Size().String(),
+	}
+
+	prefixes := set.Prefixes()
+
+	if maxPrefixes > 0 {
+		var extra *big.Int
+
+		prefixes, extra = set.Summarize(maxPrefixes)
+
+		result.Extra = extra.String()
+	}
+
+	result.Count = len(prefixes)
+
+	result.Prefixes = make([]string, len(prefixes))
+
+	for i, p := range prefixes {
+		result.Prefixes[i] = p.String()
+	}
+
+	return result, nil
+}
+
+func serveAggregateError(w http.ResponseWriter, r *http.Request, pr *message.Printer, err error, errorChannel chan<- Error) {
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	w.WriteHeader(http.StatusBadRequest)
+
+	var output string
+
+	switch {
+	case errors.Is(err, ErrInvalidEntry):
+		output = pr.Sprintf("Invalid entry %s", strings.TrimPrefix(err.Error(), ErrInvalidEntry.Error()+" "))
+	case errors.Is(err, ErrTooManyEntries):
+		output = pr.Sprintf("Entry count must be no greater than %d", maxAggregateEntries)
+	case errors.Is(err, ErrInvalidMaxPrefixes):
+		output = pr.Sprintf("Maximum prefix count must be a positive integer")
+	default:
+		output = pr.Sprintf("Invalid aggregation request")
+	}
+
+	_, err = w.Write([]byte(output + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
+
+func serveAggregate(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		var maxPrefixes int
+
+		if v := r.URL.Query().Get("max"); v != "" {
+			var err error
+
+			maxPrefixes, err = strconv.Atoi(v)
+			if err != nil || maxPrefixes < 1 {
+				serveAggregateError(w, r, pr, ErrInvalidMaxPrefixes, errorChannel)
+
+				return
+			}
+		}
+
+		entries, err := readEntries(http.MaxBytesReader(w, r.Body, maxAggregateBodySize), r.Header.Get("Content-Type"))
+		if err != nil {
+			serveAggregateError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		result, err := aggregate(entries, maxPrefixes)
+		if err != nil {
+			serveAggregateError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			fields := [][2]string{
+				{"Prefixes", localizeNumber(pr, strconv.Itoa(result.Count))},
+				{"Addresses", localizeNumber(pr, result.Addresses)},
+			}
+
+			if result.Extra != "" {
+				fields = append(fields, [2]string{"Extra addresses", localizeNumber(pr, result.Extra)})
+			}
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, fields))
+
+			output.WriteString("\n")
+
+			for _, prefix := range result.Prefixes {
+				output.WriteString(prefix + "\n")
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
+
+// parseRange reads a range written as either start-end or start/end.
+func parseRange(path string) (netip.Addr, netip.Addr, error) {
+	path = strings.Trim(path, "/")
+
+	start, end, found := strings.Cut(path, "-")
+	if !found {
+		start, end, _ = strings.Cut(path, "/")
+	}
+
+	first, err := netip.ParseAddr(start)
+	if err != nil || first.Zone() != "" {
+		return netip.Addr{}, netip.Addr{}, ErrInvalidRange
+	}
+
+	last, err := netip.ParseAddr(end)
+	if err != nil || last.Zone() != "" {
+		return netip.Addr{}, netip.Addr{}, ErrInvalidRange
+	}
+
+	return first, last, nil
+}
+
+func serveRange(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		first, last, err := parseRange(p.ByName("range"))
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		var set PrefixSet
+
+		err = set.AddRange(first, last)
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		prefixes := set.Prefixes()
+
+		result := RangePrefixes{
+			First:     first.String(),
+			Last:      last.String(),
+			Count:     len(prefixes),
+			Addresses: set.Size().String(),
+			Prefixes:  make([]string, len(prefixes)),
+		}
+
+		for i, prefix := range prefixes {
+			result.Prefixes[i] = prefix.String()
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, [][2]string{
+				{"First", result.First},
+				{"Last", result.Last},
+				{"Prefixes", localizeNumber(pr, strconv.Itoa(result.Count))},
+				{"Addresses", localizeNumber(pr, result.Addresses)},
+			}))
+
+			output.WriteString("\n")
+
+			for _, prefix := range result.Prefixes {
+				output.WriteString(prefix + "\n")
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammario/ipisp/v2"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
+)
+
+const asnLookupTimeout = 10 * time.Second
+
+var (
+	ErrASNNotFound = errors.New("AS not found")
+	ErrInvalidASN  = errors.New("invalid AS number")
+)
+
+// ASNRecord holds the registration details of an AS. When returned for an
+// address, Prefix is the announced network containing it, and is left unset
+// if the backend knows nothing about the address.
+type ASNRecord struct {
+	Addr     netip.Addr
+	ASN      uint32
+	Name     string
+	Country  string
+	Registry string
+	Prefix   netip.Prefix
+}
+
+// ASString formats the AS number the same way Team Cymru does.
+func (a ASNRecord) ASString() string {
+	if a.ASN == 0 {
+		return "N/A"
+	}
+
+	return "AS" + strconv.FormatUint(uint64(a.ASN), 10)
+}
+
+// ASNBackend looks up the AS announcing an address, or the details of an AS
+// by number. LookupAddrs returns one record per address, in the same order.
+type ASNBackend interface {
+	LookupAddrs(ctx context.Context, addrs ...netip.Addr) ([]ASNRecord, error)
+	LookupASN(ctx context.Context, asn uint32) (ASNRecord, error)
+}
+
+// prefixLister is implemented by backends which know every prefix announced
+// by an AS.
+type prefixLister interface {
+	AnnouncedPrefixes(asn uint32) []netip.Prefix
+}
+
+// cymruBackend queries Team Cymru's IP to ASN mapping service, over the
+// bulk session shared by every request.
+type cymruBackend struct{}
+
+func (cymruBackend) LookupAddrs(ctx context.Context, addrs ...netip.Addr) ([]ASNRecord, error) {
+	found, err := cymruBulk.LookupAddrs(ctx, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]ASNRecord, len(addrs))
+
+	for i, addr := range addrs {
+		records[i] = ASNRecord{Addr: addr}
+
+		response, ok := found[addr.Unmap()]
+		if !ok {
+			continue
+		}
+
+		records[i] = cymruRecord(response)
+		records[i].Addr = addr
+	}
+
+	return records, nil
+}
+
+func (cymruBackend) LookupASN(ctx context.Context, asn uint32) (ASNRecord, error) {
+	response, err := ipisp.LookupASN(ctx, ipisp.ASN(asn))
+
+	var dnsErr *net.DNSError
+
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return ASNRecord{}, ErrASNNotFound
+	}
+	if err != nil {
+		return ASNRecord{}, err
+	}
+
+	if response.ISPName == "" {
+		return ASNRecord{}, ErrASNNotFound
+	}
+
+	return cymruRecord(*response), nil
+}
+
+func cymruRecord(response ipisp.Response) ASNRecord {
+	record := ASNRecord{
+		Name:     response.ISPName,
+		Country:  response.Country,
+		Registry: strings.ToUpper(response.Registry),
+	}
+
+	// Unannounced addresses are reported with an AS number of -1.
+	if response.ASN > 0 {
+		record.ASN = uint32(response.ASN)
+	}
+
+	if response.Range != nil {
+		prefix, err := netip.ParsePrefix(response.Range.String())
+		if err == nil {
+			record.Prefix = prefix
+		}
+	}
+
+	return record
+}
+
+// fallbackBackend answers from the primary backend where it can, and asks
+// the fallback about anything the primary does not know.
+type fallbackBackend struct {
+	primary  ASNBackend
+	fallback ASNBackend
+}
+
+func (f fallbackBackend) LookupAddrs(ctx context.Context, addrs ...netip.Addr) ([]ASNRecord, error) {
+	records, err := f.primary.LookupAddrs(ctx, addrs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []int
+
+	for i, record := range records {
+		if !record.Prefix.IsValid() {
+			missing = append(missing, i)
+		}
+	}
+
+	if len(missing) == 0 {
+		return records, nil
+	}
+
+	lookups := make([]netip.Addr, len(missing))
+
+	for i, index := range missing {
+		lookups[i] = addrs[index]
+	}
+
+	fallbacks, err := f.fallback.LookupAddrs(ctx, lookups...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, index := range missing {
+		records[index] = fallbacks[i]
+	}
+
+	return records, nil
+}
+
+func (f fallbackBackend) LookupASN(ctx context.Context, asn uint32) (ASNRecord, error) {
+	record, err := f.primary.LookupASN(ctx, asn)
+	if errors.Is(err, ErrASNNotFound) {
+		return f.fallback.LookupASN(ctx, asn)
+	}
+
+	return record, err
+}
+
+func (f fallbackBackend) AnnouncedPrefixes(asn uint32) []netip.Prefix {
+	lister, ok := f.primary.(prefixLister)
+	if !ok {
+		return nil
+	}
+
+	return lister.AnnouncedPrefixes(asn)
+}
+
+// newASNBackend returns the local database set with --asn-db, falling back to
+// Team Cymru for anything it does not cover, or Team Cymru alone if no
+// database was provided.
+func newASNBackend(errorChannel chan<- Error) (ASNBackend, error) {
+	if asnDB == "" {
+		return cymruBackend{}, nil
+	}
+
+	local, err := openASNDatabase(asnDB, errorChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	if !asnFallback {
+		return local, nil
+	}
+
+	return fallbackBackend{local, cymruBackend{}}, nil
+}
+
+type ASInfo struct {
+	ASN      string   `json:"asn"`
+	Name     string   `json:"name"`
+	Country  string   `json:"country,omitempty"`
+	Registry string   `json:"registry,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+type ASOrigin struct {
+	Address  string `json:"address"`
+	ASN      string `json:"asn"`
+	Name     string `json:"name,omitempty"`
+	Prefix   string `json:"prefix"`
+	Country  string `json:"country,omitempty"`
+	Registry string `json:"registry,omitempty"`
+}
+
+// parseASN reads an AS number with or without a leading "AS", in either
+// asplain or asdot notation.
+func parseASN(s string) (uint32, error) {
+	s = strings.TrimPrefix(strings.ToUpper(s), "AS")
+
+	if high, low, found := strings.Cut(s, "."); found {
+		h, err := strconv.ParseUint(high, 10, 16)
+		if err != nil {
+			return 0, ErrInvalidASN
+		}
+
+		l, err := strconv.ParseUint(low, 10, 16)
+		if err != nil {
+			return 0, ErrInvalidASN
+		}
+
+		if h == 0 && l == 0 {
+			return 0, ErrInvalidASN
+		}
+
+		return uint32(h<<16 | l), nil
+	}
+
+	asn, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || asn == 0 {
+		return 0, ErrInvalidASN
+	}
+
+	return uint32(asn), nil
+}
+
+func serveASNError(w http.ResponseWriter, r *http.Request, pr *message.Printer, err error, errorChannel chan<- Error) {
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	var output string
+
+	switch {
+	case errors.Is(err, ErrInvalidASN):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid AS number requested")
+	case errors.Is(err, ErrInvalidAddress):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid IP address requested")
+	case errors.Is(err, ErrASNNotFound):
+		w.WriteHeader(http.StatusNotFound)
+
+		output = pr.Sprintf("No AS found")
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+
+		output = pr.Sprintf("Lookup failed")
+	}
+
+	_, err = w.Write([]byte(output + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
+
+func serveASN(backend ASNBackend, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		asn, err := parseASN(p.ByName("asn"))
+		if err != nil {
+			serveASNError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), asnLookupTimeout)
+		defer cancel()
+
+		record, err := backend.LookupASN(ctx, asn)
+		if err != nil {
+			serveASNError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		result := ASInfo{
+			ASN:      record.ASString(),
+			Name:     record.Name,
+			Country:  record.Country,
+			Registry: record.Registry,
+		}
+
+		if lister, ok := backend.(prefixLister); ok {
+			prefixes := slices.Clone(lister.AnnouncedPrefixes(asn))
+
+			slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+				if c := a.Addr().Compare(b.Addr()); c != 0 {
+					return c
+				}
+
+				return a.Bits() - b.Bits()
+			})
+
+			for _, prefix := range prefixes {
+				result.Prefixes = append(result.Prefixes, prefix.String())
+			}
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			fields := [][2]string{
+				{"ASN", result.ASN},
+				{"Name", result.Name},
+			}
+
+			if result.Country != "" {
+				fields = append(fields, [2]string{"Country", result.Country})
+			}
+
+			if result.Registry != "" {
+				fields = append(fields, [2]string{"Registry", result.Registry})
+			}
+
+			if len(result.Prefixes) > 0 {
+				fields = append(fields, [2]string{"Prefixes", localizeNumber(pr, strconv.Itoa(len(result.Prefixes)))})
+			}
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, fields))
+
+			if len(result.Prefixes) > 0 {
+				output.WriteString("\n")
+
+				for _, prefix := range result.Prefixes {
+					output.WriteString(prefix + "\n")
+				}
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
+
+func serveASNOrigin(backend ASNBackend, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		addr, err := netip.ParseAddr(strings.Trim(p.ByName("ip"), "/"))
+		if err != nil || addr.Zone() != "" {
+			serveASNError(w, r, pr, ErrInvalidAddress, errorChannel)
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), asnLookupTimeout)
+		defer cancel()
+
+		records, err := backend.LookupAddrs(ctx, addr.Unmap())
+		if err == nil && !records[0].Prefix.IsValid() {
+			err = ErrASNNotFound
+		}
+		if err != nil {
+			serveASNError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		result := ASOrigin{
+			Address:  records[0].Addr.String(),
+			ASN:      records[0].ASString(),
+			Name:     records[0].Name,
+			Prefix:   records[0].Prefix.String(),
+			Country:  records[0].Country,
+			Registry: records[0].Registry,
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			fields := [][2]string{
+				{"Address", result.Address},
+				{"ASN", result.ASN},
+			}
+
+			for _, field := range []struct {
+				label string
+				value string
+			}{
+				{"Name", result.Name},
+				{"Prefix", result.Prefix},
+				{"Country", result.Country},
+				{"Registry", result.Registry},
+			} {
+				if field.value != "" {
+					fields = append(fields, [2]string{field.label, field.value})
+				}
+			}
+
+			_, err = w.Write([]byte(formatFields(pr, fields)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
+
+// routeASN dispatches requests below an AS number to the named tool, as a
+// wildcard route cannot share its position with a static one.
+func routeASN(backend ASNBackend, errorChannel chan<- Error) httprouter.Handle {
+	tools := map[string]httprouter.Handle{
+		"ip": serveASNOrigin(backend, errorChannel),
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		tool := p.ByName("asn")
+
+		handler, ok := tools[tool]
+		if !ok {
+			serveASNError(w, r, localizer(r), ErrInvalidASN, errorChannel)
+
+			return
+		}
+
+		handler(w, r, httprouter.Params{{Key: tool, Value: p.ByName("rest")}})
+	}
+}
+
+func registerASN(mux *httprouter.Router, usage *sync.Map, backend ASNBackend, errorChannel chan<- Error) {
+	const module = "asn"
+
+	mux.GET("/asn/", serveUsage(module, usage, errorChannel))
+	mux.GET("/asn/:asn", serveASN(backend, errorChannel))
+	mux.GET("/asn/:asn/*rest", routeASN(backend, errorChannel))
+
+	usage.Store(module, []string{
+		"/asn/AS15169",
+		"/asn/13335",
+		"/asn/ip/8.8.8.8",
+		"/asn/ip/2606:4700:4700::1111",
+	})
+}
@@ -0,0 +1,463 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// asnReloadDelay is how long to wait after the last change to the database
+// file before reloading it, so that a file being written is not read early.
+const asnReloadDelay = 2 * time.Second
+
+var ErrInvalidASNDatabase = errors.New("invalid ASN database")
+
+// trieNode is a node of a path-compressed binary trie. Nodes added only to
+// join two branches have a value of -1.
+type trieNode struct {
+	key   uint128
+	bits  uint8
+	value int32
+	child [2]*trieNode
+}
+
+// prefixTrie maps the prefixes of a single address family to values, and
+// finds the longest prefix containing an address.
+type prefixTrie struct {
+	root  *trieNode
+	width int
+}
+
+// bit returns bit i of u, counting from the most significant bit of an
+// address of the given width.
+func (u uint128) bit(width, i int) int {
+	pos := width - 1 - i
+
+	if pos >= 64 {
+		return int(u.hi >> (pos - 64) & 1)
+	}
+
+	return int(u.lo >> pos & 1)
+}
+
+// commonBits returns the number of leading bits shared by a and b, up to the
+// given limit.
+func commonBits(a, b uint128, width, limit int) int {
+	return min(width-a.xor(b).bitLength(), limit)
+}
+
+func (t *prefixTrie) insert(key uint128, bits int, value int32) {
+	node := &t.root
+
+	for {
+		n := *node
+		if n == nil {
+			*node = &trieNode{key: key, bits: uint8(bits), value: value}
+
+			return
+		}
+
+		common := commonBits(n.key, key, t.width, min(int(n.bits), bits))
+
+		switch {
+		case common == int(n.bits) && common == bits:
+			n.value = value
+
+			return
+		case common == int(n.bits):
+			node = &n.child[key.bit(t.width, common)]
+		case common == bits:
+			parent := &trieNode{key: key, bits: uint8(bits), value: value}
+
+			parent.child[n.key.bit(t.width, bits)] = n
+
+			*node = parent
+
+			return
+		default:
+			glue := &trieNode{key: key.and(ones(t.width - common).not()), bits: uint8(common), value: -1}
+
+			glue.child[key.bit(t.width, common)] = &trieNode{key: key, bits: uint8(bits), value: value}
+			glue.child[n.key.bit(t.width, common)] = n
+
+			*node = glue
+
+			return
+		}
+	}
+}
+
+// lookup returns the node for the longest prefix containing key, or nil if
+// there is none.
+func (t *prefixTrie) lookup(key uint128) *trieNode {
+	var best *trieNode
+
+	for n := t.root; n != nil; n = n.child[key.bit(t.width, int(n.bits))] {
+		if commonBits(n.key, key, t.width, int(n.bits)) < int(n.bits) {
+			break
+		}
+
+		if n.value >= 0 {
+			best = n
+		}
+
+		if int(n.bits) == t.width {
+			break
+		}
+	}
+
+	return best
+}
+
+type asnEntry struct {
+	asn     uint32
+	country string
+}
+
+type asnInfo struct {
+	name    string
+	country string
+}
+
+// asnTable is a loaded ASN database. Trie values index into entries, which
+// are shared between every prefix announced by the same AS and country.
+type asnTable struct {
+	v4       prefixTrie
+	v6       prefixTrie
+	entries  []asnEntry
+	indices  map[asnEntry]int32
+	systems  map[uint32]asnInfo
+	routes   map[uint32][]netip.Prefix
+	prefixes int
+}
+
+func newASNTable() *asnTable {
+	return &asnTable{
+		v4:      prefixTrie{width: 32},
+		v6:      prefixTrie{width: 128},
+		indices: make(map[asnEntry]int32),
+		systems: make(map[uint32]asnInfo),
+		routes:  make(map[uint32][]netip.Prefix),
+	}
+}
+
+func (t *asnTable) trie(is4 bool) *prefixTrie {
+	if is4 {
+		return &t.v4
+	}
+
+	return &t.v6
+}
+
+func (t *asnTable) add(p netip.Prefix, asn uint32, name, country string) {
+	entry := asnEntry{asn, country}
+
+	index, ok := t.indices[entry]
+	if !ok {
+		index = int32(len(t.entries))
+
+		t.entries = append(t.entries, entry)
+		t.indices[entry] = index
+	}
+
+	if asn != 0 {
+		if _, ok := t.systems[asn]; !ok {
+			t.systems[asn] = asnInfo{name, country}
+		}
+
+		t.routes[asn] = append(t.routes[asn], p)
+	}
+
+	first, _ := prefixBounds(p)
+
+	t.trie(p.Addr().Is4()).insert(first, p.Bits(), index)
+
+	t.prefixes++
+}
+
+func (t *asnTable) lookup(addr netip.Addr) ASNRecord {
+	addr = addr.Unmap()
+
+	record := ASNRecord{Addr: addr}
+
+	trie := t.trie(addr.Is4())
+
+	node := trie.lookup(fromAddr(addr))
+	if node == nil {
+		return record
+	}
+
+	entry := t.entries[node.value]
+
+	record.ASN = entry.asn
+	record.Name = t.systems[entry.asn].name
+	record.Country = entry.country
+	record.Prefix = netip.PrefixFrom(node.key.toAddr(addr.Is4()), int(node.bits))
+
+	return record
+}
+
+// parseTSVAddr reads an address from an iptoasn dataset, which writes IPv4
+// addresses either in dotted-quad form or as a plain integer.
+func parseTSVAddr(s string) (netip.Addr, error) {
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return uint128{0, n}.toAddr(true), nil
+	}
+
+	return netip.ParseAddr(s)
+}
+
+// loadASNTSV reads an iptoasn dataset, where each line holds the first and
+// last address of a range, its AS number, country code and AS description.
+func loadASNTSV(r io.Reader) (*asnTable, error) {
+	table := newASNTable()
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+
+	for s.Scan() {
+		line++
+
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) < 5 {
+			continue
+		}
+
+		first, err := parseTSVAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidASNDatabase, line, err)
+		}
+
+		last, err := parseTSVAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidASNDatabase, line, err)
+		}
+
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidASNDatabase, line, err)
+		}
+
+		if first.Is4() != last.Is4() || last.Less(first) {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidASNDatabase, line, ErrInvalidRange)
+		}
+
+		// Ranges not announced by any AS are kept, so that lookups for them
+		// are answered locally instead of being passed to the fallback.
+		country := fields[3]
+		if country == "None" {
+			country = ""
+		}
+
+		for _, p := range rangePrefixes(addrRange{fromAddr(first), fromAddr(last)}, first.Is4()) {
+			table.add(p, uint32(asn), fields[4], country)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// loadASNMMDB reads a MaxMind DB in the layout of the GeoLite2 ASN database.
+func loadASNMMDB(path string) (*asnTable, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	table := newASNTable()
+
+	for result := range reader.Networks() {
+		var system struct {
+			Number       uint32 `maxminddb:"autonomous_system_number"`
+			Organization string `maxminddb:"autonomous_system_organization"`
+		}
+
+		err := result.Decode(&system)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidASNDatabase, err)
+		}
+
+		table.add(result.Prefix().Masked(), system.Number, system.Organization, "")
+	}
+
+	return table, nil
+}
+
+// loadASNDatabase reads an ASN database, choosing the format from the file
+// extension. iptoasn datasets may be gzip-compressed.
+func loadASNDatabase(path string) (*asnTable, error) {
+	if strings.HasSuffix(path, ".mmdb") {
+		return loadASNMMDB(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	return loadASNTSV(r)
+}
+
+// localBackend answers ASN lookups from a database loaded into memory, which
+// is reloaded whenever the file changes.
+type localBackend struct {
+	path  string
+	table atomic.Pointer[asnTable]
+}
+
+func (l *localBackend) LookupAddrs(ctx context.Context, addrs ...netip.Addr) ([]ASNRecord, error) {
+	table := l.table.Load()
+
+	records := make([]ASNRecord, len(addrs))
+
+	for i, addr := range addrs {
+		records[i] = table.lookup(addr)
+		records[i].Addr = addr
+	}
+
+	return records, nil
+}
+
+func (l *localBackend) LookupASN(ctx context.Context, asn uint32) (ASNRecord, error) {
+	system, ok := l.table.Load().systems[asn]
+	if !ok {
+		return ASNRecord{}, ErrASNNotFound
+	}
+
+	return ASNRecord{
+		ASN:     asn,
+		Name:    system.name,
+		Country: system.country,
+	}, nil
+}
+
+func (l *localBackend) AnnouncedPrefixes(asn uint32) []netip.Prefix {
+	return l.table.Load().routes[asn]
+}
+
+func (l *localBackend) load() error {
+	startTime := time.Now()
+
+	table, err := loadASNDatabase(l.path)
+	if err != nil {
+		return err
+	}
+
+	l.table.Store(table)
+
+	if verbose {
+		fmt.Printf("%s | Loaded ASN database (%d prefixes) in %dms\n",
+			startTime.Format(timeFormats["RFC3339"]),
+			table.prefixes,
+			time.Since(startTime).Milliseconds())
+	}
+
+	return nil
+}
+
+// watch reloads the database after its file is written or replaced. The
+// parent directory is watched rather than the file itself, so that updates
+// made by renaming a new file into place are also seen. The previous
+// database is kept if the new one fails to load.
+func (l *localBackend) watch(errorChannel chan<- Error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = watcher.Add(filepath.Dir(l.path))
+	if err != nil {
+		watcher.Close()
+
+		return err
+	}
+
+	go func() {
+		var timer *time.Timer
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) != filepath.Clean(l.path) || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+					continue
+				}
+
+				if timer != nil {
+					timer.Stop()
+				}
+
+				timer = time.AfterFunc(asnReloadDelay, func() {
+					err := l.load()
+					if err != nil {
+						errorChannel <- Error{Message: err, Path: "reloadASNDatabase()"}
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				errorChannel <- Error{Message: err, Path: "watchASNDatabase()"}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func openASNDatabase(path string, errorChannel chan<- Error) (*localBackend, error) {
+	l := &localBackend{path: path}
+
+	err := l.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.watch(errorChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,264 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	maxBatchBodySize = 1 << 20
+)
+
+var (
+	ErrInvalidBatchWorkers  = errors.New("batch worker count must be a positive integer")
+	ErrInvalidMaxBatchItems = errors.New("max batch item count must be a positive integer")
+	ErrStreamingBatchItem   = errors.New("streaming endpoints cannot be batched")
+
+	// Tools which cannot be invoked from within a batch.
+	batchExcluded = []string{"admin", "batch"}
+)
+
+type BatchItem struct {
+	Tool    string            `json:"tool"`
+	Args    []string          `json:"args"`
+	Options map[string]string `json:"options"`
+}
+
+type BatchResult struct {
+	Tool        string `json:"tool"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Result      string `json:"result,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// batchRecorder captures the response of a tool invoked as part of a batch.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *batchRecorder) Header() http.Header {
+	return b.header
+}
+
+func (b *batchRecorder) Write(data []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+
+	return b.body.Write(data)
+}
+
+func (b *batchRecorder) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+// batchTarget builds the request URL for an item. Each argument is escaped,
+// so that characters such as ? and # reach the tool as part of the argument
+// rather than being taken as the start of a query or fragment.
+func batchTarget(item BatchItem) *url.URL {
+	path := []string{item.Tool}
+	rawPath := []string{url.PathEscape(item.Tool)}
+
+	for _, arg := range item.Args {
+		arg = strings.Trim(arg, "/")
+
+		path = append(path, arg)
+		rawPath = append(rawPath, url.PathEscape(arg))
+	}
+
+	target := &url.URL{
+		Path:    "/" + strings.Join(path, "/"),
+		RawPath: "/" + strings.Join(rawPath, "/"),
+	}
+
+	if len(item.Args) == 0 {
+		target.Path += "/"
+		target.RawPath += "/"
+	}
+
+	if len(item.Options) > 0 {
+		query := url.Values{}
+
+		for k, v := range item.Options {
+			query.Set(k, v)
+		}
+
+		target.RawQuery = query.Encode()
+	}
+
+	return target
+}
+
+func runBatchItem(item BatchItem, handler http.Handler, modules *Modules, r *http.Request) BatchResult {
+	result := BatchResult{Tool: item.Tool}
+
+	if !modules.Has(item.Tool) || slices.Contains(batchExcluded, item.Tool) {
+		result.Status = http.StatusBadRequest
+		result.Error = fmt.Sprintf("unknown tool %q", item.Tool)
+
+		return result
+	}
+
+	target := batchTarget(item)
+
+	// Streams never finish, and the recorder can't flush them.
+	if strings.HasSuffix(strings.TrimSuffix(target.Path, "/"), "/stream") {
+		result.Status = http.StatusBadRequest
+		result.Error = ErrStreamingBatchItem.Error()
+
+		return result
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		result.Status = http.StatusBadRequest
+		result.Error = err.Error()
+
+		return result
+	}
+
+	req.URL = target
+
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Type")
+	req.RemoteAddr = r.RemoteAddr
+	req.RequestURI = target.RequestURI()
+
+	rec := &batchRecorder{header: http.Header{}}
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	result.Status = rec.status
+
+	if rec.status >= http.StatusBadRequest {
+		result.Error = strings.TrimSpace(rec.body.String())
+
+		if result.Error == "" {
+			result.Error = http.StatusText(rec.status)
+		}
+
+		return result
+	}
+
+	result.ContentType = rec.header.Get("Content-Type")
+	if result.ContentType == "" {
+		result.ContentType = http.DetectContentType(rec.body.Bytes())
+	}
+
+	switch {
+	case strings.HasPrefix(result.ContentType, "text/"),
+		strings.HasPrefix(result.ContentType, "application/json"):
+		result.Result = strings.TrimSuffix(rec.body.String(), "\n")
+	default:
+		result.Encoding = "base64"
+		result.Result = base64.StdEncoding.EncodeToString(rec.body.Bytes())
+	}
+
+	return result
+}
+
+func serveBatch(handler http.Handler, modules *Modules, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		securityHeaders(w)
+
+		var items []BatchItem
+
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&items)
+		if err != nil {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err = w.Write([]byte(localizer(r).Sprintf("Invalid batch request") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		maxItems := batchItemLimit.Load()
+
+		if int64(len(items)) > maxItems {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err = w.Write([]byte(localizer(r).Sprintf("Batch item count must be no greater than %d", maxItems) + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		results := make([]BatchResult, len(items))
+
+		jobs := make(chan int)
+
+		var wg sync.WaitGroup
+
+		for range min(batchWorkers, len(items)) {
+			wg.Go(func() {
+				for i := range jobs {
+					results[i] = runBatchItem(items[i], handler, modules, r)
+				}
+			})
+		}
+
+		for i := range items {
+			jobs <- i
+		}
+
+		close(jobs)
+
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if verbose {
+			fmt.Printf("%s | %s => %s (%d items)\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				len(items))
+		}
+
+		err = json.NewEncoder(w).Encode(results)
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		}
+	}
+}
+
+func registerBatch(mux *httprouter.Router, handler http.Handler, modules *Modules, errorChannel chan<- Error) {
+	mux.POST("/batch", serveBatch(handler, modules, errorChannel))
+}
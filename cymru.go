@@ -0,0 +1,407 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ammario/ipisp/v2"
+)
+
+const (
+	// cymruBatchDelay is how long lookups are held so that those arriving
+	// from concurrent requests can be sent as one query, of at most
+	// cymruMaxBatch addresses.
+	cymruBatchDelay = 10 * time.Millisecond
+	cymruMaxBatch   = 1000
+
+	// cymruIdleTimeout is how long a session is kept open without use, and
+	// cymruQueryTimeout how long a single bulk query may take.
+	cymruIdleTimeout  = 1 * time.Minute
+	cymruQueryTimeout = 10 * time.Second
+
+	// After a failed connection, no new one is attempted until the backoff
+	// has passed. It starts at cymruMinBackoff and doubles with each further
+	// failure, up to cymruMaxBackoff.
+	cymruMinBackoff = 1 * time.Second
+	cymruMaxBackoff = 1 * time.Minute
+)
+
+var (
+	ErrCymruResponse    = errors.New("unexpected response from Team Cymru")
+	ErrCymruTimeout     = errors.New("timed out waiting for Team Cymru")
+	ErrCymruUnavailable = errors.New("Team Cymru unavailable")
+)
+
+// cymruBulk is the session shared by every Team Cymru lookup.
+var cymruBulk = &bulkClient{}
+
+type bulkRequest struct {
+	addrs []netip.Addr
+	reply chan bulkResult
+}
+
+type bulkResult struct {
+	responses map[netip.Addr]ipisp.Response
+	err       error
+}
+
+// BulkClientStats describes the state of the shared bulk session.
+type BulkClientStats struct {
+	Connected   bool
+	Connections uint64
+	Queries     uint64
+	Requests    uint64
+	Addresses   uint64
+	Failures    uint64
+	LastError   string
+	LastErrorAt time.Time
+	RetryAt     time.Time
+}
+
+// bulkClient shares a single bulk WHOIS session with Team Cymru between all
+// requests. Lookups are handed to one goroutine, which owns the session and
+// combines lookups made at around the same time into a single query.
+type bulkClient struct {
+	once     sync.Once
+	requests chan *bulkRequest
+
+	mu    sync.Mutex
+	stats BulkClientStats
+
+	// These are only touched by the goroutine started in run.
+	session *ipisp.BulkClient
+	backoff time.Duration
+	lastErr error
+}
+
+// LookupAddrs returns Team Cymru's response for each address it knows about,
+// keyed by the unmapped address.
+func (c *bulkClient) LookupAddrs(ctx context.Context, addrs []netip.Addr) (map[netip.Addr]ipisp.Response, error) {
+	c.once.Do(func() {
+		c.requests = make(chan *bulkRequest)
+
+		go c.run()
+	})
+
+	req := &bulkRequest{addrs: addrs, reply: make(chan bulkResult, 1)}
+
+	select {
+	case c.requests <- req:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case result := <-req.reply:
+		return result.responses, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the session's state.
+func (c *bulkClient) Stats() BulkClientStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+func (c *bulkClient) run() {
+	idle := time.NewTimer(cymruIdleTimeout)
+
+	for {
+		var batch []*bulkRequest
+
+		select {
+		case req := <-c.requests:
+			batch = append(batch, req)
+		case <-idle.C:
+			c.disconnect()
+
+			continue
+		}
+
+		wait := time.NewTimer(cymruBatchDelay)
+
+		count := len(batch[0].addrs)
+
+	collect:
+		for count < cymruMaxBatch {
+			select {
+			case req := <-c.requests:
+				batch = append(batch, req)
+
+				count += len(req.addrs)
+			case <-wait.C:
+				break collect
+			}
+		}
+
+		wait.Stop()
+
+		c.serve(batch)
+
+		idle.Reset(cymruIdleTimeout)
+	}
+}
+
+// serve looks up the addresses from every request in a batch at once. If
+// Team Cymru's response can't be read, which happens for addresses it has no
+// AS for, each request is retried on its own so that only the one that asked
+// for them fails.
+func (c *bulkClient) serve(batch []*bulkRequest) {
+	addrs := uniqueAddrs(batch...)
+
+	responses, err := c.lookupAll(addrs)
+
+	c.mu.Lock()
+	c.stats.Requests += uint64(len(batch))
+	c.stats.Addresses += uint64(len(addrs))
+	c.mu.Unlock()
+
+	if errors.Is(err, ErrCymruResponse) && len(batch) > 1 {
+		for _, req := range batch {
+			responses, err := c.lookupAll(uniqueAddrs(req))
+
+			req.reply <- bulkResult{responses, err}
+		}
+
+		return
+	}
+
+	for _, req := range batch {
+		req.reply <- bulkResult{responses, err}
+	}
+}
+
+func uniqueAddrs(batch ...*bulkRequest) []netip.Addr {
+	seen := make(map[netip.Addr]bool)
+
+	var addrs []netip.Addr
+
+	for _, req := range batch {
+		for _, addr := range req.addrs {
+			addr = addr.Unmap()
+
+			if !seen[addr] {
+				seen[addr] = true
+
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	return addrs
+}
+
+func (c *bulkClient) lookupAll(addrs []netip.Addr) (map[netip.Addr]ipisp.Response, error) {
+	responses := make(map[netip.Addr]ipisp.Response, len(addrs))
+
+	for chunk := range slices.Chunk(addrs, cymruMaxBatch) {
+		err := c.query(chunk, responses)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return responses, nil
+}
+
+// query sends one bulk query, connecting first if needed. Team Cymru closes
+// sessions it considers idle, so a query that fails on a session which was
+// already open is retried once on a new one.
+func (c *bulkClient) query(addrs []netip.Addr, responses map[netip.Addr]ipisp.Response) error {
+	ips := make([]net.IP, len(addrs))
+
+	for i, addr := range addrs {
+		ips[i] = net.IP(addr.AsSlice())
+	}
+
+	for {
+		reused := c.session != nil
+
+		err := c.connect()
+		if err != nil {
+			return err
+		}
+
+		found, err := c.lookup(ips)
+		if err == nil && len(found) < len(ips) {
+			// The client stops reading without an error when the session
+			// is closed from the other end.
+			err = io.ErrUnexpectedEOF
+		}
+
+		if err == nil {
+			for _, response := range found {
+				addr, ok := netip.AddrFromSlice(response.IP)
+				if ok {
+					responses[addr.Unmap()] = response
+				}
+			}
+
+			c.backoff = 0
+
+			c.mu.Lock()
+			c.stats.Queries++
+			c.stats.RetryAt = time.Time{}
+			c.mu.Unlock()
+
+			return nil
+		}
+
+		// Whatever was left unread makes the session unusable.
+		c.disconnect()
+
+		if !connectionError(err) {
+			return fmt.Errorf("%w: %w", ErrCymruResponse, err)
+		}
+
+		if !reused {
+			c.fail(err)
+
+			return err
+		}
+	}
+}
+
+// connectionError reports whether a query failed because of the session,
+// rather than because the response couldn't be parsed.
+func connectionError(err error) bool {
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrCymruTimeout)
+}
+
+// connect opens a session if there isn't one, unless an earlier failure is
+// still being backed off from.
+func (c *bulkClient) connect() error {
+	if c.session != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	retryAt := c.stats.RetryAt
+	c.mu.Unlock()
+
+	if time.Now().Before(retryAt) {
+		return fmt.Errorf("%w until %s: %w", ErrCymruUnavailable, retryAt.Format(timeFormats["RFC3339"]), c.lastErr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cymruQueryTimeout)
+	defer cancel()
+
+	session, err := ipisp.DialBulkClient(ctx)
+	if err != nil {
+		c.fail(err)
+
+		return err
+	}
+
+	c.session = session
+
+	c.mu.Lock()
+	c.stats.Connected = true
+	c.stats.Connections++
+	c.mu.Unlock()
+
+	return nil
+}
+
+// lookup runs a query on the open session. The client sets no deadline of
+// its own, so a query that takes too long is abandoned by closing the
+// session underneath it.
+func (c *bulkClient) lookup(ips []net.IP) ([]ipisp.Response, error) {
+	var (
+		responses []ipisp.Response
+		err       error
+	)
+
+	done := make(chan struct{})
+
+	session := c.session
+
+	go func() {
+		responses, err = session.LookupIPs(ips...)
+
+		close(done)
+	}()
+
+	timeout := time.NewTimer(cymruQueryTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-done:
+		return responses, err
+	case <-timeout.C:
+		session.Close()
+
+		<-done
+
+		c.session = nil
+
+		return nil, ErrCymruTimeout
+	}
+}
+
+func (c *bulkClient) disconnect() {
+	if c.session != nil {
+		c.session.Close()
+
+		c.session = nil
+	}
+
+	c.mu.Lock()
+	c.stats.Connected = false
+	c.mu.Unlock()
+}
+
+// fail records an error on a new session, and backs off before the next
+// attempt. The backoff is reset by the next query that succeeds.
+func (c *bulkClient) fail(err error) {
+	c.backoff = min(max(c.backoff*2, cymruMinBackoff), cymruMaxBackoff)
+	c.lastErr = err
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Failures++
+	c.stats.LastError = err.Error()
+	c.stats.LastErrorAt = time.Now()
+	c.stats.RetryAt = time.Now().Add(c.backoff)
+}
+
+// String renders the stats one per line, in the style of the admin API.
+func (s BulkClientStats) String() string {
+	var output strings.Builder
+
+	fmt.Fprintf(&output, "connected: %t\n", s.Connected)
+	fmt.Fprintf(&output, "connections: %d\n", s.Connections)
+	fmt.Fprintf(&output, "queries: %d\n", s.Queries)
+	fmt.Fprintf(&output, "requests: %d\n", s.Requests)
+	fmt.Fprintf(&output, "addresses: %d\n", s.Addresses)
+	fmt.Fprintf(&output, "failures: %d\n", s.Failures)
+
+	if s.LastError != "" {
+		fmt.Fprintf(&output, "last error: %s (%s)\n", s.LastError, s.LastErrorAt.Format(timeFormats["RFC3339"]))
+	}
+
+	if time.Now().Before(s.RetryAt) {
+		fmt.Fprintf(&output, "retry at: %s\n", s.RetryAt.Format(timeFormats["RFC3339"]))
+	}
+
+	return output.String()
+}
@@ -6,29 +6,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
-	"net/http"
+	"net/netip"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
-	"github.com/ammario/ipisp/v2"
 	"github.com/julienschmidt/httprouter"
 )
 
-func getBulkClient() (*ipisp.BulkClient, error) {
-	c, err := ipisp.DialBulkClient(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	return c, nil
-}
-
-func getHostnames(host net.IP, resolver *net.Resolver) ([]string, error) {
-	hosts, err := resolver.LookupAddr(context.Background(), host.String())
+func getHostnames(ctx context.Context, host netip.Addr, resolver *net.Resolver) ([]string, error) {
+	hosts, err := resolver.LookupAddr(ctx, host.String())
 	if err != nil {
 		return []string{}, err
 	}
@@ -40,368 +30,388 @@ func getHostnames(host net.IP, resolver *net.Resolver) ([]string, error) {
 	return hosts, nil
 }
 
-func getIP(host string, resolver *net.Resolver) (net.IP, error) {
-	hosts, err := resolver.LookupHost(context.Background(), host)
+// getIPs returns every address for a host, with IPv4 addresses first.
+func getIPs(ctx context.Context, host, protocol string, resolver *net.Resolver) ([]netip.Addr, error) {
+	ips, err := resolver.LookupNetIP(ctx, protocol, host)
 	if err != nil {
 		return nil, err
 	}
 
-	return net.ParseIP(hosts[0]), nil
-}
-
-func parseHost(host, protocol string, ctx *ipisp.BulkClient, resolver *net.Resolver) (string, error) {
-	ips, err := resolver.LookupIP(context.Background(), protocol, host)
-	if len(ips) == 0 || err != nil {
-		return "", err
+	for i := range ips {
+		ips[i] = ips[i].Unmap()
 	}
 
-	responses, err := ctx.LookupIPs(ips...)
-	if err != nil {
-		return "", err
-	}
+	sort.SliceStable(ips, func(i, j int) bool {
+		if ips[i].Is4() != ips[j].Is4() {
+			return ips[i].Is4()
+		}
 
-	sort.SliceStable(responses, func(i, j int) bool {
-		return responses[i].IP.String() < responses[j].IP.String()
+		return ips[i].Less(ips[j])
 	})
 
-	var retVal strings.Builder
-
-	retVal.WriteString(fmt.Sprintf("%s:\n\n", host))
+	return ips, nil
+}
 
-	for response := range responses {
-		var h strings.Builder
+// addressInfo holds what was found out about a single address, along with
+// the errors from any lookups that failed, so that one failure doesn't
+// prevent the rest of the results from being shown.
+type addressInfo struct {
+	addr      netip.Addr
+	record    ASNRecord
+	found     bool
+	hostnames []string
+	errs      []error
+}
 
-		hostnames, err := getHostnames(responses[response].IP, resolver)
-		if err != nil {
-			return "", err
-		}
+func (info addressInfo) provider() string {
+	if !info.found {
+		return "n/a"
+	}
 
-		switch {
-		case len(hostnames) < 1:
-			h.WriteString("n/a")
-		case len(hostnames) == 1:
-			h.WriteString(strings.TrimRight(hostnames[0], "."))
-		default:
-			for i := range hostnames {
-				h.WriteString(strings.TrimRight(hostnames[i], ".") + ", ")
-			}
-		}
+	return fmt.Sprintf("%v (%v)", info.record.ASString(), info.record.Name)
+}
 
-		retVal.WriteString(fmt.Sprintf("  %v:\n    Provider: %v (%v)\n    Hostname(s): %v\n    Range: %v\n\n",
-			responses[response].IP,
-			responses[response].ASN,
-			responses[response].ISPName,
-			strings.TrimRight(h.String(), ","),
-			responses[response].Range))
+func (info addressInfo) prefix() string {
+	if !info.found || !info.record.Prefix.IsValid() {
+		return "n/a"
 	}
 
-	return retVal.String(), nil
+	return info.record.Prefix.String()
 }
 
-func serveHostRecord(protocol string, resolver *net.Resolver, errorChannel chan<- Error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		startTime := time.Now()
+func (info addressInfo) writeErrors(w *strings.Builder, indent string) {
+	for _, err := range info.errs {
+		w.WriteString(fmt.Sprintf("%sError: %v\n", indent, err))
+	}
+}
 
-		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+// describeAddrs looks up the ASN for each address in a single request, and
+// optionally its hostnames, with each PTR lookup run concurrently.
+func describeAddrs(ctx context.Context, addrs []netip.Addr, backend ASNBackend, resolver *net.Resolver, ptr bool) []addressInfo {
+	infos := make([]addressInfo, len(addrs))
 
-		securityHeaders(w)
+	for i, addr := range addrs {
+		infos[i].addr = addr
+	}
 
-		ctx, err := getBulkClient()
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	var wg sync.WaitGroup
 
-			w.WriteHeader(http.StatusInternalServerError)
+	if ptr {
+		for i := range infos {
+			wg.Go(func() {
+				hostnames, err := getHostnames(ctx, infos[i].addr, resolver)
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
+				var dnsErr *net.DNSError
 
-			return
+				switch {
+				case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+				case err != nil:
+					infos[i].errs = append(infos[i].errs, err)
+				default:
+					infos[i].hostnames = hostnames
+				}
+			})
 		}
+	}
 
-		host := strings.TrimPrefix(p.ByName("host"), "/")
-
-		parsedHost, err := parseHost(host, protocol, ctx, resolver)
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	records, err := backend.LookupAddrs(ctx, addrs...)
 
-			w.WriteHeader(http.StatusInternalServerError)
+	wg.Wait()
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
-
-			return
+	if err != nil {
+		for i := range infos {
+			infos[i].errs = append(infos[i].errs, err)
 		}
 
-		_, err = w.Write([]byte(parsedHost + "\n"))
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-
-			return
-		}
+		return infos
+	}
 
-		if verbose {
-			fmt.Printf("%s | %s => %s\n",
-				startTime.Format(timeFormats["RFC3339"]),
-				realIP(r, true),
-				r.RequestURI)
+	for i := range infos {
+		if i < len(records) {
+			infos[i].record = records[i]
+			infos[i].found = true
 		}
 	}
+
+	return infos
 }
 
-func parseMX(ctx *ipisp.BulkClient, resolver *net.Resolver, host string) (string, error) {
-	records, err := resolver.LookupMX(context.Background(), host)
-	if len(records) == 0 || err != nil {
-		return "", err
-	}
+func parseHost(protocol string, backend ASNBackend, resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		ips, err := getIPs(ctx, host, protocol, resolver)
+		if len(ips) == 0 || err != nil {
+			return "", err
+		}
 
-	if len(records) > 1 {
-		sort.SliceStable(records, func(i, j int) bool {
-			return records[i].Host < records[j].Host
-		})
-	}
+		var retVal strings.Builder
 
-	hosts := make([]string, len(records))
-	priorities := make([]uint16, len(records))
-	ips := make([]net.IP, len(records))
+		retVal.WriteString(fmt.Sprintf("%s:\n\n", host))
 
-	for h := range records {
-		hosts[h] = records[h].Host
-		priorities[h] = records[h].Pref
-	}
+		for _, info := range describeAddrs(ctx, ips, backend, resolver, true) {
+			hostnames := "n/a"
 
-	for h := range hosts {
-		ip, err := getIP(hosts[h], resolver)
-		if err != nil {
-			return "", err
-		}
+			if len(info.hostnames) > 0 {
+				names := make([]string, len(info.hostnames))
 
-		ips[h] = ip
-	}
+				for i, hostname := range info.hostnames {
+					names[i] = strings.TrimRight(hostname, ".")
+				}
 
-	responses, err := ctx.LookupIPs(ips...)
-	if len(responses) == 0 || err != nil {
-		return "", err
-	}
+				hostnames = strings.Join(names, ", ")
+			}
 
-	var retVal strings.Builder
+			retVal.WriteString(fmt.Sprintf("  %v:\n    Provider: %v\n    Hostname(s): %v\n    Range: %v\n",
+				info.addr,
+				info.provider(),
+				hostnames,
+				info.prefix()))
 
-	retVal.WriteString(fmt.Sprintf("%v:\n", host))
+			info.writeErrors(&retVal, "    ")
 
-	for response := range responses {
-		retVal.WriteString(fmt.Sprintf("\n  (%v) %v:\n    IP: %v\n    Provider: %v (%v)\n",
-			priorities[response],
-			strings.TrimRight(hosts[response], "."),
-			responses[response].IP,
-			responses[response].ASN,
-			responses[response].ISPName))
+			retVal.WriteString("\n")
+		}
+
+		return retVal.String(), nil
 	}
+}
 
-	return retVal.String(), nil
+// nameserverTarget is a host named by an MX or NS record, along with its
+// addresses.
+type nameserverTarget struct {
+	host     string
+	priority uint16
+	addrs    []netip.Addr
+	err      error
 }
 
-func serveMXRecord(resolver *net.Resolver, errorChannel chan<- Error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		startTime := time.Now()
+// resolveTargets looks up every address of each target concurrently, then
+// looks up the ASN for all of them at once.
+func resolveTargets(ctx context.Context, targets []nameserverTarget, backend ASNBackend, resolver *net.Resolver) map[netip.Addr]addressInfo {
+	var wg sync.WaitGroup
 
-		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+	for i := range targets {
+		wg.Go(func() {
+			targets[i].addrs, targets[i].err = getIPs(ctx, targets[i].host, "ip", resolver)
+		})
+	}
 
-		securityHeaders(w)
+	wg.Wait()
 
-		ctx, err := getBulkClient()
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	var addrs []netip.Addr
 
-			w.WriteHeader(http.StatusInternalServerError)
+	seen := make(map[netip.Addr]bool)
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
+	for _, target := range targets {
+		for _, addr := range target.addrs {
+			if !seen[addr] {
+				seen[addr] = true
 
-			return
+				addrs = append(addrs, addr)
+			}
 		}
+	}
 
-		host := strings.TrimPrefix(p.ByName("host"), "/")
+	infos := make(map[netip.Addr]addressInfo, len(addrs))
 
-		parsedHost, err := parseMX(ctx, resolver, host)
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	if len(addrs) == 0 {
+		return infos
+	}
 
-			w.WriteHeader(http.StatusInternalServerError)
+	for _, info := range describeAddrs(ctx, addrs, backend, resolver, false) {
+		infos[info.addr] = info
+	}
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
+	return infos
+}
 
-			return
-		}
+// writeTarget renders each address of an MX or NS target, or the error from
+// looking them up.
+func writeTarget(w *strings.Builder, target nameserverTarget, infos map[netip.Addr]addressInfo) {
+	if target.err != nil {
+		w.WriteString(fmt.Sprintf("    Error: %v\n", target.err))
 
-		_, err = w.Write([]byte(parsedHost + "\n"))
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		return
+	}
 
-			return
-		}
+	for _, addr := range target.addrs {
+		info := infos[addr]
 
-		if verbose {
-			fmt.Printf("%s | %s => %s\n",
-				startTime.Format(timeFormats["RFC3339"]),
-				realIP(r, true),
-				r.RequestURI)
-		}
+		w.WriteString(fmt.Sprintf("    IP: %v\n    Provider: %v\n", addr, info.provider()))
+
+		info.writeErrors(w, "    ")
 	}
 }
 
-func parseNS(ctx *ipisp.BulkClient, resolver *net.Resolver, host string) (string, error) {
-	records, err := resolver.LookupNS(context.Background(), host)
+// lookupMX returns the mail exchangers for a host sorted by name, along with
+// the details of every address they resolve to.
+func lookupMX(ctx context.Context, host string, backend ASNBackend, resolver *net.Resolver) ([]nameserverTarget, map[netip.Addr]addressInfo, error) {
+	records, err := resolver.LookupMX(ctx, host)
 	if len(records) == 0 || err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
 	sort.SliceStable(records, func(i, j int) bool {
 		return records[i].Host < records[j].Host
 	})
 
-	var hosts []string
+	targets := make([]nameserverTarget, len(records))
 
-	for h := range records {
-		record := records[h]
-		hosts = append(hosts, record.Host)
+	for i, record := range records {
+		targets[i] = nameserverTarget{host: record.Host, priority: record.Pref}
 	}
 
-	var ips []net.IP
-	for h := 0; h < len(hosts); h++ {
-		ip, err := getIP(hosts[h], resolver)
-		if err != nil {
-			return "", err
-		}
-
-		ips = append(ips, ip)
-	}
-
-	responses, err := ctx.LookupIPs(ips...)
-	if len(responses) == 0 || err != nil {
-		return "", err
-	}
+	return targets, resolveTargets(ctx, targets, backend, resolver), nil
+}
 
-	var retVal strings.Builder
+func writeMX(w *strings.Builder, targets []nameserverTarget, infos map[netip.Addr]addressInfo) {
+	for _, target := range targets {
+		w.WriteString(fmt.Sprintf("\n  (%v) %v:\n", target.priority, strings.TrimRight(target.host, ".")))
 
-	_, err = retVal.WriteString(fmt.Sprintf("%v:\n", host))
-	if err != nil {
-		return "", err
+		writeTarget(w, target, infos)
 	}
+}
 
-	for response := range responses {
-		retVal.WriteString(fmt.Sprintf("\n  %v:\n    IP: %v\n    Provider: %v (%v)\n",
-			strings.TrimRight(hosts[response], "."),
-			responses[response].IP,
-			responses[response].ASN,
-			responses[response].ISPName))
-	}
+func parseMX(backend ASNBackend, resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		targets, infos, err := lookupMX(ctx, host, backend, resolver)
+		if len(targets) == 0 || err != nil {
+			return "", err
+		}
 
-	return retVal.String(), nil
-}
+		var retVal strings.Builder
 
-func serveNSRecord(resolver *net.Resolver, errorChannel chan<- Error) httprouter.Handle {
-	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
-		startTime := time.Now()
+		retVal.WriteString(fmt.Sprintf("%v:\n", host))
 
-		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		writeMX(&retVal, targets, infos)
 
-		securityHeaders(w)
+		return retVal.String(), nil
+	}
+}
 
-		ctx, err := getBulkClient()
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+func parseNS(backend ASNBackend, resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		records, err := resolver.LookupNS(ctx, host)
+		if len(records) == 0 || err != nil {
+			return "", err
+		}
 
-			w.WriteHeader(http.StatusInternalServerError)
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].Host < records[j].Host
+		})
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
+		targets := make([]nameserverTarget, len(records))
 
-			return
+		for i, record := range records {
+			targets[i] = nameserverTarget{host: record.Host}
 		}
 
-		host := strings.TrimPrefix(p.ByName("host"), "/")
+		infos := resolveTargets(ctx, targets, backend, resolver)
 
-		parsedHost, err := parseNS(ctx, resolver, host)
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		var retVal strings.Builder
 
-			w.WriteHeader(http.StatusInternalServerError)
+		retVal.WriteString(fmt.Sprintf("%v:\n", host))
 
-			_, err = w.Write([]byte("Lookup failed\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-			}
+		for _, target := range targets {
+			retVal.WriteString(fmt.Sprintf("\n  %v:\n", strings.TrimRight(target.host, ".")))
 
-			return
+			writeTarget(&retVal, target, infos)
 		}
 
-		if verbose {
-			fmt.Printf("%s | %s => %s\n",
-				startTime.Format(timeFormats["RFC3339"]),
-				realIP(r, true),
-				r.RequestURI)
-		}
+		return retVal.String(), nil
+	}
+}
 
-		_, err = w.Write([]byte(parsedHost + "\n"))
-		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+// newResolver returns a resolver that sends its queries to the upstreams set
+// with --dns-resolver, falling back to the system resolver.
+func newResolver(server *upstreamSet) *net.Resolver {
+	if server.system {
+		return net.DefaultResolver
+	}
 
-			return
-		}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return &upstreamConn{ctx: ctx, set: server}, nil
+		},
 	}
 }
 
-func registerDNS(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
+func registerDNS(mux *httprouter.Router, usage *sync.Map, backend ASNBackend, server *upstreamSet, compared []comparedResolver, hints rootHints, anchors trustAnchors, errorChannel chan<- Error) {
 	const module = "dns"
 
-	var resolver *net.Resolver
-
-	if dnsResolver != "" {
-		resolver = &net.Resolver{
-			PreferGo: true,
-			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				d := net.Dialer{
-					Timeout: time.Millisecond * time.Duration(10000),
-				}
-				return d.DialContext(ctx, network, dnsResolver)
-			},
-		}
-	} else {
-		resolver = net.DefaultResolver
-	}
+	resolver := newResolver(server)
 
 	mux.GET("/dns/", serveUsage(module, usage, errorChannel))
 
-	mux.GET("/dns/a/:host", serveHostRecord("ip4", resolver, errorChannel))
+	mux.GET("/dns/a/:host", serveLookup(parseHost("ip4", backend, resolver), errorChannel))
 	mux.GET("/dns/a/", serveUsage(module, usage, errorChannel))
 
-	mux.GET("/dns/aaaa/:host", serveHostRecord("ip6", resolver, errorChannel))
+	mux.GET("/dns/aaaa/:host", serveLookup(parseHost("ip6", backend, resolver), errorChannel))
 	mux.GET("/dns/aaaa/", serveUsage(module, usage, errorChannel))
 
-	mux.GET("/dns/host/:host", serveHostRecord("ip", resolver, errorChannel))
+	mux.GET("/dns/host/:host", serveLookup(parseHost("ip", backend, resolver), errorChannel))
 	mux.GET("/dns/host/", serveUsage(module, usage, errorChannel))
 
-	mux.GET("/dns/mx/:host", serveMXRecord(resolver, errorChannel))
+	mux.GET("/dns/mx/:host", serveLookup(parseMX(backend, resolver), errorChannel))
 	mux.GET("/dns/mx/", serveUsage(module, usage, errorChannel))
 
-	mux.GET("/dns/ns/:host", serveNSRecord(resolver, errorChannel))
+	mux.GET("/dns/ns/:host", serveLookup(parseNS(backend, resolver), errorChannel))
 	mux.GET("/dns/ns/", serveUsage(module, usage, errorChannel))
 
+	mux.GET("/dns/txt/:host", serveLookup(parseTXT(resolver), errorChannel))
+	mux.GET("/dns/txt/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/cname/:host", serveLookup(parseCNAME(server), errorChannel))
+	mux.GET("/dns/cname/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/srv/:host", serveLookup(parseSRV(resolver), errorChannel))
+	mux.GET("/dns/srv/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/ptr/:host", serveLookup(parsePTR(resolver), errorChannel))
+	mux.GET("/dns/ptr/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/caa/:host", serveLookup(parseCAA(server), errorChannel))
+	mux.GET("/dns/caa/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/query/:type/:name", serveDNSQuery(server, errorChannel))
+	mux.GET("/dns/query/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/compare/:type/:name", serveDNSCompare(compared, errorChannel))
+	mux.GET("/dns/compare/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/trace/:name", serveDNSTrace(server, hints, errorChannel))
+	mux.GET("/dns/trace/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/dnssec/:type/:name", serveDNSSEC(server, anchors, errorChannel))
+	mux.GET("/dns/dnssec/", serveUsage(module, usage, errorChannel))
+
+	mux.GET("/dns/mail/:domain", serveMailCheck(newMailChecker(backend, resolver), errorChannel))
+	mux.GET("/dns/mail/", serveUsage(module, usage, errorChannel))
+
+	doh := &dohResolver{server: server, cache: newDoHCache()}
+	limiter := newRateLimiter(dohRateLimit)
+
+	mux.GET("/dns-query", serveDoH(doh, limiter, errorChannel))
+	mux.POST("/dns-query", serveDoH(doh, limiter, errorChannel))
+
 	usage.Store(module, []string{
 		"/dns/a/google.com",
 		"/dns/aaaa/google.com",
 		"/dns/host/google.com",
 		"/dns/mx/google.com",
 		"/dns/ns/google.com",
+		"/dns/txt/google.com",
+		"/dns/cname/www.github.com",
+		"/dns/srv/_xmpp-server._tcp.google.com",
+		"/dns/ptr/8.8.8.8",
+		"/dns/caa/google.com",
+		"/dns/query/soa/google.com",
+		"/dns/query/https/cloudflare.com",
+		"/dns/query/dnskey/ietf.org?dnssec",
+		"/dns/compare/a/google.com",
+		"/dns/trace/www.google.com",
+		"/dns/dnssec/a/ietf.org",
+		"/dns/mail/google.com?dkim=20230601",
+		"/dns-query?name=google.com&type=A",
 	})
 }
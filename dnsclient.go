@@ -0,0 +1,227 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	dnsLookupTimeout = 15 * time.Second
+	dnsQueryTimeout  = 5 * time.Second
+
+	// dnsUDPSize is the EDNS buffer size advertised in queries, chosen to
+	// avoid IP fragmentation as recommended by DNS Flag Day 2020.
+	dnsUDPSize = 1232
+)
+
+var ErrDNSResponse = errors.New("invalid DNS response")
+
+// dnsServer returns the first nameserver listed in /etc/resolv.conf.
+func dnsServer() string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err == nil {
+		defer f.Close()
+
+		s := bufio.NewScanner(f)
+
+		for s.Scan() {
+			fields := strings.Fields(s.Text())
+
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+
+	return "127.0.0.1:53"
+}
+
+// fqdn returns a DNS name in fully-qualified form.
+func fqdn(name string) (dnsmessage.Name, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	return dnsmessage.NewName(name)
+}
+
+// dnsRequest describes a single query made by the wire-level DNS client.
+type dnsRequest struct {
+	server *upstreamSet
+	name   string
+	qtype  dnsmessage.Type
+
+	// dnssec sets the DNSSEC OK bit, asking the server to include
+	// signatures, and checkingDisabled asks it not to validate them.
+	dnssec           bool
+	checkingDisabled bool
+
+	// iterative clears the RD bit, for queries sent straight to
+	// authoritative servers.
+	iterative bool
+
+	// tcp skips UDP and sends the query over TCP straight away. It has no
+	// effect on DNS-over-TLS and DNS-over-HTTPS upstreams.
+	tcp bool
+}
+
+// dnsReply is the response to a dnsRequest, along with the protocol it was
+// received over, the upstream that sent it and how long the exchange took.
+type dnsReply struct {
+	message  *dnsmessage.Message
+	protocol string
+	server   string
+	elapsed  time.Duration
+}
+
+func newQuery(req dnsRequest) ([]byte, uint16, error) {
+	name, err := fqdn(req.name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	id := uint16(rand.Uint32())
+
+	// The AD bit is set so that validating resolvers report whether the
+	// answer was authenticated, as described in RFC 6840.
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:               id,
+		RecursionDesired: !req.iterative,
+		AuthenticData:    true,
+		CheckingDisabled: req.checkingDisabled,
+	})
+
+	builder.EnableCompression()
+
+	err = builder.StartQuestions()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = builder.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  req.qtype,
+		Class: dnsmessage.ClassINET,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = builder.StartAdditionals()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var opt dnsmessage.ResourceHeader
+
+	err = opt.SetEDNS0(dnsUDPSize, dnsmessage.RCodeSuccess, req.dnssec)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = builder.OPTResource(opt, dnsmessage.OPTResource{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err := builder.Finish()
+
+	return query, id, err
+}
+
+func exchangeUDP(ctx context.Context, server string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+
+	// Responses with the wrong ID are discarded rather than treated as an
+	// error, as they may be late replies to an earlier query.
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		var msg dnsmessage.Message
+
+		err = msg.Unpack(buf[:n])
+		if err != nil || msg.ID != id || !msg.Response {
+			continue
+		}
+
+		return &msg, nil
+	}
+}
+
+func exchangeTCP(ctx context.Context, server string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	return exchangeStream(conn, query, id)
+}
+
+// exchange sends the request to the configured upstreams.
+func (req dnsRequest) exchange(ctx context.Context) (dnsReply, error) {
+	query, id, err := newQuery(req)
+	if err != nil {
+		return dnsReply{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	startTime := time.Now()
+
+	reply := req.server.exchange(ctx, query, id, req.tcp)
+	if reply.err != nil {
+		return dnsReply{}, reply.err
+	}
+
+	return dnsReply{reply.message, reply.protocol, reply.server, time.Since(startTime)}, nil
+}
+
+// dnsExchange sends a recursive query to the configured upstreams.
+func dnsExchange(ctx context.Context, server *upstreamSet, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	reply, err := dnsRequest{server: server, name: name, qtype: qtype}.exchange(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.message, nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubServer answers DNS queries over both UDP and TCP on the same local
+// port, counting the queries received over each.
+type stubServer struct {
+	addr     string
+	truncate bool
+	udp      atomic.Int32
+	tcp      atomic.Int32
+}
+
+// newStubServer starts a stub answering every A query with 192.0.2.1. If
+// truncate is set, responses sent over UDP have no answers and the TC bit
+// set, as a server does when a response is too large for UDP.
+func newStubServer(t *testing.T, truncate bool) *stubServer {
+	t.Helper()
+
+	s := &stubServer{truncate: truncate}
+
+	// The TCP listener takes the port the UDP socket was given, which is
+	// very rarely already in use.
+	var (
+		conn     net.PacketConn
+		listener net.Listener
+		err      error
+	)
+
+	for range 10 {
+		conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		listener, err = net.Listen("tcp", conn.LocalAddr().String())
+		if err == nil {
+			break
+		}
+
+		conn.Close()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		listener.Close()
+	})
+
+	s.addr = conn.LocalAddr().String()
+
+	go s.serveUDP(conn)
+	go s.serveTCP(listener)
+
+	return s
+}
+
+func (s *stubServer) respond(query dnsmessage.Message, tcp bool) dnsmessage.Message {
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.ID,
+			Response:           true,
+			RecursionDesired:   query.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: query.Questions,
+	}
+
+	if s.truncate && !tcp {
+		response.Truncated = true
+
+		return response
+	}
+
+	for _, question := range query.Questions {
+		if question.Type != dnsmessage.TypeA {
+			continue
+		}
+
+		response.Answers = append(response.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: netip.MustParseAddr("192.0.2.1").As4()},
+		})
+	}
+
+	return response
+}
+
+func (s *stubServer) serveUDP(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		var query dnsmessage.Message
+
+		if query.Unpack(buf[:n]) != nil {
+			continue
+		}
+
+		s.udp.Add(1)
+
+		response := s.respond(query, false)
+
+		packed, err := response.Pack()
+		if err != nil {
+			continue
+		}
+
+		conn.WriteTo(packed, addr)
+	}
+}
+
+func (s *stubServer) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			for {
+				var length [2]byte
+
+				_, err := io.ReadFull(conn, length[:])
+				if err != nil {
+					return
+				}
+
+				buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+
+				_, err = io.ReadFull(conn, buf)
+				if err != nil {
+					return
+				}
+
+				var query dnsmessage.Message
+
+				if query.Unpack(buf) != nil {
+					return
+				}
+
+				s.tcp.Add(1)
+
+				response := s.respond(query, true)
+
+				packed, err := response.Pack()
+				if err != nil {
+					return
+				}
+
+				_, err = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...))
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func newTestUpstreams(t *testing.T, strategy string, addresses ...string) *upstreamSet {
+	t.Helper()
+
+	set, err := newUpstreamSet(addresses, strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return set
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name     string
+		truncate bool
+		tcp      bool
+		protocol string
+
+		// The number of queries the server should receive over each.
+		udpQueries, tcpQueries int32
+	}{
+		{name: "UDP", protocol: "UDP", udpQueries: 1},
+		{name: "truncated", truncate: true, protocol: "TCP", udpQueries: 1, tcpQueries: 1},
+		{name: "TCP only", tcp: true, protocol: "TCP", tcpQueries: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := newStubServer(t, test.truncate)
+
+			req := dnsRequest{
+				server: newTestUpstreams(t, strategyFailover, stub.addr),
+				name:   "example.com",
+				qtype:  dnsmessage.TypeA,
+				tcp:    test.tcp,
+			}
+
+			reply, err := req.exchange(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reply.protocol != test.protocol {
+				t.Errorf("protocol = %s, want %s", reply.protocol, test.protocol)
+			}
+
+			if reply.server != stub.addr {
+				t.Errorf("server = %s, want %s", reply.server, stub.addr)
+			}
+
+			if reply.message.Truncated || len(reply.message.Answers) != 1 {
+				t.Errorf("got %d answers, truncated %t, want 1 answer", len(reply.message.Answers), reply.message.Truncated)
+			}
+
+			if udp, tcp := stub.udp.Load(), stub.tcp.Load(); udp != test.udpQueries || tcp != test.tcpQueries {
+				t.Errorf("queries over UDP, TCP = %d, %d, want %d, %d", udp, tcp, test.udpQueries, test.tcpQueries)
+			}
+		})
+	}
+}
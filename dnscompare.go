@@ -0,0 +1,301 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/text/message"
+)
+
+const (
+	// compareWorkers is the number of resolvers queried at once, and
+	// compareTimeout how long each one is given to respond.
+	compareWorkers = 8
+	compareTimeout = 3 * time.Second
+)
+
+// comparedResolver is one of the resolvers set with --dns-compare.
+type comparedResolver struct {
+	name   string
+	server *upstreamSet
+}
+
+// parseComparedResolvers accepts resolvers as name=resolver, where the
+// resolver takes any form accepted by --dns-resolver. Resolvers given
+// without a name are named after themselves.
+func parseComparedResolvers(entries []string) ([]comparedResolver, error) {
+	var resolvers []comparedResolver
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		name, address, found := strings.Cut(entry, "=")
+		if !found {
+			name, address = entry, entry
+		}
+
+		u, err := parseUpstream(address)
+		if err != nil {
+			return nil, err
+		}
+
+		resolvers = append(resolvers, comparedResolver{
+			name:   name,
+			server: &upstreamSet{upstreams: []*upstream{u}, strategy: strategyFailover},
+		})
+	}
+
+	return resolvers, nil
+}
+
+type DNSCompareAnswer struct {
+	Resolver string      `json:"resolver"`
+	Server   string      `json:"server"`
+	Status   string      `json:"status,omitempty"`
+	Answer   []DNSRecord `json:"answer"`
+	Time     int64       `json:"time_ms"`
+	Error    string      `json:"error,omitempty"`
+	Agrees   bool        `json:"agrees"`
+}
+
+type DNSCompareResult struct {
+	Name       string             `json:"name"`
+	Type       string             `json:"type"`
+	Consistent bool               `json:"consistent"`
+	Answers    []DNSCompareAnswer `json:"answers"`
+}
+
+// answerKey summarises an answer for comparison. TTLs, ordering and case
+// are ignored, as they differ between resolvers without the answers doing so.
+func answerKey(answer DNSCompareAnswer) string {
+	var records []string
+
+	for _, record := range answer.Answer {
+		if record.Type == "RRSIG" {
+			continue
+		}
+
+		records = append(records, strings.ToLower(record.Name+" "+record.Type+" "+record.Data))
+	}
+
+	slices.Sort(records)
+
+	return answer.Status + "\n" + strings.Join(records, "\n")
+}
+
+// compareAnswers marks each answer that matches the one given by most
+// resolvers, and reports whether every resolver that responded agreed.
+// Ties go to the answer seen first.
+func compareAnswers(answers []DNSCompareAnswer) bool {
+	counts := make(map[string]int)
+
+	var keys []string
+
+	for _, answer := range answers {
+		if answer.Error != "" {
+			continue
+		}
+
+		key := answerKey(answer)
+
+		if counts[key] == 0 {
+			keys = append(keys, key)
+		}
+
+		counts[key]++
+	}
+
+	majority := ""
+
+	for _, key := range keys {
+		if majority == "" || counts[key] > counts[majority] {
+			majority = key
+		}
+	}
+
+	for i := range answers {
+		answers[i].Agrees = answers[i].Error == "" && answerKey(answers[i]) == majority
+	}
+
+	return len(keys) == 1
+}
+
+func compareResolvers(ctx context.Context, resolvers []comparedResolver, name string, qtype dnsmessage.Type) DNSCompareResult {
+	answers := make([]DNSCompareAnswer, len(resolvers))
+
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(compareWorkers, len(resolvers)) {
+		wg.Go(func() {
+			for i := range jobs {
+				answers[i] = queryComparedResolver(ctx, resolvers[i], name, qtype)
+			}
+		})
+	}
+
+	for i := range resolvers {
+		jobs <- i
+	}
+
+	close(jobs)
+
+	wg.Wait()
+
+	return DNSCompareResult{
+		Name:       name,
+		Type:       typeName(qtype),
+		Consistent: compareAnswers(answers),
+		Answers:    answers,
+	}
+}
+
+func queryComparedResolver(ctx context.Context, resolver comparedResolver, name string, qtype dnsmessage.Type) DNSCompareAnswer {
+	ctx, cancel := context.WithTimeout(ctx, compareTimeout)
+	defer cancel()
+
+	answer := DNSCompareAnswer{
+		Resolver: resolver.name,
+		Server:   resolver.server.String(),
+		Answer:   []DNSRecord{},
+	}
+
+	startTime := time.Now()
+
+	req := dnsRequest{server: resolver.server, name: name, qtype: qtype}
+
+	reply, err := req.exchange(ctx)
+
+	answer.Time = time.Since(startTime).Milliseconds()
+
+	if err != nil {
+		answer.Error = err.Error()
+
+		return answer
+	}
+
+	result := newDNSQueryResult(req, reply)
+
+	answer.Status = result.Status
+	answer.Answer = result.Answer
+
+	return answer
+}
+
+// Text renders a summary line for each resolver, followed by the records
+// each one returned.
+func (result DNSCompareResult) Text(pr *message.Printer) string {
+	var output strings.Builder
+
+	consistent := pr.Sprintf("No")
+	if result.Consistent {
+		consistent = pr.Sprintf("Yes")
+	}
+
+	output.WriteString(formatFields(pr, [][2]string{
+		{"Query", result.Name + " " + result.Type},
+		{"Consistent", consistent},
+	}))
+
+	output.WriteString("\n; " + pr.Sprintf("Resolvers") + "\n")
+
+	tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	for _, answer := range result.Answers {
+		var note string
+
+		switch {
+		case answer.Error != "":
+			note = pr.Sprintf("Failed") + ": " + answer.Error
+		case !answer.Agrees:
+			note = pr.Sprintf("Differs")
+		}
+
+		status := answer.Status
+		if status == "" {
+			status = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%dms\t%s\n", answer.Resolver, answer.Server, status, answer.Time, note)
+	}
+
+	tw.Flush()
+
+	for _, answer := range result.Answers {
+		if len(answer.Answer) == 0 {
+			continue
+		}
+
+		output.WriteString("\n; " + answer.Resolver + "\n")
+
+		tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+		for _, record := range answer.Answer {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", record.Name, record.TTL, record.Class, record.Type, record.Data)
+		}
+
+		tw.Flush()
+	}
+
+	return output.String()
+}
+
+func serveDNSCompare(resolvers []comparedResolver, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		qtype, err := parseType(p.ByName("type"))
+		if err != nil {
+			serveDNSQueryError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		name := p.ByName("name")
+
+		if !validName(name) {
+			serveDNSQueryError(w, r, pr, ErrInvalidName, errorChannel)
+
+			return
+		}
+
+		result := compareResolvers(r.Context(), resolvers, name, qtype)
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(result.Text(pr)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
@@ -0,0 +1,879 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/text/message"
+)
+
+var (
+	ErrInvalidRecordType = errors.New("invalid record type")
+	ErrInvalidName       = errors.New("invalid name")
+)
+
+// dnsTypes maps the mnemonics accepted by /dns/query/ to their record types.
+// Any other type can be requested in the TYPEnnn form from RFC 3597.
+var dnsTypes = map[string]dnsmessage.Type{
+	"A":          dnsmessage.TypeA,
+	"NS":         dnsmessage.TypeNS,
+	"CNAME":      dnsmessage.TypeCNAME,
+	"SOA":        dnsmessage.TypeSOA,
+	"PTR":        dnsmessage.TypePTR,
+	"HINFO":      dnsmessage.TypeHINFO,
+	"MX":         dnsmessage.TypeMX,
+	"TXT":        dnsmessage.TypeTXT,
+	"AAAA":       dnsmessage.TypeAAAA,
+	"SRV":        dnsmessage.TypeSRV,
+	"NAPTR":      35,
+	"DNAME":      39,
+	"DS":         typeDS,
+	"SSHFP":      44,
+	"RRSIG":      typeRRSIG,
+	"NSEC":       typeNSEC,
+	"DNSKEY":     typeDNSKEY,
+	"NSEC3":      typeNSEC3,
+	"NSEC3PARAM": 51,
+	"TLSA":       52,
+	"SMIMEA":     53,
+	"CDS":        59,
+	"CDNSKEY":    60,
+	"OPENPGPKEY": 61,
+	"SVCB":       dnsmessage.TypeSVCB,
+	"HTTPS":      dnsmessage.TypeHTTPS,
+	"ANY":        dnsmessage.TypeALL,
+	"CAA":        typeCAA,
+}
+
+var dnsTypeNames = func() map[dnsmessage.Type]string {
+	names := make(map[dnsmessage.Type]string, len(dnsTypes)+1)
+
+	for name, t := range dnsTypes {
+		names[t] = name
+	}
+
+	names[dnsmessage.TypeOPT] = "OPT"
+
+	return names
+}()
+
+var dnsRCodes = map[dnsmessage.RCode]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+	16: "BADVERS",
+}
+
+var ednsOptions = map[uint16]string{
+	3:  "NSID",
+	8:  "ECS",
+	10: "COOKIE",
+	12: "PADDING",
+	15: "EDE",
+}
+
+var svcParamNames = map[dnsmessage.SVCParamKey]string{
+	dnsmessage.SVCParamMandatory:          "mandatory",
+	dnsmessage.SVCParamALPN:               "alpn",
+	dnsmessage.SVCParamNoDefaultALPN:      "no-default-alpn",
+	dnsmessage.SVCParamPort:               "port",
+	dnsmessage.SVCParamIPv4Hint:           "ipv4hint",
+	dnsmessage.SVCParamECH:                "ech",
+	dnsmessage.SVCParamIPv6Hint:           "ipv6hint",
+	dnsmessage.SVCParamDOHPath:            "dohpath",
+	dnsmessage.SVCParamOHTTP:              "ohttp",
+	dnsmessage.SVCParamTLSSupportedGroups: "tls-supported-groups",
+}
+
+var base32Hex = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+type DNSRecord struct {
+	Name  string `json:"name"`
+	TTL   uint32 `json:"ttl"`
+	Class string `json:"class"`
+	Type  string `json:"type"`
+	Data  string `json:"data"`
+}
+
+type DNSEDNS struct {
+	Version  uint8    `json:"version"`
+	UDPSize  uint16   `json:"udp_size"`
+	DNSSECOK bool     `json:"dnssec_ok"`
+	Options  []string `json:"options,omitempty"`
+}
+
+type DNSQueryResult struct {
+	Server     string      `json:"server"`
+	Protocol   string      `json:"protocol"`
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Status     string      `json:"status"`
+	Flags      []string    `json:"flags"`
+	EDNS       *DNSEDNS    `json:"edns,omitempty"`
+	Answer     []DNSRecord `json:"answer"`
+	Authority  []DNSRecord `json:"authority"`
+	Additional []DNSRecord `json:"additional"`
+	Time       int64       `json:"time_ms"`
+}
+
+func typeName(t dnsmessage.Type) string {
+	if name, ok := dnsTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+func className(c dnsmessage.Class) string {
+	switch c {
+	case dnsmessage.ClassINET:
+		return "IN"
+	case dnsmessage.ClassCHAOS:
+		return "CH"
+	case dnsmessage.ClassHESIOD:
+		return "HS"
+	case dnsmessage.ClassANY:
+		return "ANY"
+	default:
+		return fmt.Sprintf("CLASS%d", c)
+	}
+}
+
+func rcodeName(rcode dnsmessage.RCode) string {
+	if name, ok := dnsRCodes[rcode]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// parseType reads a record type given either as a mnemonic or in the TYPEnnn
+// form. Meta-types which cannot be answered by a single query are rejected.
+func parseType(s string) (dnsmessage.Type, error) {
+	s = strings.ToUpper(s)
+
+	if t, ok := dnsTypes[s]; ok {
+		return t, nil
+	}
+
+	n, err := strconv.ParseUint(strings.TrimPrefix(s, "TYPE"), 10, 16)
+	if err != nil || !strings.HasPrefix(s, "TYPE") || n == 0 {
+		return 0, ErrInvalidRecordType
+	}
+
+	switch t := dnsmessage.Type(n); t {
+	case dnsmessage.TypeOPT, 251, dnsmessage.TypeAXFR:
+		return 0, ErrInvalidRecordType
+	default:
+		return t, nil
+	}
+}
+
+// validName reports whether a name can be sent in a query, with every label
+// between 1 and 63 bytes long. Only the root may end in an empty label.
+func validName(name string) bool {
+	if name == "." {
+		return true
+	}
+
+	name = strings.TrimSuffix(name, ".")
+
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for label := range strings.SplitSeq(name, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// headerFlags lists the flags set in a message header, in the order used by
+// dig.
+func headerFlags(h dnsmessage.Header) []string {
+	flags := []string{}
+
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"qr", h.Response},
+		{"aa", h.Authoritative},
+		{"tc", h.Truncated},
+		{"rd", h.RecursionDesired},
+		{"ra", h.RecursionAvailable},
+		{"ad", h.AuthenticData},
+		{"cd", h.CheckingDisabled},
+	} {
+		if flag.set {
+			flags = append(flags, flag.name)
+		}
+	}
+
+	return flags
+}
+
+func typeNames(types []dnsmessage.Type) []string {
+	names := make([]string, len(types))
+
+	for i, t := range types {
+		names[i] = typeName(t)
+	}
+
+	return names
+}
+
+// quoteString writes a character-string in presentation format, escaping
+// quotes, backslashes and unprintable bytes.
+func quoteString(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func upperHex(data []byte) string {
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// readName reads an uncompressed domain name from record data, as found in
+// types where RFC 3597 forbids compression.
+func readName(data []byte, off int) (string, int, error) {
+	var labels []string
+
+	for {
+		if off >= len(data) {
+			return "", 0, ErrDNSResponse
+		}
+
+		length := int(data[off])
+		off++
+
+		if length == 0 {
+			break
+		}
+
+		if length > 63 || off+length > len(data) {
+			return "", 0, ErrDNSResponse
+		}
+
+		var label strings.Builder
+
+		for _, c := range data[off : off+length] {
+			switch {
+			case c == '.' || c == '\\':
+				label.WriteByte('\\')
+				label.WriteByte(c)
+			case c <= ' ' || c > '~':
+				fmt.Fprintf(&label, "\\%03d", c)
+			default:
+				label.WriteByte(c)
+			}
+		}
+
+		labels = append(labels, label.String())
+
+		off += length
+	}
+
+	return strings.Join(labels, ".") + ".", off, nil
+}
+
+// readString reads a length-prefixed character-string from record data.
+func readString(data []byte, off int) (string, int, error) {
+	if off >= len(data) || off+1+int(data[off]) > len(data) {
+		return "", 0, ErrDNSResponse
+	}
+
+	end := off + 1 + int(data[off])
+
+	return string(data[off+1 : end]), end, nil
+}
+
+// typeBitmap decodes the list of types in an NSEC or NSEC3 record, as
+// defined in RFC 4034.
+func typeBitmap(data []byte) ([]dnsmessage.Type, error) {
+	var types []dnsmessage.Type
+
+	for len(data) > 0 {
+		if len(data) < 2 || data[1] == 0 || data[1] > 32 || len(data) < 2+int(data[1]) {
+			return nil, ErrDNSResponse
+		}
+
+		window := int(data[0])
+
+		for i, b := range data[2 : 2+data[1]] {
+			for bit := range 8 {
+				if b&(0x80>>bit) != 0 {
+					types = append(types, dnsmessage.Type(window<<8|i<<3|bit))
+				}
+			}
+		}
+
+		data = data[2+data[1]:]
+	}
+
+	return types, nil
+}
+
+// unknownRecord renders record data which dnsmessage does not decode itself,
+// in the presentation format defined for its type.
+func unknownRecord(t dnsmessage.Type, data []byte) (string, error) {
+	switch t {
+	case typeCAA:
+		return caaRecord(data)
+	case dnsmessage.TypeHINFO:
+		cpu, off, err := readString(data, 0)
+		if err != nil {
+			return "", err
+		}
+
+		system, _, err := readString(data, off)
+		if err != nil {
+			return "", err
+		}
+
+		return quoteString(cpu) + " " + quoteString(system), nil
+	case 35: // NAPTR
+		if len(data) < 4 {
+			return "", ErrDNSResponse
+		}
+
+		fields := []string{
+			strconv.Itoa(int(binary.BigEndian.Uint16(data))),
+			strconv.Itoa(int(binary.BigEndian.Uint16(data[2:]))),
+		}
+
+		off := 4
+
+		for range 3 {
+			var s string
+			var err error
+
+			s, off, err = readString(data, off)
+			if err != nil {
+				return "", err
+			}
+
+			fields = append(fields, quoteString(s))
+		}
+
+		replacement, _, err := readName(data, off)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.Join(append(fields, replacement), " "), nil
+	case 39: // DNAME
+		name, _, err := readName(data, 0)
+
+		return name, err
+	case typeDS, 59: // CDS
+		if len(data) < 4 {
+			return "", ErrDNSResponse
+		}
+
+		return fmt.Sprintf("%d %d %d %s",
+			binary.BigEndian.Uint16(data), data[2], data[3], upperHex(data[4:])), nil
+	case 44: // SSHFP
+		if len(data) < 2 {
+			return "", ErrDNSResponse
+		}
+
+		return fmt.Sprintf("%d %d %s", data[0], data[1], upperHex(data[2:])), nil
+	case typeRRSIG:
+		if len(data) < 18 {
+			return "", ErrDNSResponse
+		}
+
+		signer, off, err := readName(data, 18)
+		if err != nil {
+			return "", err
+		}
+
+		const timestamp = "20060102150405"
+
+		return fmt.Sprintf("%s %d %d %d %s %s %d %s %s",
+			typeName(dnsmessage.Type(binary.BigEndian.Uint16(data))),
+			data[2],
+			data[3],
+			binary.BigEndian.Uint32(data[4:]),
+			time.Unix(int64(binary.BigEndian.Uint32(data[8:])), 0).UTC().Format(timestamp),
+			time.Unix(int64(binary.BigEndian.Uint32(data[12:])), 0).UTC().Format(timestamp),
+			binary.BigEndian.Uint16(data[16:]),
+			signer,
+			base64.StdEncoding.EncodeToString(data[off:])), nil
+	case typeNSEC:
+		nsec, err := parseNSEC(data)
+		if err != nil {
+			return "", err
+		}
+
+		return strings.Join(append([]string{nsec.next}, typeNames(nsec.types)...), " "), nil
+	case typeDNSKEY, 60: // CDNSKEY
+		if len(data) < 4 {
+			return "", ErrDNSResponse
+		}
+
+		return fmt.Sprintf("%d %d %d %s",
+			binary.BigEndian.Uint16(data), data[2], data[3], base64.StdEncoding.EncodeToString(data[4:])), nil
+	case typeNSEC3, 51: // NSEC3PARAM
+		nsec3, err := parseNSEC3(data, t == 51)
+		if err != nil {
+			return "", err
+		}
+
+		salt := "-"
+		if len(nsec3.salt) > 0 {
+			salt = upperHex(nsec3.salt)
+		}
+
+		params := fmt.Sprintf("%d %d %d %s", nsec3.hashAlgorithm, nsec3.flags, nsec3.iterations, salt)
+
+		if t == 51 {
+			return params, nil
+		}
+
+		return strings.Join(append([]string{params, nsec3.next}, typeNames(nsec3.types)...), " "), nil
+	case 52, 53: // TLSA, SMIMEA
+		if len(data) < 3 {
+			return "", ErrDNSResponse
+		}
+
+		return fmt.Sprintf("%d %d %d %s", data[0], data[1], data[2], upperHex(data[3:])), nil
+	case 61: // OPENPGPKEY
+		return base64.StdEncoding.EncodeToString(data), nil
+	default:
+		return genericRecord(data), nil
+	}
+}
+
+// genericRecord renders record data in the form RFC 3597 uses for types
+// without a known presentation format.
+func genericRecord(data []byte) string {
+	if len(data) == 0 {
+		return `\# 0`
+	}
+
+	return fmt.Sprintf(`\# %d %s`, len(data), upperHex(data))
+}
+
+// svcParam renders a single SVCB parameter as defined in RFC 9460.
+func svcParam(param dnsmessage.SVCParam) string {
+	name, ok := svcParamNames[param.Key]
+	if !ok {
+		name = fmt.Sprintf("key%d", param.Key)
+	}
+
+	value := param.Value
+
+	switch param.Key {
+	case dnsmessage.SVCParamNoDefaultALPN, dnsmessage.SVCParamOHTTP:
+		if len(value) == 0 {
+			return name
+		}
+	case dnsmessage.SVCParamMandatory:
+		var keys []string
+
+		for ; len(value) >= 2; value = value[2:] {
+			key := dnsmessage.SVCParamKey(binary.BigEndian.Uint16(value))
+
+			if keyName, ok := svcParamNames[key]; ok {
+				keys = append(keys, keyName)
+			} else {
+				keys = append(keys, fmt.Sprintf("key%d", key))
+			}
+		}
+
+		return name + "=" + strings.Join(keys, ",")
+	case dnsmessage.SVCParamALPN:
+		var ids []string
+
+		for off := 0; off < len(value); {
+			id, next, err := readString(value, off)
+			if err != nil {
+				break
+			}
+
+			ids = append(ids, strings.ReplaceAll(id, ",", `\,`))
+
+			off = next
+		}
+
+		return name + "=" + quoteString(strings.Join(ids, ","))
+	case dnsmessage.SVCParamPort:
+		if len(value) == 2 {
+			return name + "=" + strconv.Itoa(int(binary.BigEndian.Uint16(value)))
+		}
+	case dnsmessage.SVCParamIPv4Hint, dnsmessage.SVCParamIPv6Hint:
+		size := 4
+		if param.Key == dnsmessage.SVCParamIPv6Hint {
+			size = 16
+		}
+
+		var addrs []string
+
+		for ; len(value) >= size; value = value[size:] {
+			addr, _ := netip.AddrFromSlice(value[:size])
+
+			addrs = append(addrs, addr.String())
+		}
+
+		return name + "=" + strings.Join(addrs, ",")
+	case dnsmessage.SVCParamECH:
+		return name + "=" + base64.StdEncoding.EncodeToString(value)
+	case dnsmessage.SVCParamTLSSupportedGroups:
+		var groups []string
+
+		for ; len(value) >= 2; value = value[2:] {
+			groups = append(groups, strconv.Itoa(int(binary.BigEndian.Uint16(value))))
+		}
+
+		return name + "=" + strings.Join(groups, ",")
+	}
+
+	return name + "=" + quoteString(string(value))
+}
+
+// recordData renders the data of a resource record in presentation format.
+func recordData(resource dnsmessage.Resource) string {
+	switch body := resource.Body.(type) {
+	case *dnsmessage.AResource:
+		return netip.AddrFrom4(body.A).String()
+	case *dnsmessage.AAAAResource:
+		return netip.AddrFrom16(body.AAAA).String()
+	case *dnsmessage.NSResource:
+		return body.NS.String()
+	case *dnsmessage.CNAMEResource:
+		return body.CNAME.String()
+	case *dnsmessage.PTRResource:
+		return body.PTR.String()
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", body.Pref, body.MX)
+	case *dnsmessage.SOAResource:
+		return fmt.Sprintf("%s %s %d %d %d %d %d",
+			body.NS, body.MBox, body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL)
+	case *dnsmessage.TXTResource:
+		strs := make([]string, len(body.TXT))
+
+		for i, s := range body.TXT {
+			strs[i] = quoteString(s)
+		}
+
+		return strings.Join(strs, " ")
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target)
+	case *dnsmessage.SVCBResource:
+		return svcbRecord(body)
+	case *dnsmessage.HTTPSResource:
+		return svcbRecord(&body.SVCBResource)
+	case *dnsmessage.UnknownResource:
+		data, err := unknownRecord(resource.Header.Type, body.Data)
+		if err != nil {
+			return genericRecord(body.Data)
+		}
+
+		return data
+	default:
+		return ""
+	}
+}
+
+func svcbRecord(body *dnsmessage.SVCBResource) string {
+	fields := []string{strconv.Itoa(int(body.Priority)), body.Target.String()}
+
+	for _, param := range body.Params {
+		fields = append(fields, svcParam(param))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// ednsOption renders an EDNS option from the OPT pseudo-record. Extended DNS
+// errors from RFC 8914 are decoded, as they explain SERVFAIL responses.
+func ednsOption(option dnsmessage.Option) string {
+	name, ok := ednsOptions[option.Code]
+	if !ok {
+		name = fmt.Sprintf("OPT%d", option.Code)
+	}
+
+	if option.Code == 15 && len(option.Data) >= 2 {
+		info := fmt.Sprintf("%s: %d", name, binary.BigEndian.Uint16(option.Data))
+
+		if text := string(option.Data[2:]); text != "" {
+			info += " " + quoteString(text)
+		}
+
+		return info
+	}
+
+	return name + ": " + upperHex(option.Data)
+}
+
+func dnsRecords(resources []dnsmessage.Resource) []DNSRecord {
+	records := []DNSRecord{}
+
+	for _, resource := range resources {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			continue
+		}
+
+		records = append(records, DNSRecord{
+			Name:  resource.Header.Name.String(),
+			TTL:   resource.Header.TTL,
+			Class: className(resource.Header.Class),
+			Type:  typeName(resource.Header.Type),
+			Data:  recordData(resource),
+		})
+	}
+
+	return records
+}
+
+func newDNSQueryResult(req dnsRequest, reply dnsReply) DNSQueryResult {
+	msg := reply.message
+
+	result := DNSQueryResult{
+		Server:     reply.server,
+		Protocol:   reply.protocol,
+		Name:       req.name,
+		Type:       typeName(req.qtype),
+		Flags:      headerFlags(msg.Header),
+		Answer:     dnsRecords(msg.Answers),
+		Authority:  dnsRecords(msg.Authorities),
+		Additional: dnsRecords(msg.Additionals),
+		Time:       reply.elapsed.Milliseconds(),
+	}
+
+	if len(msg.Questions) > 0 {
+		result.Name = msg.Questions[0].Name.String()
+	}
+
+	rcode := msg.RCode
+
+	for _, resource := range msg.Additionals {
+		opt, ok := resource.Body.(*dnsmessage.OPTResource)
+		if !ok {
+			continue
+		}
+
+		rcode = resource.Header.ExtendedRCode(rcode)
+
+		result.EDNS = &DNSEDNS{
+			Version:  uint8(resource.Header.TTL >> 16),
+			UDPSize:  uint16(resource.Header.Class),
+			DNSSECOK: resource.Header.DNSSECAllowed(),
+		}
+
+		for _, option := range opt.Options {
+			result.EDNS.Options = append(result.EDNS.Options, ednsOption(option))
+		}
+	}
+
+	result.Status = rcodeName(rcode)
+
+	return result
+}
+
+// Text renders the result in a layout similar to dig, with each section as
+// a table of records in presentation format.
+func (result DNSQueryResult) Text(pr *message.Printer) string {
+	var output strings.Builder
+
+	fields := [][2]string{
+		{"Server", fmt.Sprintf("%s (%s)", result.Server, result.Protocol)},
+		{"Query", result.Name + " " + result.Type},
+		{"Status", result.Status},
+		{"Flags", strings.Join(result.Flags, " ")},
+	}
+
+	if result.EDNS != nil {
+		edns := fmt.Sprintf("version %d, udp %d", result.EDNS.Version, result.EDNS.UDPSize)
+		if result.EDNS.DNSSECOK {
+			edns += ", do"
+		}
+
+		fields = append(fields, [2]string{"EDNS", edns})
+
+		for _, option := range result.EDNS.Options {
+			fields = append(fields, [2]string{"Option", option})
+		}
+	}
+
+	fields = append(fields, [2]string{"Time", fmt.Sprintf("%dms", result.Time)})
+
+	output.WriteString(formatFields(pr, fields))
+
+	for _, section := range []struct {
+		name    string
+		records []DNSRecord
+	}{
+		{"Answer", result.Answer},
+		{"Authority", result.Authority},
+		{"Additional", result.Additional},
+	} {
+		if len(section.records) == 0 {
+			continue
+		}
+
+		output.WriteString("\n; " + pr.Sprintf(section.name) + "\n")
+
+		tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+		for _, record := range section.records {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", record.Name, record.TTL, record.Class, record.Type, record.Data)
+		}
+
+		tw.Flush()
+	}
+
+	return output.String()
+}
+
+func serveDNSQuery(server *upstreamSet, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		qtype, err := parseType(p.ByName("type"))
+		if err != nil {
+			serveDNSQueryError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		name := p.ByName("name")
+
+		if !validName(name) {
+			serveDNSQueryError(w, r, pr, ErrInvalidName, errorChannel)
+
+			return
+		}
+
+		req := dnsRequest{
+			server:           server,
+			name:             name,
+			qtype:            qtype,
+			dnssec:           r.URL.Query().Has("dnssec"),
+			checkingDisabled: r.URL.Query().Has("cd"),
+			tcp:              r.URL.Query().Has("tcp"),
+		}
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		defer cancel()
+
+		reply, err := req.exchange(ctx)
+		if err != nil {
+			serveDNSQueryError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		result := newDNSQueryResult(req, reply)
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(result.Text(pr)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
+	}
+}
+
+func serveDNSQueryError(w http.ResponseWriter, r *http.Request, pr *message.Printer, err error, errorChannel chan<- Error) {
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	var output string
+
+	switch {
+	case errors.Is(err, ErrInvalidRecordType):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid record type requested")
+	case errors.Is(err, ErrInvalidName):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid name requested")
+	case errors.Is(err, ErrInvalidSelector):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid DKIM selector requested")
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+
+		output = pr.Sprintf("Lookup failed")
+	}
+
+	_, err = w.Write([]byte(output + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
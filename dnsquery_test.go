@@ -0,0 +1,90 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestUnknownRecord(t *testing.T) {
+	tests := []struct {
+		name  string
+		qtype dnsmessage.Type
+		data  []byte
+		want  string
+	}{
+		{"CAA", typeCAA, []byte{0, 5, 'i', 's', 's', 'u', 'e', 'c', 'a', '.', 'e', 'x'}, `0 issue "ca.ex"`},
+		{"HINFO", dnsmessage.TypeHINFO, []byte{3, 'x', '"', 'y', 5, 'L', 'i', 'n', 'u', 'x'}, `"x\"y" "Linux"`},
+		{"NAPTR", 35, []byte{0, 100, 0, 10, 1, 'u', 7, 'E', '2', 'U', '+', 's', 'i', 'p', 0, 2, 'e', 'x', 0}, `100 10 "u" "E2U+sip" "" ex.`},
+		{"DNAME", 39, []byte{2, 'e', 'x', 3, 'n', 'e', 't', 0}, "ex.net."},
+		{"DS", typeDS, []byte{0x0e, 0x1d, 15, 2, 0x3a, 0xa5}, "3613 15 2 3AA5"},
+		{"SSHFP", 44, []byte{4, 2, 0xde, 0xad}, "4 2 DEAD"},
+		{"NSEC", typeNSEC, []byte{1, 'b', 0, 0, 6, 0x40, 0, 0, 0, 0, 0x03}, "b. A RRSIG NSEC"},
+		{"DNSKEY", typeDNSKEY, []byte{1, 0, 3, 15, 0xff}, "256 3 15 /w=="},
+		{"NSEC3PARAM", 51, []byte{1, 0, 0, 0, 0}, "1 0 0 -"},
+		{"TLSA", 52, []byte{3, 1, 1, 0xab}, "3 1 1 AB"},
+		{"name with dots", 39, []byte{3, 'a', '.', 'b', 0}, `a\.b.`},
+		{"empty", 65280, nil, `\# 0`},
+		{"generic", 65280, []byte{0xab, 0xcd}, `\# 2 ABCD`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := unknownRecord(test.qtype, test.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnknownRecordMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		qtype dnsmessage.Type
+		data  []byte
+	}{
+		{"CAA without tag length", typeCAA, []byte{0}},
+		{"CAA truncated tag", typeCAA, []byte{0, 5, 'i', 's'}},
+		{"HINFO empty", dnsmessage.TypeHINFO, nil},
+		{"HINFO truncated string", dnsmessage.TypeHINFO, []byte{5, 'L', 'i'}},
+		{"HINFO without OS", dnsmessage.TypeHINFO, []byte{1, 'x'}},
+		{"NAPTR too short", 35, []byte{0, 100, 0}},
+		{"NAPTR truncated strings", 35, []byte{0, 100, 0, 10, 1, 'u'}},
+		{"NAPTR without replacement", 35, []byte{0, 100, 0, 10, 0, 0, 0}},
+		{"DNAME unterminated", 39, []byte{2, 'e', 'x'}},
+		{"DNAME label too long", 39, append([]byte{64}, make([]byte, 65)...)},
+		{"DS too short", typeDS, []byte{0, 1, 13}},
+		{"CDS too short", 59, []byte{0}},
+		{"SSHFP too short", 44, []byte{4}},
+		{"RRSIG too short", typeRRSIG, make([]byte, 17)},
+		{"RRSIG without signer", typeRRSIG, make([]byte, 18)},
+		{"NSEC unterminated", typeNSEC, []byte{1, 'b'}},
+		{"NSEC bad bitmap", typeNSEC, []byte{0, 0, 0}},
+		{"DNSKEY too short", typeDNSKEY, []byte{1, 0, 3}},
+		{"CDNSKEY too short", 60, nil},
+		{"NSEC3 truncated salt", typeNSEC3, []byte{1, 0, 0, 0, 8, 0xab}},
+		{"NSEC3 truncated next owner", typeNSEC3, []byte{1, 0, 0, 0, 0, 20, 0xab}},
+		{"NSEC3PARAM too short", 51, []byte{1, 0}},
+		{"TLSA too short", 52, []byte{3, 1}},
+		{"SMIMEA too short", 53, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := unknownRecord(test.qtype, test.data)
+			if !errors.Is(err, ErrDNSResponse) {
+				t.Errorf("got %q, %v, want %v", got, err, ErrDNSResponse)
+			}
+		})
+	}
+}
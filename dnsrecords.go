@@ -0,0 +1,322 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// maxCNAMEChain limits how many aliases are followed, in case a
+	// resolver returns a looping chain.
+	maxCNAMEChain = 16
+
+	typeDS     dnsmessage.Type = 43
+	typeRRSIG  dnsmessage.Type = 46
+	typeNSEC   dnsmessage.Type = 47
+	typeDNSKEY dnsmessage.Type = 48
+	typeNSEC3  dnsmessage.Type = 50
+	typeCAA    dnsmessage.Type = 257
+)
+
+// lookup runs a DNS lookup for a host, returning its formatted output.
+type lookup func(ctx context.Context, host string) (string, error)
+
+// notFound builds the error returned by net.Resolver for names which do not
+// exist, so raw and resolver-backed lookups fail in the same way.
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func parseTXT(resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		records, err := resolver.LookupTXT(ctx, host)
+		if len(records) == 0 || err != nil {
+			return "", err
+		}
+
+		sort.Strings(records)
+
+		var retVal strings.Builder
+
+		retVal.WriteString(fmt.Sprintf("%v:\n\n", host))
+
+		for _, record := range records {
+			retVal.WriteString(fmt.Sprintf("  %v\n", record))
+		}
+
+		return retVal.String(), nil
+	}
+}
+
+// cnameChain follows the aliases for a name through the answer to a single
+// recursive query, returning each name in the order they were followed.
+func cnameChain(ctx context.Context, server *upstreamSet, host string) ([]string, error) {
+	msg, err := dnsExchange(ctx, server, host, dnsmessage.TypeA)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.RCode == dnsmessage.RCodeNameError {
+		return nil, notFound(host)
+	}
+
+	targets := make(map[string]string)
+
+	for _, answer := range msg.Answers {
+		if cname, ok := answer.Body.(*dnsmessage.CNAMEResource); ok {
+			targets[strings.ToLower(answer.Header.Name.String())] = cname.CNAME.String()
+		}
+	}
+
+	chain := []string{strings.TrimSuffix(host, ".")}
+
+	name := strings.ToLower(host)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	for range maxCNAMEChain {
+		target, ok := targets[name]
+		if !ok {
+			break
+		}
+
+		chain = append(chain, strings.TrimSuffix(target, "."))
+
+		name = strings.ToLower(target)
+	}
+
+	return chain, nil
+}
+
+func parseCNAME(server *upstreamSet) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		chain, err := cnameChain(ctx, server, host)
+		if err != nil {
+			return "", err
+		}
+
+		var retVal strings.Builder
+
+		retVal.WriteString(fmt.Sprintf("%v:\n\n", host))
+
+		if len(chain) < 2 {
+			retVal.WriteString("  n/a\n")
+
+			return retVal.String(), nil
+		}
+
+		for i := 1; i < len(chain); i++ {
+			retVal.WriteString(fmt.Sprintf("  %v -> %v\n", chain[i-1], chain[i]))
+		}
+
+		return retVal.String(), nil
+	}
+}
+
+func parseSRV(resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		_, records, err := resolver.LookupSRV(ctx, "", "", host)
+		if len(records) == 0 || err != nil {
+			return "", err
+		}
+
+		// Records are shuffled by weight within each priority, so they are
+		// sorted again to give stable output.
+		sort.SliceStable(records, func(i, j int) bool {
+			switch {
+			case records[i].Priority != records[j].Priority:
+				return records[i].Priority < records[j].Priority
+			case records[i].Weight != records[j].Weight:
+				return records[i].Weight > records[j].Weight
+			default:
+				return records[i].Target < records[j].Target
+			}
+		})
+
+		var retVal strings.Builder
+
+		retVal.WriteString(fmt.Sprintf("%v:\n", host))
+
+		for _, record := range records {
+			retVal.WriteString(fmt.Sprintf("\n  %v:\n    Priority: %v\n    Weight: %v\n    Port: %v\n",
+				strings.TrimRight(record.Target, "."),
+				record.Priority,
+				record.Weight,
+				record.Port))
+		}
+
+		return retVal.String(), nil
+	}
+}
+
+func parsePTR(resolver *net.Resolver) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return "", err
+		}
+
+		hostnames, err := getHostnames(ctx, addr, resolver)
+		if len(hostnames) == 0 || err != nil {
+			return "", err
+		}
+
+		var retVal strings.Builder
+
+		retVal.WriteString(fmt.Sprintf("%v:\n\n", host))
+
+		for _, hostname := range hostnames {
+			retVal.WriteString(fmt.Sprintf("  %v\n", strings.TrimRight(hostname, ".")))
+		}
+
+		return retVal.String(), nil
+	}
+}
+
+// caaRecord decodes the flags, tag and value of a CAA record, as defined in
+// RFC 8659.
+func caaRecord(data []byte) (string, error) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return "", ErrDNSResponse
+	}
+
+	flags := data[0]
+	tag := string(data[2 : 2+data[1]])
+	value := string(data[2+data[1]:])
+
+	return fmt.Sprintf("%d %s %s", flags, tag, strconv.Quote(value)), nil
+}
+
+// findCAA returns the CAA records relevant to a name, climbing towards the
+// root until a name with CAA records is found, as described in RFC 8659.
+func findCAA(ctx context.Context, server *upstreamSet, host string) (string, []string, error) {
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+
+		msg, err := dnsExchange(ctx, server, name, typeCAA)
+		if err != nil {
+			return "", nil, err
+		}
+
+		var records []string
+
+		for _, answer := range msg.Answers {
+			if answer.Header.Type != typeCAA {
+				continue
+			}
+
+			unknown, ok := answer.Body.(*dnsmessage.UnknownResource)
+			if !ok {
+				continue
+			}
+
+			record, err := caaRecord(unknown.Data)
+			if err != nil {
+				return "", nil, err
+			}
+
+			records = append(records, record)
+		}
+
+		if len(records) > 0 {
+			sort.Strings(records)
+
+			return name, records, nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+func parseCAA(server *upstreamSet) lookup {
+	return func(ctx context.Context, host string) (string, error) {
+		name, records, err := findCAA(ctx, server, host)
+		if err != nil {
+			return "", err
+		}
+
+		var retVal strings.Builder
+
+		retVal.WriteString(fmt.Sprintf("%v:\n", host))
+
+		if len(records) == 0 {
+			retVal.WriteString("\n  n/a\n")
+
+			return retVal.String(), nil
+		}
+
+		retVal.WriteString(fmt.Sprintf("\n  %v:\n", name))
+
+		for _, record := range records {
+			retVal.WriteString(fmt.Sprintf("    %v\n", record))
+		}
+
+		return retVal.String(), nil
+	}
+}
+
+func serveLookup(parse lookup, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+		securityHeaders(w)
+
+		host := strings.TrimPrefix(p.ByName("host"), "/")
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		defer cancel()
+
+		parsedHost, err := parse(ctx, host)
+		if err == nil && parsedHost == "" {
+			err = notFound(host)
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			w.WriteHeader(http.StatusInternalServerError)
+
+			_, err = w.Write([]byte(localizer(r).Sprintf("Lookup failed") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		_, err = w.Write([]byte(parsedHost + "\n"))
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
+	}
+}
@@ -0,0 +1,1527 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/text/message"
+)
+
+// DNSSEC algorithm numbers, from the IANA registry.
+const (
+	algRSASHA1          = 5
+	algRSASHA1NSEC3SHA1 = 7
+	algRSASHA256        = 8
+	algRSASHA512        = 10
+	algECDSAP256SHA256  = 13
+	algECDSAP384SHA384  = 14
+	algED25519          = 15
+)
+
+// DS digest types, from the IANA registry.
+const (
+	digestSHA1   = 1
+	digestSHA256 = 2
+	digestSHA384 = 4
+)
+
+const (
+	// dnskeyZoneFlag marks keys which may sign zone data, as opposed to
+	// keys used for other purposes.
+	dnskeyZoneFlag = 0x0100
+
+	nsec3OptOut = 0x01
+
+	// maxNSEC3Iterations is the limit above which RFC 9276 allows responses
+	// to be treated as insecure, to bound the cost of hashing names.
+	maxNSEC3Iterations = 150
+)
+
+var (
+	ErrDNSSECNoSignature  = errors.New("no signatures found")
+	ErrDNSSECExpired      = errors.New("signature has expired")
+	ErrDNSSECNotYetValid  = errors.New("signature is not yet valid")
+	ErrDNSSECNoKey        = errors.New("no key matches the signatures")
+	ErrDNSSECBadSignature = errors.New("signature does not verify")
+	ErrDNSSECAlgorithm    = errors.New("unsupported algorithm")
+	ErrDNSSECNoDenial     = errors.New("no proof of nonexistence")
+	ErrDNSSECTypeExists   = errors.New("denial shows the type exists")
+	ErrDNSSECIterations   = errors.New("too many NSEC3 iterations")
+	ErrInvalidTrustAnchor = errors.New("invalid trust anchor")
+)
+
+// rootTrustAnchors are the DS records for the root zone KSKs published by
+// IANA, used when no trust anchors are configured.
+var rootTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// dnssecStatus is the result of validating a zone or RRset. Values are
+// ordered by severity, so the status of a whole answer is the greatest
+// status of its parts.
+type dnssecStatus int
+
+const (
+	dnssecSecure dnssecStatus = iota
+	dnssecInsecure
+	dnssecIndeterminate
+	dnssecBogus
+)
+
+func (s dnssecStatus) String() string {
+	switch s {
+	case dnssecSecure:
+		return "secure"
+	case dnssecInsecure:
+		return "insecure"
+	case dnssecIndeterminate:
+		return "indeterminate"
+	default:
+		return "bogus"
+	}
+}
+
+type DNSSECLink struct {
+	Zone   string `json:"zone"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type DNSSECRRset struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type DNSSECResult struct {
+	Server  string        `json:"server"`
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Status  string        `json:"status"`
+	Failure string        `json:"failure,omitempty"`
+	Chain   []DNSSECLink  `json:"chain"`
+	Answer  []DNSSECRRset `json:"answer"`
+}
+
+type dsRecord struct {
+	keyTag     uint16
+	algorithm  uint8
+	digestType uint8
+	digest     []byte
+}
+
+type dnskey struct {
+	flags     uint16
+	protocol  uint8
+	algorithm uint8
+	key       []byte
+	rdata     []byte
+}
+
+type rrsig struct {
+	typeCovered dnsmessage.Type
+	algorithm   uint8
+	labels      uint8
+	originalTTL uint32
+	expiration  uint32
+	inception   uint32
+	keyTag      uint16
+	signer      string
+	signature   []byte
+
+	// header holds the record data up to the signature, with the signer
+	// name in canonical form, as it is included in the signed data.
+	header []byte
+}
+
+type nsecRecord struct {
+	next  string
+	types []dnsmessage.Type
+}
+
+type nsec3Record struct {
+	hashAlgorithm uint8
+	flags         uint8
+	iterations    uint16
+	salt          []byte
+	next          string
+	types         []dnsmessage.Type
+}
+
+// rrset is a set of records sharing an owner name and type.
+type rrset struct {
+	name    string
+	qtype   dnsmessage.Type
+	records []dnsmessage.Resource
+}
+
+func parseDS(data []byte) (dsRecord, error) {
+	if len(data) < 4 {
+		return dsRecord{}, ErrDNSResponse
+	}
+
+	return dsRecord{
+		keyTag:     binary.BigEndian.Uint16(data),
+		algorithm:  data[2],
+		digestType: data[3],
+		digest:     data[4:],
+	}, nil
+}
+
+func parseDNSKEY(data []byte) (dnskey, error) {
+	if len(data) < 4 {
+		return dnskey{}, ErrDNSResponse
+	}
+
+	return dnskey{
+		flags:     binary.BigEndian.Uint16(data),
+		protocol:  data[2],
+		algorithm: data[3],
+		key:       data[4:],
+		rdata:     data,
+	}, nil
+}
+
+func parseRRSIG(data []byte) (rrsig, error) {
+	if len(data) < 18 {
+		return rrsig{}, ErrDNSResponse
+	}
+
+	signer, off, err := readName(data, 18)
+	if err != nil {
+		return rrsig{}, err
+	}
+
+	return rrsig{
+		typeCovered: dnsmessage.Type(binary.BigEndian.Uint16(data)),
+		algorithm:   data[2],
+		labels:      data[3],
+		originalTTL: binary.BigEndian.Uint32(data[4:]),
+		expiration:  binary.BigEndian.Uint32(data[8:]),
+		inception:   binary.BigEndian.Uint32(data[12:]),
+		keyTag:      binary.BigEndian.Uint16(data[16:]),
+		signer:      strings.ToLower(signer),
+		signature:   data[off:],
+		header:      append(slices.Clone(data[:18]), lowerASCII(data[18:off])...),
+	}, nil
+}
+
+func parseNSEC(data []byte) (nsecRecord, error) {
+	next, off, err := readName(data, 0)
+	if err != nil {
+		return nsecRecord{}, err
+	}
+
+	types, err := typeBitmap(data[off:])
+	if err != nil {
+		return nsecRecord{}, err
+	}
+
+	return nsecRecord{next: next, types: types}, nil
+}
+
+// parseNSEC3 reads an NSEC3 record, or an NSEC3PARAM record, which holds
+// only the hash parameters.
+func parseNSEC3(data []byte, paramsOnly bool) (nsec3Record, error) {
+	if len(data) < 5 || len(data) < 5+int(data[4]) {
+		return nsec3Record{}, ErrDNSResponse
+	}
+
+	record := nsec3Record{
+		hashAlgorithm: data[0],
+		flags:         data[1],
+		iterations:    binary.BigEndian.Uint16(data[2:]),
+		salt:          data[5 : 5+data[4]],
+	}
+
+	if paramsOnly {
+		return record, nil
+	}
+
+	off := 5 + int(data[4])
+
+	if off >= len(data) || off+1+int(data[off]) > len(data) {
+		return nsec3Record{}, ErrDNSResponse
+	}
+
+	record.next = strings.ToLower(base32Hex.EncodeToString(data[off+1 : off+1+int(data[off])]))
+
+	types, err := typeBitmap(data[off+1+int(data[off]):])
+	if err != nil {
+		return nsec3Record{}, err
+	}
+
+	record.types = types
+
+	return record, nil
+}
+
+// keyTag computes the tag identifying a DNSKEY, as defined in RFC 4034.
+func (k dnskey) keyTag() uint16 {
+	var sum uint32
+
+	for i, b := range k.rdata {
+		if i%2 == 0 {
+			sum += uint32(b) << 8
+		} else {
+			sum += uint32(b)
+		}
+	}
+
+	sum += sum >> 16 & 0xffff
+
+	return uint16(sum)
+}
+
+// digest computes the digest of a DNSKEY used in DS records, returning false
+// if the digest type is not supported.
+func (k dnskey) digest(owner string, digestType uint8) ([]byte, bool) {
+	data := append(wireName(owner, true), k.rdata...)
+
+	switch digestType {
+	case digestSHA1:
+		sum := sha1.Sum(data)
+
+		return sum[:], true
+	case digestSHA256:
+		sum := sha256.Sum256(data)
+
+		return sum[:], true
+	case digestSHA384:
+		sum := sha512.Sum384(data)
+
+		return sum[:], true
+	default:
+		return nil, false
+	}
+}
+
+func supportedAlgorithm(algorithm uint8) bool {
+	switch algorithm {
+	case algRSASHA1, algRSASHA1NSEC3SHA1, algRSASHA256, algRSASHA512,
+		algECDSAP256SHA256, algECDSAP384SHA384, algED25519:
+		return true
+	default:
+		return false
+	}
+}
+
+func supportedDigest(digestType uint8) bool {
+	switch digestType {
+	case digestSHA1, digestSHA256, digestSHA384:
+		return true
+	default:
+		return false
+	}
+}
+
+func lowerASCII(data []byte) []byte {
+	lower := make([]byte, len(data))
+
+	for i, c := range data {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		lower[i] = c
+	}
+
+	return lower
+}
+
+// wireName encodes a domain name in uncompressed wire format, optionally
+// lowercasing it into the canonical form used by DNSSEC.
+func wireName(name string, lower bool) []byte {
+	if lower {
+		name = strings.ToLower(name)
+	}
+
+	var data []byte
+
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+
+		data = append(data, byte(len(label)))
+		data = append(data, label...)
+	}
+
+	return append(data, 0)
+}
+
+// nameLabels returns the labels of a name, from the leftmost to the root.
+func nameLabels(name string) []string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" {
+		return nil
+	}
+
+	return strings.Split(name, ".")
+}
+
+// compareNames orders names as described in RFC 4034, comparing labels from
+// the root downwards.
+func compareNames(a, b string) int {
+	x, y := nameLabels(a), nameLabels(b)
+
+	for i, j := len(x)-1, len(y)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(x[i], y[j]); c != 0 {
+			return c
+		}
+	}
+
+	return len(x) - len(y)
+}
+
+// covers reports whether name falls strictly between owner and next, with
+// the last record in a zone wrapping around to the first.
+func covers(owner, next, name string, compare func(a, b string) int) bool {
+	if compare(owner, next) < 0 {
+		return compare(owner, name) < 0 && compare(name, next) < 0
+	}
+
+	return compare(owner, name) < 0 || compare(name, next) < 0
+}
+
+// ancestors returns the names from the root down to name itself.
+func ancestors(name string) []string {
+	labels := nameLabels(name)
+
+	names := []string{"."}
+
+	for i := len(labels) - 1; i >= 0; i-- {
+		names = append(names, strings.Join(labels[i:], ".")+".")
+	}
+
+	return names
+}
+
+func sameName(a, b string) bool {
+	return compareNames(a, b) == 0
+}
+
+// canonicalData encodes the data of a record in the canonical form from
+// RFC 4034, with embedded names uncompressed and, for the types listed in
+// RFC 4034 and RFC 6840, lowercased.
+func canonicalData(resource dnsmessage.Resource) ([]byte, error) {
+	switch body := resource.Body.(type) {
+	case *dnsmessage.AResource:
+		return body.A[:], nil
+	case *dnsmessage.AAAAResource:
+		return body.AAAA[:], nil
+	case *dnsmessage.NSResource:
+		return wireName(body.NS.String(), true), nil
+	case *dnsmessage.CNAMEResource:
+		return wireName(body.CNAME.String(), true), nil
+	case *dnsmessage.PTRResource:
+		return wireName(body.PTR.String(), true), nil
+	case *dnsmessage.MXResource:
+		return append(binary.BigEndian.AppendUint16(nil, body.Pref), wireName(body.MX.String(), true)...), nil
+	case *dnsmessage.SOAResource:
+		data := append(wireName(body.NS.String(), true), wireName(body.MBox.String(), true)...)
+
+		for _, n := range []uint32{body.Serial, body.Refresh, body.Retry, body.Expire, body.MinTTL} {
+			data = binary.BigEndian.AppendUint32(data, n)
+		}
+
+		return data, nil
+	case *dnsmessage.TXTResource:
+		var data []byte
+
+		for _, s := range body.TXT {
+			data = append(data, byte(len(s)))
+			data = append(data, s...)
+		}
+
+		return data, nil
+	case *dnsmessage.SRVResource:
+		data := binary.BigEndian.AppendUint16(nil, body.Priority)
+		data = binary.BigEndian.AppendUint16(data, body.Weight)
+		data = binary.BigEndian.AppendUint16(data, body.Port)
+
+		return append(data, wireName(body.Target.String(), true)...), nil
+	case *dnsmessage.SVCBResource:
+		return svcbData(body), nil
+	case *dnsmessage.HTTPSResource:
+		return svcbData(&body.SVCBResource), nil
+	case *dnsmessage.UnknownResource:
+		switch resource.Header.Type {
+		case 39: // DNAME
+			return lowerASCII(body.Data), nil
+		case 35: // NAPTR
+			off := 4
+
+			for range 3 {
+				var err error
+
+				_, off, err = readString(body.Data, off)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return append(slices.Clone(body.Data[:off]), lowerASCII(body.Data[off:])...), nil
+		default:
+			return body.Data, nil
+		}
+	default:
+		return nil, ErrDNSResponse
+	}
+}
+
+// svcbData encodes an SVCB record. Its target name is not lowercased, as
+// required by RFC 9460.
+func svcbData(body *dnsmessage.SVCBResource) []byte {
+	data := binary.BigEndian.AppendUint16(nil, body.Priority)
+	data = append(data, wireName(body.Target.String(), false)...)
+
+	for _, param := range body.Params {
+		data = binary.BigEndian.AppendUint16(data, uint16(param.Key))
+		data = binary.BigEndian.AppendUint16(data, uint16(len(param.Value)))
+		data = append(data, param.Value...)
+	}
+
+	return data
+}
+
+// signedData builds the data covered by an RRSIG, as defined in RFC 4034,
+// from the records of an RRset in canonical order.
+func signedData(set rrset, sig rrsig) ([]byte, error) {
+	labels := nameLabels(set.name)
+
+	if len(labels) > 0 && labels[0] == "*" {
+		labels = labels[1:]
+	}
+
+	if int(sig.labels) > len(labels) {
+		return nil, ErrDNSSECBadSignature
+	}
+
+	// Answers synthesized from a wildcard are signed under the wildcard
+	// name, which is rebuilt from the label count in the signature.
+	owner := set.name
+	if int(sig.labels) < len(labels) {
+		owner = "*." + strings.Join(labels[len(labels)-int(sig.labels):], ".") + "."
+	}
+
+	prefix := wireName(owner, true)
+	prefix = binary.BigEndian.AppendUint16(prefix, uint16(set.qtype))
+	prefix = binary.BigEndian.AppendUint16(prefix, uint16(dnsmessage.ClassINET))
+	prefix = binary.BigEndian.AppendUint32(prefix, sig.originalTTL)
+
+	var records [][]byte
+
+	for _, resource := range set.records {
+		data, err := canonicalData(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, data)
+	}
+
+	slices.SortFunc(records, bytes.Compare)
+
+	records = slices.CompactFunc(records, bytes.Equal)
+
+	signed := slices.Clone(sig.header)
+
+	for _, data := range records {
+		signed = append(signed, prefix...)
+		signed = binary.BigEndian.AppendUint16(signed, uint16(len(data)))
+		signed = append(signed, data...)
+	}
+
+	return signed, nil
+}
+
+// rsaKey decodes an RSA public key in the format defined in RFC 3110.
+func rsaKey(data []byte) (*rsa.PublicKey, error) {
+	if len(data) < 1 {
+		return nil, ErrDNSResponse
+	}
+
+	length, off := int(data[0]), 1
+
+	if length == 0 {
+		if len(data) < 3 {
+			return nil, ErrDNSResponse
+		}
+
+		length, off = int(binary.BigEndian.Uint16(data[1:])), 3
+	}
+
+	if length == 0 || length > 8 || off+length >= len(data) {
+		return nil, ErrDNSResponse
+	}
+
+	exponent := new(big.Int).SetBytes(data[off : off+length])
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, ErrDNSResponse
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(data[off+length:]),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+func verifySignature(algorithm uint8, key, data, signature []byte) error {
+	switch algorithm {
+	case algRSASHA1, algRSASHA1NSEC3SHA1, algRSASHA256, algRSASHA512:
+		pub, err := rsaKey(key)
+		if err != nil {
+			return err
+		}
+
+		var hash crypto.Hash
+		var digest []byte
+
+		switch algorithm {
+		case algRSASHA256:
+			sum := sha256.Sum256(data)
+
+			hash, digest = crypto.SHA256, sum[:]
+		case algRSASHA512:
+			sum := sha512.Sum512(data)
+
+			hash, digest = crypto.SHA512, sum[:]
+		default:
+			sum := sha1.Sum(data)
+
+			hash, digest = crypto.SHA1, sum[:]
+		}
+
+		if rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return ErrDNSSECBadSignature
+		}
+
+		return nil
+	case algECDSAP256SHA256, algECDSAP384SHA384:
+		curve, size := elliptic.P256(), 32
+
+		var digest []byte
+
+		if algorithm == algECDSAP384SHA384 {
+			curve, size = elliptic.P384(), 48
+
+			sum := sha512.Sum384(data)
+
+			digest = sum[:]
+		} else {
+			sum := sha256.Sum256(data)
+
+			digest = sum[:]
+		}
+
+		if len(key) != 2*size || len(signature) != 2*size {
+			return ErrDNSSECBadSignature
+		}
+
+		pub, err := ecdsa.ParseUncompressedPublicKey(curve, append([]byte{4}, key...))
+		if err != nil {
+			return err
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrDNSSECBadSignature
+		}
+
+		return nil
+	case algED25519:
+		if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, data, signature) {
+			return ErrDNSSECBadSignature
+		}
+
+		return nil
+	default:
+		return ErrDNSSECAlgorithm
+	}
+}
+
+// verifyRRset checks that an RRset is signed by one of the given keys, with
+// a signature valid at the given time, returning the signature used.
+func verifyRRset(set rrset, sigs []rrsig, keys []dnskey, now time.Time) (rrsig, error) {
+	if len(sigs) == 0 {
+		return rrsig{}, ErrDNSSECNoSignature
+	}
+
+	err := ErrDNSSECNoKey
+
+	// Signature validity periods use serial number arithmetic, so that
+	// they are not limited by the 32-bit timestamp.
+	t := uint32(now.Unix())
+
+	for _, sig := range sigs {
+		switch {
+		case int32(t-sig.inception) < 0:
+			err = ErrDNSSECNotYetValid
+
+			continue
+		case int32(sig.expiration-t) < 0:
+			err = ErrDNSSECExpired
+
+			continue
+		}
+
+		for _, key := range keys {
+			if key.keyTag() != sig.keyTag || key.algorithm != sig.algorithm || key.flags&dnskeyZoneFlag == 0 {
+				continue
+			}
+
+			data, dataErr := signedData(set, sig)
+			if dataErr != nil {
+				err = dataErr
+
+				continue
+			}
+
+			err = verifySignature(sig.algorithm, key.key, data, sig.signature)
+			if err == nil {
+				return sig, nil
+			}
+		}
+	}
+
+	return rrsig{}, err
+}
+
+// nsec3Hash hashes a name with the parameters from an NSEC3 record, as
+// defined in RFC 5155.
+func nsec3Hash(name string, params nsec3Record) (string, error) {
+	if params.hashAlgorithm != 1 {
+		return "", ErrDNSSECAlgorithm
+	}
+
+	if params.iterations > maxNSEC3Iterations {
+		return "", ErrDNSSECIterations
+	}
+
+	sum := sha1.Sum(append(wireName(name, true), params.salt...))
+
+	for range params.iterations {
+		sum = sha1.Sum(append(sum[:], params.salt...))
+	}
+
+	return strings.ToLower(base32Hex.EncodeToString(sum[:])), nil
+}
+
+// groupRRsets splits resource records into RRsets, keeping the order in
+// which they first appear. Signatures and EDNS records are left out.
+func groupRRsets(resources []dnsmessage.Resource) []rrset {
+	var sets []rrset
+
+	for _, resource := range resources {
+		t := resource.Header.Type
+		if t == typeRRSIG || t == dnsmessage.TypeOPT {
+			continue
+		}
+
+		name := resource.Header.Name.String()
+
+		i := slices.IndexFunc(sets, func(set rrset) bool {
+			return set.qtype == t && sameName(set.name, name)
+		})
+		if i < 0 {
+			sets = append(sets, rrset{name: name, qtype: t})
+
+			i = len(sets) - 1
+		}
+
+		sets[i].records = append(sets[i].records, resource)
+	}
+
+	return sets
+}
+
+// signatures returns the RRSIGs covering an RRset.
+func signatures(resources []dnsmessage.Resource, set rrset) []rrsig {
+	var sigs []rrsig
+
+	for _, resource := range resources {
+		if resource.Header.Type != typeRRSIG || !sameName(resource.Header.Name.String(), set.name) {
+			continue
+		}
+
+		unknown, ok := resource.Body.(*dnsmessage.UnknownResource)
+		if !ok {
+			continue
+		}
+
+		sig, err := parseRRSIG(unknown.Data)
+		if err != nil || sig.typeCovered != set.qtype {
+			continue
+		}
+
+		sigs = append(sigs, sig)
+	}
+
+	return sigs
+}
+
+func findRRset(resources []dnsmessage.Resource, name string, t dnsmessage.Type) (rrset, bool) {
+	for _, set := range groupRRsets(resources) {
+		if set.qtype == t && sameName(set.name, name) {
+			return set, true
+		}
+	}
+
+	return rrset{}, false
+}
+
+func rrsetData(set rrset) [][]byte {
+	data := make([][]byte, 0, len(set.records))
+
+	for _, resource := range set.records {
+		if unknown, ok := resource.Body.(*dnsmessage.UnknownResource); ok {
+			data = append(data, unknown.Data)
+		}
+	}
+
+	return data
+}
+
+// trustAnchors maps zones to the DS records trusted for them.
+type trustAnchors map[string][]dsRecord
+
+// loadTrustAnchors reads DS or DNSKEY records in zone file format, one per
+// line, falling back to the root zone KSKs if path is empty. DNSKEY records
+// are converted to DS records using a SHA-256 digest.
+func loadTrustAnchors(path string) (trustAnchors, error) {
+	lines := rootTrustAnchors
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		lines = nil
+
+		s := bufio.NewScanner(f)
+
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	anchors := make(trustAnchors)
+
+	for i, line := range lines {
+		line, _, _ = strings.Cut(line, ";")
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		zone := strings.ToLower(fields[0])
+		if !strings.HasSuffix(zone, ".") {
+			zone += "."
+		}
+
+		// The TTL and class are optional, and may appear in either order.
+		fields = fields[1:]
+
+		for len(fields) > 0 && (strings.EqualFold(fields[0], "IN") || isNumber(fields[0])) {
+			fields = fields[1:]
+		}
+
+		ds, err := parseTrustAnchor(zone, fields)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTrustAnchor, i+1, err)
+		}
+
+		anchors[zone] = append(anchors[zone], ds)
+	}
+
+	if len(anchors) == 0 {
+		return nil, fmt.Errorf("%w: no records found in %s", ErrInvalidTrustAnchor, path)
+	}
+
+	return anchors, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+
+	return err == nil
+}
+
+func parseTrustAnchor(zone string, fields []string) (dsRecord, error) {
+	if len(fields) < 5 {
+		return dsRecord{}, errors.New("too few fields")
+	}
+
+	var numbers [3]uint64
+
+	for i := range numbers {
+		n, err := strconv.ParseUint(fields[i+1], 10, 16)
+		if err != nil {
+			return dsRecord{}, err
+		}
+
+		numbers[i] = n
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "DS":
+		digest, err := hex.DecodeString(strings.Join(fields[4:], ""))
+		if err != nil {
+			return dsRecord{}, err
+		}
+
+		return dsRecord{uint16(numbers[0]), uint8(numbers[1]), uint8(numbers[2]), digest}, nil
+	case "DNSKEY":
+		key, err := base64.StdEncoding.DecodeString(strings.Join(fields[4:], ""))
+		if err != nil {
+			return dsRecord{}, err
+		}
+
+		rdata := binary.BigEndian.AppendUint16(nil, uint16(numbers[0]))
+		rdata = append(rdata, uint8(numbers[1]), uint8(numbers[2]))
+
+		k, _ := parseDNSKEY(append(rdata, key...))
+
+		digest, _ := k.digest(zone, digestSHA256)
+
+		return dsRecord{k.keyTag(), k.algorithm, digestSHA256, digest}, nil
+	default:
+		return dsRecord{}, fmt.Errorf("unsupported record type %s", fields[0])
+	}
+}
+
+// zoneState is the outcome of validating the keys for a zone.
+type zoneState struct {
+	name   string
+	status dnssecStatus
+	keys   []dnskey
+}
+
+// validator follows the chain of trust from a trust anchor down to the zone
+// holding a name, querying the configured resolver with checking disabled
+// so that records are returned even if the resolver considers them bogus.
+type validator struct {
+	ctx     context.Context
+	server  *upstreamSet
+	anchors trustAnchors
+	now     time.Time
+
+	replies map[string]*dnsmessage.Message
+	zones   map[string]*zoneState
+	chain   []DNSSECLink
+}
+
+func newValidator(ctx context.Context, server *upstreamSet, anchors trustAnchors) *validator {
+	return &validator{
+		ctx:     ctx,
+		server:  server,
+		anchors: anchors,
+		now:     time.Now(),
+		replies: make(map[string]*dnsmessage.Message),
+		zones:   make(map[string]*zoneState),
+	}
+}
+
+func (v *validator) query(name string, t dnsmessage.Type) (*dnsmessage.Message, error) {
+	key := strings.ToLower(name) + "/" + typeName(t)
+
+	if msg, ok := v.replies[key]; ok {
+		return msg, nil
+	}
+
+	reply, err := dnsRequest{
+		server:           v.server,
+		name:             name,
+		qtype:            t,
+		dnssec:           true,
+		checkingDisabled: true,
+	}.exchange(v.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch reply.message.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+	default:
+		return nil, fmt.Errorf("%s response", rcodeName(reply.message.RCode))
+	}
+
+	v.replies[key] = reply.message
+
+	return reply.message, nil
+}
+
+func (v *validator) link(name string, status dnssecStatus, detail string, keys []dnskey) *zoneState {
+	v.chain = append(v.chain, DNSSECLink{
+		Zone:   name,
+		Status: status.String(),
+		Detail: detail,
+	})
+
+	return &zoneState{name: name, status: status, keys: keys}
+}
+
+// verifyKeys validates the DNSKEY RRset of a zone, which must be signed by
+// a key matching one of the DS records from its parent or trust anchor.
+func (v *validator) verifyKeys(zone string, dsSet []dsRecord, source string) *zoneState {
+	var supported []dsRecord
+
+	for _, ds := range dsSet {
+		if supportedAlgorithm(ds.algorithm) && supportedDigest(ds.digestType) {
+			supported = append(supported, ds)
+		}
+	}
+
+	// RFC 4035 treats zones signed only with unknown algorithms as if they
+	// were unsigned.
+	if len(supported) == 0 {
+		return v.link(zone, dnssecInsecure, fmt.Sprintf("no DS records from %s use a supported algorithm", source), nil)
+	}
+
+	msg, err := v.query(zone, typeDNSKEY)
+	if err != nil {
+		return v.link(zone, dnssecIndeterminate, fmt.Sprintf("DNSKEY query failed: %v", err), nil)
+	}
+
+	set, ok := findRRset(msg.Answers, zone, typeDNSKEY)
+	if !ok {
+		return v.link(zone, dnssecBogus, "no DNSKEY records found", nil)
+	}
+
+	var keys, trusted []dnskey
+
+	for _, data := range rrsetData(set) {
+		key, err := parseDNSKEY(data)
+		if err != nil || key.protocol != 3 || key.flags&dnskeyZoneFlag == 0 {
+			continue
+		}
+
+		keys = append(keys, key)
+
+		for _, ds := range supported {
+			if key.keyTag() != ds.keyTag || key.algorithm != ds.algorithm {
+				continue
+			}
+
+			if digest, _ := key.digest(zone, ds.digestType); bytes.Equal(digest, ds.digest) {
+				trusted = append(trusted, key)
+
+				break
+			}
+		}
+	}
+
+	if len(trusted) == 0 {
+		return v.link(zone, dnssecBogus, fmt.Sprintf("no DNSKEY matches the DS records from %s", source), nil)
+	}
+
+	sig, err := verifyRRset(set, signatures(msg.Answers, set), trusted, v.now)
+	if err != nil {
+		return v.link(zone, dnssecBogus, fmt.Sprintf("DNSKEY RRset: %v", err), nil)
+	}
+
+	return v.link(zone, dnssecSecure, fmt.Sprintf("DNSKEY %d/%d matches DS from %s", sig.keyTag, sig.algorithm, source), keys)
+}
+
+// delegation follows the chain of trust from a secure zone to name, which
+// may be a child zone or just another name within the parent. The second
+// return value is false if name does not exist.
+func (v *validator) delegation(parent *zoneState, name string) (*zoneState, bool) {
+	msg, err := v.query(name, typeDS)
+	if err != nil {
+		return v.link(name, dnssecIndeterminate, fmt.Sprintf("DS query failed: %v", err), nil), true
+	}
+
+	if msg.RCode == dnsmessage.RCodeNameError {
+		return parent, false
+	}
+
+	if set, ok := findRRset(msg.Answers, name, typeDS); ok {
+		_, err := verifyRRset(set, signatures(msg.Answers, set), parent.keys, v.now)
+		if err != nil {
+			return v.link(name, dnssecBogus, fmt.Sprintf("DS RRset in %s: %v", parent.name, err), nil), true
+		}
+
+		var dsSet []dsRecord
+
+		for _, data := range rrsetData(set) {
+			if ds, err := parseDS(data); err == nil {
+				dsSet = append(dsSet, ds)
+			}
+		}
+
+		return v.verifyKeys(name, dsSet, parent.name), true
+	}
+
+	// Without a DS record, name is either an unsigned delegation or not a
+	// zone at all, which is told apart by looking for an SOA record.
+	soa, err := v.query(name, dnsmessage.TypeSOA)
+	if err != nil {
+		return v.link(name, dnssecIndeterminate, fmt.Sprintf("SOA query failed: %v", err), nil), true
+	}
+
+	if _, ok := findRRset(soa.Answers, name, dnsmessage.TypeSOA); !ok {
+		return parent, true
+	}
+
+	proof, err := v.denial(parent, msg, name, typeDS)
+
+	switch {
+	case errors.Is(err, ErrDNSSECIterations), errors.Is(err, ErrDNSSECAlgorithm):
+		return v.link(name, dnssecInsecure, fmt.Sprintf("no DS records, but the proof cannot be checked: %v", err), nil), true
+	case err != nil:
+		return v.link(name, dnssecBogus, fmt.Sprintf("no DS records in %s, and no valid proof: %v", parent.name, err), nil), true
+	}
+
+	return v.link(name, dnssecInsecure, fmt.Sprintf("unsigned delegation from %s (%s)", parent.name, proof), nil), true
+}
+
+// walk returns the state of the zone holding name, validating each zone on
+// the way down from the closest trust anchor.
+func (v *validator) walk(name string) *zoneState {
+	var zone *zoneState
+
+	for _, n := range ancestors(name) {
+		if state, ok := v.zones[n]; ok {
+			zone = state
+
+			continue
+		}
+
+		exists := true
+
+		switch {
+		case v.anchors[n] != nil:
+			zone = v.verifyKeys(n, v.anchors[n], "trust anchor")
+		case zone == nil:
+			continue
+		case zone.status == dnssecSecure:
+			zone, exists = v.delegation(zone, n)
+		}
+
+		if !exists {
+			break
+		}
+
+		v.zones[n] = zone
+	}
+
+	if zone == nil {
+		if _, ok := v.zones[""]; !ok {
+			v.zones[""] = v.link(name, dnssecIndeterminate, "no trust anchor covers this name", nil)
+		}
+
+		return v.zones[""]
+	}
+
+	return zone
+}
+
+// denial checks the NSEC or NSEC3 records in a negative response, which must
+// be signed by zone and prove that name, or the type t at name, does not
+// exist. The wildcard proofs for NXDOMAIN responses are not checked.
+func (v *validator) denial(zone *zoneState, msg *dnsmessage.Message, name string, t dnsmessage.Type) (string, error) {
+	var nsecs map[string]nsecRecord
+	var nsec3s map[string]nsec3Record
+
+	for _, set := range groupRRsets(msg.Authorities) {
+		if set.qtype != typeNSEC && set.qtype != typeNSEC3 {
+			continue
+		}
+
+		_, err := verifyRRset(set, signatures(msg.Authorities, set), zone.keys, v.now)
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %w", typeName(set.qtype), set.name, err)
+		}
+
+		for _, data := range rrsetData(set) {
+			if set.qtype == typeNSEC {
+				if nsec, err := parseNSEC(data); err == nil {
+					if nsecs == nil {
+						nsecs = make(map[string]nsecRecord)
+					}
+
+					nsecs[strings.ToLower(set.name)] = nsec
+				}
+			} else if nsec3, err := parseNSEC3(data, false); err == nil && len(nameLabels(set.name)) > 0 {
+				if nsec3s == nil {
+					nsec3s = make(map[string]nsec3Record)
+				}
+
+				nsec3s[nameLabels(set.name)[0]] = nsec3
+			}
+		}
+	}
+
+	nxdomain := msg.RCode == dnsmessage.RCodeNameError
+
+	switch {
+	case nsecs != nil:
+		for owner, nsec := range nsecs {
+			switch {
+			case !nxdomain && sameName(owner, name):
+				if slices.Contains(nsec.types, t) || slices.Contains(nsec.types, dnsmessage.TypeCNAME) {
+					return "", ErrDNSSECTypeExists
+				}
+
+				return "proven by NSEC", nil
+			case nxdomain && covers(owner, nsec.next, name, compareNames):
+				return "proven by NSEC", nil
+			}
+		}
+	case nsec3s != nil:
+		var params nsec3Record
+
+		for _, nsec3 := range nsec3s {
+			params = nsec3
+
+			break
+		}
+
+		hash, err := nsec3Hash(name, params)
+		if err != nil {
+			return "", err
+		}
+
+		if nsec3, ok := nsec3s[hash]; ok && !nxdomain {
+			if slices.Contains(nsec3.types, t) || slices.Contains(nsec3.types, dnsmessage.TypeCNAME) {
+				return "", ErrDNSSECTypeExists
+			}
+
+			return "proven by NSEC3", nil
+		}
+
+		if t == typeDS && !nxdomain {
+			for owner, nsec3 := range nsec3s {
+				if nsec3.flags&nsec3OptOut != 0 && covers(owner, nsec3.next, hash, strings.Compare) {
+					return "proven by NSEC3 opt-out", nil
+				}
+			}
+		}
+
+		if nxdomain {
+			return v.closestEncloser(nsec3s, params, name)
+		}
+	}
+
+	return "", ErrDNSSECNoDenial
+}
+
+// closestEncloser checks the NSEC3 proof that a name does not exist: a
+// record matching its closest existing ancestor, and one covering the next
+// name below that ancestor, as described in RFC 5155.
+func (v *validator) closestEncloser(nsec3s map[string]nsec3Record, params nsec3Record, name string) (string, error) {
+	names := ancestors(name)
+
+	for i := len(names) - 2; i >= 0; i-- {
+		hash, err := nsec3Hash(names[i], params)
+		if err != nil {
+			return "", err
+		}
+
+		if _, ok := nsec3s[hash]; !ok {
+			continue
+		}
+
+		next, err := nsec3Hash(names[i+1], params)
+		if err != nil {
+			return "", err
+		}
+
+		for owner, nsec3 := range nsec3s {
+			if covers(owner, nsec3.next, next, strings.Compare) {
+				return "proven by NSEC3", nil
+			}
+		}
+
+		break
+	}
+
+	return "", ErrDNSSECNoDenial
+}
+
+// verifyAnswer validates a single RRset from an answer against the keys of
+// the zone which signed it. Signatures are grouped by signer, and each signer
+// must be the owner name or one of its ancestors, as required by RFC 4035.
+// If there is more than one signer, the best outcome among them is used.
+func (v *validator) verifyAnswer(msg *dnsmessage.Message, set rrset) (dnssecStatus, string) {
+	sigs := signatures(msg.Answers, set)
+
+	if len(sigs) == 0 {
+		zone := v.walk(set.name)
+		if zone.status != dnssecSecure {
+			return zone.status, fmt.Sprintf("zone %s is %s", zone.name, zone.status)
+		}
+
+		return dnssecBogus, ErrDNSSECNoSignature.Error()
+	}
+
+	var signers []string
+
+	for _, sig := range sigs {
+		if !slices.Contains(signers, sig.signer) {
+			signers = append(signers, sig.signer)
+		}
+	}
+
+	status, detail := dnssecBogus, ""
+
+	for _, signer := range signers {
+		s, d := v.verifySigner(set, sigs, signer)
+		if s == dnssecSecure {
+			return s, d
+		}
+
+		if detail == "" || s < status {
+			status, detail = s, d
+		}
+	}
+
+	return status, detail
+}
+
+// verifySigner validates an RRset using only the signatures from one signer.
+func (v *validator) verifySigner(set rrset, sigs []rrsig, signer string) (dnssecStatus, string) {
+	if !isSubdomain(set.name, signer) {
+		return dnssecBogus, fmt.Sprintf("signed by %s, which is not an ancestor of %s", signer, set.name)
+	}
+
+	zone := v.walk(signer)
+
+	switch {
+	case zone.status != dnssecSecure:
+		return zone.status, fmt.Sprintf("zone %s is %s", zone.name, zone.status)
+	case !sameName(zone.name, signer):
+		return dnssecBogus, fmt.Sprintf("signed by %s, which is not a zone", signer)
+	}
+
+	sigs = slices.DeleteFunc(slices.Clone(sigs), func(sig rrsig) bool {
+		return sig.signer != signer
+	})
+
+	sig, err := verifyRRset(set, sigs, zone.keys, v.now)
+	if err != nil {
+		return dnssecBogus, err.Error()
+	}
+
+	return dnssecSecure, fmt.Sprintf("signed by %s with key %d", sig.signer, sig.keyTag)
+}
+
+func (v *validator) validate(name string, t dnsmessage.Type) DNSSECResult {
+	result := DNSSECResult{
+		Server: v.server.String(),
+		Name:   name,
+		Type:   typeName(t),
+		Answer: []DNSSECRRset{},
+	}
+
+	if qname, err := fqdn(name); err == nil {
+		result.Name = qname.String()
+	}
+
+	status := dnssecSecure
+
+	add := func(name string, t dnsmessage.Type, s dnssecStatus, detail string) {
+		result.Answer = append(result.Answer, DNSSECRRset{
+			Name:   name,
+			Type:   typeName(t),
+			Status: s.String(),
+			Detail: detail,
+		})
+
+		status = max(status, s)
+	}
+
+	msg, err := v.query(name, t)
+	if err != nil {
+		add(result.Name, t, dnssecIndeterminate, fmt.Sprintf("query failed: %v", err))
+	}
+
+	if msg != nil {
+		sets := groupRRsets(msg.Answers)
+
+		for _, set := range sets {
+			s, detail := v.verifyAnswer(msg, set)
+
+			add(set.name, set.qtype, s, detail)
+		}
+
+		if len(sets) == 0 {
+			zone := v.walk(name)
+
+			if zone.status != dnssecSecure {
+				add(result.Name, t, zone.status, fmt.Sprintf("zone %s is %s", zone.name, zone.status))
+			} else if proof, err := v.denial(zone, msg, name, t); err != nil {
+				add(result.Name, t, dnssecBogus, fmt.Sprintf("%s: %v", rcodeName(msg.RCode), err))
+			} else {
+				add(result.Name, t, dnssecSecure, fmt.Sprintf("%s %s", rcodeName(msg.RCode), proof))
+			}
+		}
+	}
+
+	result.Chain = v.chain
+	if result.Chain == nil {
+		result.Chain = []DNSSECLink{}
+	}
+
+	result.Status = status.String()
+
+	// The failing step is the first link or RRset with the final status,
+	// when that status is a failure.
+	if status == dnssecBogus || status == dnssecIndeterminate {
+		for _, link := range result.Chain {
+			if link.Status == result.Status {
+				result.Failure = fmt.Sprintf("%s: %s", link.Zone, link.Detail)
+
+				break
+			}
+		}
+
+		if result.Failure == "" {
+			for _, set := range result.Answer {
+				if set.Status == result.Status {
+					result.Failure = fmt.Sprintf("%s %s: %s", set.Name, set.Type, set.Detail)
+
+					break
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// Text renders the result with the chain of trust and the answer as tables.
+func (result DNSSECResult) Text(pr *message.Printer) string {
+	var output strings.Builder
+
+	fields := [][2]string{
+		{"Server", result.Server},
+		{"Query", result.Name + " " + result.Type},
+		{"Status", result.Status},
+	}
+
+	if result.Failure != "" {
+		fields = append(fields, [2]string{"Failure", result.Failure})
+	}
+
+	output.WriteString(formatFields(pr, fields))
+
+	output.WriteString("\n; " + pr.Sprintf("Chain of trust") + "\n")
+
+	tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	for _, link := range result.Chain {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", link.Zone, link.Status, link.Detail)
+	}
+
+	tw.Flush()
+
+	output.WriteString("\n; " + pr.Sprintf("Answer") + "\n")
+
+	tw = tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	for _, set := range result.Answer {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", set.Name, set.Type, set.Status, set.Detail)
+	}
+
+	tw.Flush()
+
+	return output.String()
+}
+
+func serveDNSSEC(server *upstreamSet, anchors trustAnchors, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		qtype, err := parseType(p.ByName("type"))
+		if err == nil && (qtype == dnsmessage.TypeALL || qtype == typeRRSIG) {
+			err = ErrInvalidRecordType
+		}
+		if err != nil {
+			serveDNSQueryError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		name := p.ByName("name")
+
+		if !validName(name) {
+			serveDNSQueryError(w, r, pr, ErrInvalidName, errorChannel)
+
+			return
+		}
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+		defer cancel()
+
+		result := newValidator(ctx, server, anchors).validate(name, qtype)
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(result.Text(pr)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// signatureVector is a signed RRset published as an example in an RFC,
+// along with the key it was signed with and that key's DS record.
+type signatureVector struct {
+	rfc       string
+	zone      string
+	algorithm uint8
+	key       string
+	keyTag    uint16
+	dsDigest  uint8
+	ds        string
+	set       rrset
+	labels    uint8
+	signature string
+
+	// Expiration and inception, in the format used by RRSIG records.
+	expiration string
+	inception  string
+}
+
+func mustName(t *testing.T, name string) dnsmessage.Name {
+	t.Helper()
+
+	n, err := dnsmessage.NewName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func mustBase64(t *testing.T, s string) []byte {
+	t.Helper()
+
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func mustTime(t *testing.T, s string) uint32 {
+	t.Helper()
+
+	parsed, err := time.Parse("20060102150405", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return uint32(parsed.Unix())
+}
+
+func signatureVectors(t *testing.T) []signatureVector {
+	header := func(name string, qtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: mustName(t, name), Type: qtype, Class: dnsmessage.ClassINET, TTL: 3600}
+	}
+
+	mx := rrset{
+		name:  "example.com.",
+		qtype: dnsmessage.TypeMX,
+		records: []dnsmessage.Resource{{
+			Header: header("example.com.", dnsmessage.TypeMX),
+			Body:   &dnsmessage.MXResource{Pref: 10, MX: mustName(t, "mail.example.com.")},
+		}},
+	}
+
+	a := rrset{
+		name:  "www.example.net.",
+		qtype: dnsmessage.TypeA,
+		records: []dnsmessage.Resource{{
+			Header: header("www.example.net.", dnsmessage.TypeA),
+			Body:   &dnsmessage.AResource{A: netip.MustParseAddr("192.0.2.1").As4()},
+		}},
+	}
+
+	return []signatureVector{
+		{
+			rfc:        "RFC 8080, example 1",
+			zone:       "example.com.",
+			algorithm:  algED25519,
+			key:        "l02Woi0iS8Aa25FQkUd9RMzZHJpBoRQwAQEX1SxZJA4=",
+			keyTag:     3613,
+			dsDigest:   digestSHA256,
+			ds:         "3aa5ab37efce57f737fc1627013fee07bdf241bd10f3b1964ab55c78e79a304b",
+			set:        mx,
+			labels:     2,
+			expiration: "20150819220000",
+			inception:  "20150729220000",
+			signature: `oL9krJun7xfBOIWcGHi7mag5/hdZrKWw15jPGrHpjQeRAvTdszaPD+QLs3f
+				x8A4M3e23mRZ9VrbpMngwcrqNAg==`,
+		},
+		{
+			rfc:        "RFC 8080, example 2",
+			zone:       "example.com.",
+			algorithm:  algED25519,
+			key:        "zPnZ/QwEe7S8C5SPz2OfS5RR40ATk2/rYnE9xHIEijs=",
+			keyTag:     35217,
+			dsDigest:   digestSHA256,
+			ds:         "401781b934e392de492ec77ae2e15d70f6575a1c0bc59c5275c04ebe80c6614c",
+			set:        mx,
+			labels:     2,
+			expiration: "20150819220000",
+			inception:  "20150729220000",
+			signature: `zXQ0bkYgQTEFyfLyi9QoiY6D8ZdYo4wyUhVioYZXFdT410QPRITQSqJSnzQ
+				oSm5poJ7gD7AQR0O7KuI5k2pcBg==`,
+		},
+		{
+			rfc:       "RFC 6605, P-256",
+			zone:      "example.net.",
+			algorithm: algECDSAP256SHA256,
+			key: `GojIhhXUN/u4v54ZQqGSnyhWJwaubCvTmeexv7bR6edb
+				krSqQpF64cYbcB7wNcP+e+MAnLr+Wi9xMWyQLc8NAA==`,
+			keyTag:     55648,
+			dsDigest:   digestSHA256,
+			ds:         "b4c8c1fe2e7477127b27115656ad6256f424625bf5c1e2770ce6d6e37df61d17",
+			set:        a,
+			labels:     3,
+			expiration: "20100909100439",
+			inception:  "20100812100439",
+			signature: `qx6wLYqmh+l9oCKTN6qIc+bw6ya+KJ8oMz0YP107epXA
+				yGmt+3SNruPFKG7tZoLBLlUzGGus7ZwmwWep666VCw==`,
+		},
+		{
+			rfc:       "RFC 6605, P-384",
+			zone:      "example.net.",
+			algorithm: algECDSAP384SHA384,
+			key: `xKYaNhWdGOfJ+nPrL8/arkwf2EY3MDJ+SErKivBVSum1
+				w/egsXvSADtNJhyem5RCOpgQ6K8X1DRSEkrbYQ+OB+v8
+				/uX45NBwY8rp65F6Glur8I/mlVNgF6W/qTI37m40`,
+			keyTag:     10771,
+			dsDigest:   digestSHA384,
+			ds:         "72d7b62976ce06438e9c0bf319013cf801f09ecc84b8d7e9495f27e305c6a9b0563a9b5f4d288405c3008a946df983d6",
+			set:        a,
+			labels:     3,
+			expiration: "20100909102025",
+			inception:  "20100812102025",
+			signature: `/L5hDKIvGDyI1fcARX3z65qrmPsVz73QD1Mr5CEqOiLP
+				95hxQouuroGCeZOvzFaxsT8Glr74hbavRKayJNuydCuz
+				WTSSPdz7wnqXL5bdcJzusdnI0RSMROxxwGipWcJm`,
+		},
+	}
+}
+
+// vectorKey returns the vector's DNSKEY, a key-signing key as in the RFCs.
+func vectorKey(t *testing.T, v signatureVector) dnskey {
+	t.Helper()
+
+	key, err := parseDNSKEY(append([]byte{0x01, 0x01, 3, v.algorithm}, mustBase64(t, v.key)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// vectorSignatureData returns the vector's RRSIG in wire format, as made by
+// the given signer.
+func vectorSignatureData(t *testing.T, v signatureVector, signer string) []byte {
+	t.Helper()
+
+	data := binary.BigEndian.AppendUint16(nil, uint16(v.set.qtype))
+	data = append(data, v.algorithm, v.labels)
+	data = binary.BigEndian.AppendUint32(data, 3600)
+	data = binary.BigEndian.AppendUint32(data, mustTime(t, v.expiration))
+	data = binary.BigEndian.AppendUint32(data, mustTime(t, v.inception))
+	data = binary.BigEndian.AppendUint16(data, v.keyTag)
+	data = append(data, wireName(signer, false)...)
+
+	return append(data, mustBase64(t, v.signature)...)
+}
+
+// vectorSignature returns the vector's RRSIG, parsed from its wire format.
+func vectorSignature(t *testing.T, v signatureVector) rrsig {
+	t.Helper()
+
+	sig, err := parseRRSIG(vectorSignatureData(t, v, v.zone))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sig
+}
+
+func TestDNSKEYDigest(t *testing.T) {
+	for _, v := range signatureVectors(t) {
+		t.Run(v.rfc, func(t *testing.T) {
+			key := vectorKey(t, v)
+
+			if got := key.keyTag(); got != v.keyTag {
+				t.Errorf("key tag = %d, want %d", got, v.keyTag)
+			}
+
+			digest, ok := key.digest(v.zone, v.dsDigest)
+			if !ok {
+				t.Fatalf("digest type %d not supported", v.dsDigest)
+			}
+
+			if got := hex.EncodeToString(digest); got != v.ds {
+				t.Errorf("digest = %s, want %s", got, v.ds)
+			}
+		})
+	}
+}
+
+func TestVerifyRRset(t *testing.T) {
+	for _, v := range signatureVectors(t) {
+		t.Run(v.rfc, func(t *testing.T) {
+			key := vectorKey(t, v)
+			sig := vectorSignature(t, v)
+
+			now := time.Unix(int64(sig.inception), 0).Add(24 * time.Hour)
+
+			used, err := verifyRRset(v.set, []rrsig{sig}, []dnskey{key}, now)
+			if err != nil {
+				t.Fatalf("verifyRRset: %v", err)
+			}
+
+			if used.keyTag != v.keyTag || used.signer != v.zone {
+				t.Errorf("signature used = %d %s, want %d %s", used.keyTag, used.signer, v.keyTag, v.zone)
+			}
+
+			_, err = verifyRRset(v.set, []rrsig{sig}, []dnskey{key}, time.Unix(int64(sig.expiration), 0).Add(time.Hour))
+			if !errors.Is(err, ErrDNSSECExpired) {
+				t.Errorf("after expiration: got %v, want %v", err, ErrDNSSECExpired)
+			}
+
+			_, err = verifyRRset(v.set, []rrsig{sig}, []dnskey{key}, time.Unix(int64(sig.inception), 0).Add(-time.Hour))
+			if !errors.Is(err, ErrDNSSECNotYetValid) {
+				t.Errorf("before inception: got %v, want %v", err, ErrDNSSECNotYetValid)
+			}
+
+			_, err = verifyRRset(v.set, nil, []dnskey{key}, now)
+			if !errors.Is(err, ErrDNSSECNoSignature) {
+				t.Errorf("without signatures: got %v, want %v", err, ErrDNSSECNoSignature)
+			}
+
+			other := vectorKey(t, v)
+			other.flags &^= dnskeyZoneFlag
+
+			_, err = verifyRRset(v.set, []rrsig{sig}, []dnskey{other}, now)
+			if !errors.Is(err, ErrDNSSECNoKey) {
+				t.Errorf("without a zone key: got %v, want %v", err, ErrDNSSECNoKey)
+			}
+
+			tampered := sig
+			tampered.originalTTL++
+
+			_, err = verifyRRset(v.set, []rrsig{tampered}, []dnskey{key}, now)
+			if !errors.Is(err, ErrDNSSECBadSignature) {
+				t.Errorf("with a modified TTL: got %v, want %v", err, ErrDNSSECBadSignature)
+			}
+
+			// A signature claiming more labels than the owner name has can't
+			// be checked, but shouldn't stop the others from being tried.
+			unusable := sig
+			unusable.labels = 10
+
+			used, err = verifyRRset(v.set, []rrsig{unusable, sig}, []dnskey{key}, now)
+			if err != nil || used.labels != sig.labels {
+				t.Errorf("after an unusable signature: got %d labels, %v, want %d labels", used.labels, err, sig.labels)
+			}
+		})
+	}
+}
+
+// TestVerifyAnswerSigner checks that a signature from outside the zone
+// holding a name is rejected, even if its signer is a secure zone.
+func TestVerifyAnswerSigner(t *testing.T) {
+	v := signatureVectors(t)[2]
+
+	record := v.set.records[0]
+
+	msg := &dnsmessage.Message{
+		Answers: []dnsmessage.Resource{
+			record,
+			{
+				Header: dnsmessage.ResourceHeader{Name: record.Header.Name, Type: typeRRSIG, Class: dnsmessage.ClassINET, TTL: 3600},
+				Body:   &dnsmessage.UnknownResource{Type: typeRRSIG, Data: vectorSignatureData(t, v, "example.org.")},
+			},
+		},
+	}
+
+	validator := newValidator(context.Background(), nil, nil)
+
+	status, detail := validator.verifyAnswer(msg, v.set)
+	if status != dnssecBogus {
+		t.Errorf("status = %s (%s), want %s", status, detail, dnssecBogus)
+	}
+
+	if len(validator.chain) > 0 {
+		t.Errorf("walked the chain of trust for %s", validator.chain[0].Zone)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	// A signer name whose label runs past the end of the data.
+	truncatedSigner := append(make([]byte, 18), 7, 'e', 'x')
+
+	// An NSEC3 record whose next hashed owner runs past the end of the data.
+	truncatedNext := []byte{1, 0, 0, 0, 0, 20, 0xaa}
+
+	tests := []struct {
+		name  string
+		parse func() error
+	}{
+		{"DS too short", func() error { _, err := parseDS([]byte{0, 1, 13}); return err }},
+		{"DNSKEY too short", func() error { _, err := parseDNSKEY([]byte{1, 1, 3}); return err }},
+		{"RRSIG too short", func() error { _, err := parseRRSIG(make([]byte, 17)); return err }},
+		{"RRSIG without signer", func() error { _, err := parseRRSIG(make([]byte, 18)); return err }},
+		{"RRSIG truncated signer", func() error { _, err := parseRRSIG(truncatedSigner); return err }},
+		{"NSEC empty", func() error { _, err := parseNSEC(nil); return err }},
+		{"NSEC bitmap without length", func() error { _, err := parseNSEC([]byte{0, 0}); return err }},
+		{"NSEC bitmap of zero length", func() error { _, err := parseNSEC([]byte{0, 0, 0}); return err }},
+		{"NSEC bitmap too long", func() error { _, err := parseNSEC(append([]byte{0, 0, 33}, make([]byte, 33)...)); return err }},
+		{"NSEC truncated bitmap", func() error { _, err := parseNSEC([]byte{0, 0, 4, 0x40}); return err }},
+		{"NSEC3 too short", func() error { _, err := parseNSEC3([]byte{1, 0, 0}, false); return err }},
+		{"NSEC3 truncated salt", func() error { _, err := parseNSEC3([]byte{1, 0, 0, 0, 4, 0xab}, false); return err }},
+		{"NSEC3 without next owner", func() error { _, err := parseNSEC3([]byte{1, 0, 0, 0, 0}, false); return err }},
+		{"NSEC3 truncated next owner", func() error { _, err := parseNSEC3(truncatedNext, false); return err }},
+		{"NSEC3PARAM truncated salt", func() error { _, err := parseNSEC3([]byte{1, 0, 0, 0, 4, 0xab}, true); return err }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.parse()
+			if !errors.Is(err, ErrDNSResponse) {
+				t.Errorf("got %v, want %v", err, ErrDNSResponse)
+			}
+		})
+	}
+}
+
+func TestParseNSEC3(t *testing.T) {
+	// 1 1 12 AABBCCDD, followed by a 20-byte next owner and a bitmap of A and
+	// RRSIG.
+	data := []byte{1, 1, 0, 12, 4, 0xaa, 0xbb, 0xcc, 0xdd, 20}
+	data = append(data, make([]byte, 20)...)
+	data = append(data, 0, 6, 0x40, 0, 0, 0, 0, 0x02)
+
+	record, err := parseNSEC3(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record.iterations != 12 || hex.EncodeToString(record.salt) != "aabbccdd" {
+		t.Errorf("parameters = %d %x, want 12 aabbccdd", record.iterations, record.salt)
+	}
+
+	if want := strings.Repeat("0", 32); record.next != want {
+		t.Errorf("next = %s, want %s", record.next, want)
+	}
+
+	if len(record.types) != 2 || record.types[0] != dnsmessage.TypeA || record.types[1] != typeRRSIG {
+		t.Errorf("types = %v, want [A RRSIG]", typeNames(record.types))
+	}
+}
@@ -0,0 +1,580 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/text/message"
+)
+
+const (
+	traceTimeout = 30 * time.Second
+
+	// maxTraceSteps bounds the number of queries made by a single trace, and
+	// maxTraceAttempts the number of servers tried for each zone.
+	maxTraceSteps    = 32
+	maxTraceAttempts = 3
+)
+
+var (
+	ErrInvalidRootHint = errors.New("invalid root hint")
+	ErrLameDelegation  = errors.New("lame delegation: no answer or referral")
+	ErrNoNameservers   = errors.New("no addresses found for nameservers")
+	ErrTraceFailed     = errors.New("no nameserver gave an answer or referral")
+	ErrTraceTooLong    = errors.New("too many referrals")
+)
+
+// defaultRootHints holds the IPv4 addresses of the root servers, a through m.
+var defaultRootHints = []string{
+	"198.41.0.4",
+	"170.247.170.2",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+// rootHints holds the servers a trace starts from. Nameservers found along
+// the way are queried on the same port as the first hint, so that a trace
+// can be run against a test hierarchy listening elsewhere than port 53.
+type rootHints struct {
+	servers []traceServer
+	port    string
+
+	// public is set when every root hint is globally reachable, in which
+	// case only globally reachable nameservers are queried. Hints pointing
+	// at a private test hierarchy leave it unset.
+	public bool
+}
+
+type traceServer struct {
+	name    string
+	address string
+}
+
+// parseRootHints accepts root server addresses as ip or ip:port.
+func parseRootHints(hints []string) (rootHints, error) {
+	parsed := rootHints{public: true}
+
+	for _, hint := range hints {
+		host, port := splitUpstreamHost(strings.TrimSpace(hint), "53")
+
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return rootHints{}, fmt.Errorf("%w %q", ErrInvalidRootHint, hint)
+		}
+
+		if parsed.port == "" {
+			parsed.port = port
+		}
+
+		if !globallyReachable(addr) {
+			parsed.public = false
+		}
+
+		parsed.servers = append(parsed.servers, traceServer{
+			address: net.JoinHostPort(addr.String(), port),
+		})
+	}
+
+	if len(parsed.servers) == 0 {
+		return rootHints{}, ErrInvalidRootHint
+	}
+
+	return parsed, nil
+}
+
+type DNSTraceNameserver struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses"`
+	Glue      bool     `json:"glue"`
+}
+
+type DNSTraceStep struct {
+	Zone        string               `json:"zone"`
+	Server      string               `json:"server,omitempty"`
+	Address     string               `json:"address"`
+	Status      string               `json:"status,omitempty"`
+	Flags       []string             `json:"flags,omitempty"`
+	Time        int64                `json:"time_ms"`
+	Referral    string               `json:"referral,omitempty"`
+	Nameservers []DNSTraceNameserver `json:"nameservers,omitempty"`
+	Answer      []DNSRecord          `json:"answer,omitempty"`
+	Authority   []DNSRecord          `json:"authority,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+type DNSTraceResult struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Status  string         `json:"status,omitempty"`
+	Failure string         `json:"failure,omitempty"`
+	Steps   []DNSTraceStep `json:"steps"`
+}
+
+// isSubdomain reports whether name is zone itself, or falls beneath it.
+func isSubdomain(name, zone string) bool {
+	x, y := nameLabels(name), nameLabels(zone)
+
+	return len(x) >= len(y) && slices.Equal(x[len(x)-len(y):], y)
+}
+
+// tracer resolves a name iteratively, starting from the root hints and
+// following each referral down to the zone holding the answer.
+type tracer struct {
+	ctx   context.Context
+	hints rootHints
+
+	// server resolves the addresses of nameservers that were referred to
+	// without glue.
+	server *upstreamSet
+}
+
+func (t *tracer) query(zone string, s traceServer, name string, qtype dnsmessage.Type) (DNSTraceStep, *dnsmessage.Message) {
+	step := DNSTraceStep{
+		Zone:    zone,
+		Server:  s.name,
+		Address: s.address,
+	}
+
+	set := &upstreamSet{
+		upstreams: []*upstream{{name: s.address, protocol: "UDP", address: s.address}},
+		strategy:  strategyFailover,
+	}
+
+	req := dnsRequest{
+		server:    set,
+		name:      name,
+		qtype:     qtype,
+		iterative: true,
+	}
+
+	startTime := time.Now()
+
+	reply, err := req.exchange(t.ctx)
+	if err != nil {
+		step.Error = err.Error()
+		step.Time = time.Since(startTime).Milliseconds()
+
+		return step, nil
+	}
+
+	result := newDNSQueryResult(req, reply)
+
+	step.Status = result.Status
+	step.Flags = result.Flags
+	step.Time = result.Time
+
+	return step, reply.message
+}
+
+// referral returns the zone delegated to by a response, along with the names
+// of its nameservers. Only delegations closer to name than the zone being
+// queried are followed, so that a trace can't loop.
+func referral(msg *dnsmessage.Message, zone, name string) (string, []string) {
+	var child string
+
+	var nameservers []string
+
+	for _, resource := range msg.Authorities {
+		ns, ok := resource.Body.(*dnsmessage.NSResource)
+		if !ok {
+			continue
+		}
+
+		owner := resource.Header.Name.String()
+
+		if sameName(owner, zone) || !isSubdomain(owner, zone) || !isSubdomain(name, owner) {
+			continue
+		}
+
+		if child != "" && !sameName(owner, child) {
+			continue
+		}
+
+		child = owner
+		nameservers = append(nameservers, ns.NS.String())
+	}
+
+	return child, nameservers
+}
+
+// glue returns the addresses given for a nameserver in the additional
+// section, with IPv4 addresses first.
+func glue(msg *dnsmessage.Message, nameserver string) []string {
+	var v4, v6 []string
+
+	for _, resource := range msg.Additionals {
+		if !sameName(resource.Header.Name.String(), nameserver) {
+			continue
+		}
+
+		switch body := resource.Body.(type) {
+		case *dnsmessage.AResource:
+			v4 = append(v4, netip.AddrFrom4(body.A).String())
+		case *dnsmessage.AAAAResource:
+			v6 = append(v6, netip.AddrFrom16(body.AAAA).String())
+		}
+	}
+
+	return append(v4, v6...)
+}
+
+// resolveNameserver looks up the IPv4 addresses of a nameserver that was
+// given without glue, falling back to its IPv6 addresses.
+func (t *tracer) resolveNameserver(nameserver string) []string {
+	var addresses []string
+
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		msg, err := dnsExchange(t.ctx, t.server, nameserver, qtype)
+		if err != nil {
+			continue
+		}
+
+		for _, resource := range msg.Answers {
+			switch body := resource.Body.(type) {
+			case *dnsmessage.AResource:
+				addresses = append(addresses, netip.AddrFrom4(body.A).String())
+			case *dnsmessage.AAAAResource:
+				addresses = append(addresses, netip.AddrFrom16(body.AAAA).String())
+			}
+		}
+
+		if len(addresses) > 0 {
+			break
+		}
+	}
+
+	return addresses
+}
+
+// delegate fills in the nameservers for a referral, and returns the servers
+// to query next. Nameservers without glue are only resolved if none of them
+// had any.
+func (t *tracer) delegate(step *DNSTraceStep, msg *dnsmessage.Message, nameservers []string) []traceServer {
+	var servers []traceServer
+
+	for _, nameserver := range nameservers {
+		ns := DNSTraceNameserver{
+			Name:      nameserver,
+			Addresses: t.reachable(glue(msg, nameserver)),
+			Glue:      true,
+		}
+
+		if len(ns.Addresses) == 0 {
+			ns.Addresses = []string{}
+			ns.Glue = false
+		}
+
+		step.Nameservers = append(step.Nameservers, ns)
+	}
+
+	for _, ns := range step.Nameservers {
+		for _, address := range ns.Addresses {
+			servers = append(servers, traceServer{
+				name:    ns.Name,
+				address: net.JoinHostPort(address, t.hints.port),
+			})
+		}
+	}
+
+	if len(servers) > 0 {
+		return shuffleServers(servers)
+	}
+
+	for i := range step.Nameservers {
+		if len(servers) >= maxTraceAttempts {
+			break
+		}
+
+		addresses := t.reachable(t.resolveNameserver(step.Nameservers[i].Name))
+
+		for _, address := range addresses {
+			step.Nameservers[i].Addresses = append(step.Nameservers[i].Addresses, address)
+
+			servers = append(servers, traceServer{
+				name:    step.Nameservers[i].Name,
+				address: net.JoinHostPort(address, t.hints.port),
+			})
+		}
+	}
+
+	return shuffleServers(servers)
+}
+
+// reachable removes the addresses a trace starting from public root hints
+// should not query, so that a referral to a private or otherwise
+// special-purpose address can't be used to reach the network the server
+// runs on.
+func (t *tracer) reachable(addresses []string) []string {
+	if !t.hints.public {
+		return addresses
+	}
+
+	return slices.DeleteFunc(addresses, func(address string) bool {
+		addr, err := netip.ParseAddr(address)
+
+		return err != nil || !globallyReachable(addr)
+	})
+}
+
+// shuffleServers spreads queries across a zone's nameservers, while still
+// trying IPv4 addresses first.
+func shuffleServers(servers []traceServer) []traceServer {
+	shuffled := slices.Clone(servers)
+
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	slices.SortStableFunc(shuffled, func(a, b traceServer) int {
+		x, _ := netip.ParseAddrPort(a.address)
+		y, _ := netip.ParseAddrPort(b.address)
+
+		switch {
+		case x.Addr().Is4() && !y.Addr().Is4():
+			return -1
+		case !x.Addr().Is4() && y.Addr().Is4():
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return shuffled
+}
+
+// finish ends a trace with the answer given in its last step.
+func (result DNSTraceResult) finish(step DNSTraceStep, msg *dnsmessage.Message) DNSTraceResult {
+	step.Answer = dnsRecords(msg.Answers)
+	step.Authority = dnsRecords(msg.Authorities)
+
+	result.Steps = append(result.Steps, step)
+	result.Status = step.Status
+
+	return result
+}
+
+func (t *tracer) trace(name string, qtype dnsmessage.Type) DNSTraceResult {
+	result := DNSTraceResult{
+		Name:  name,
+		Type:  typeName(qtype),
+		Steps: []DNSTraceStep{},
+	}
+
+	if fq, err := fqdn(name); err == nil {
+		result.Name = fq.String()
+	}
+
+	zone := "."
+	servers := shuffleServers(t.hints.servers)
+
+	for len(result.Steps) < maxTraceSteps {
+		var next []traceServer
+
+		var child string
+
+		for attempt, s := range servers {
+			if attempt == maxTraceAttempts || len(result.Steps) == maxTraceSteps {
+				break
+			}
+
+			step, msg := t.query(zone, s, result.Name, qtype)
+			if msg == nil {
+				result.Steps = append(result.Steps, step)
+
+				continue
+			}
+
+			delegated, nameservers := referral(msg, zone, result.Name)
+
+			switch {
+			case msg.RCode != dnsmessage.RCodeSuccess && msg.RCode != dnsmessage.RCodeNameError:
+				step.Error = step.Status
+			case len(msg.Answers) > 0, msg.RCode == dnsmessage.RCodeNameError:
+				return result.finish(step, msg)
+			case delegated != "":
+				step.Referral = delegated
+
+				next = t.delegate(&step, msg, nameservers)
+				child = delegated
+			case msg.Authoritative:
+				return result.finish(step, msg)
+			default:
+				step.Error = ErrLameDelegation.Error()
+			}
+
+			result.Steps = append(result.Steps, step)
+
+			if child != "" {
+				break
+			}
+		}
+
+		switch {
+		case child == "" && len(result.Steps) == maxTraceSteps:
+			result.Failure = ErrTraceTooLong.Error()
+		case child == "":
+			result.Failure = fmt.Sprintf("%s: %s", ErrTraceFailed, zone)
+		case len(next) == 0:
+			result.Failure = fmt.Sprintf("%s: %s", ErrNoNameservers, child)
+		default:
+			zone = child
+			servers = next
+
+			continue
+		}
+
+		return result
+	}
+
+	result.Failure = ErrTraceTooLong.Error()
+
+	return result
+}
+
+// Text renders each step of the trace in turn, showing the referral or
+// answer given by each server.
+func (result DNSTraceResult) Text(pr *message.Printer) string {
+	var output strings.Builder
+
+	fields := [][2]string{
+		{"Query", result.Name + " " + result.Type},
+	}
+
+	if result.Status != "" {
+		fields = append(fields, [2]string{"Status", result.Status})
+	}
+
+	if result.Failure != "" {
+		fields = append(fields, [2]string{"Failure", result.Failure})
+	}
+
+	output.WriteString(formatFields(pr, fields))
+
+	for _, step := range result.Steps {
+		server := step.Address
+		if step.Server != "" {
+			server = step.Server + " (" + step.Address + ")"
+		}
+
+		output.WriteString("\n; " + pr.Sprintf("%s via %s in %dms", step.Zone, server, step.Time) + "\n")
+
+		if step.Error != "" {
+			output.WriteString(pr.Sprintf("Failed") + ": " + step.Error + "\n")
+
+			continue
+		}
+
+		tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+		for _, ns := range step.Nameservers {
+			source := pr.Sprintf("glue")
+			if !ns.Glue {
+				source = pr.Sprintf("resolved")
+			}
+
+			if len(ns.Addresses) == 0 {
+				source = pr.Sprintf("not resolved")
+			}
+
+			fmt.Fprintf(tw, "%s\tNS\t%s\t%s\t(%s)\n", step.Referral, ns.Name, strings.Join(ns.Addresses, ", "), source)
+		}
+
+		for _, record := range step.Answer {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", record.Name, record.TTL, record.Class, record.Type, record.Data)
+		}
+
+		if len(step.Answer) == 0 && step.Referral == "" {
+			for _, record := range step.Authority {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", record.Name, record.TTL, record.Class, record.Type, record.Data)
+			}
+		}
+
+		tw.Flush()
+	}
+
+	return output.String()
+}
+
+func serveDNSTrace(server *upstreamSet, hints rootHints, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		qtype := dnsmessage.TypeA
+
+		if r.URL.Query().Has("type") {
+			var err error
+
+			qtype, err = parseType(r.URL.Query().Get("type"))
+			if err != nil {
+				serveDNSQueryError(w, r, pr, err, errorChannel)
+
+				return
+			}
+		}
+
+		name := p.ByName("name")
+
+		if !validName(name) {
+			serveDNSQueryError(w, r, pr, ErrInvalidName, errorChannel)
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), traceTimeout)
+		defer cancel()
+
+		t := &tracer{ctx: ctx, hints: hints, server: server}
+
+		result := t.trace(name, qtype)
+
+		securityHeaders(w)
+
+		var err error
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(result.Text(pr)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
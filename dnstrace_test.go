@@ -0,0 +1,131 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func glueRecord(t *testing.T, name, address string) dnsmessage.Resource {
+	t.Helper()
+
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: mustName(t, name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 3600},
+		Body:   &dnsmessage.AResource{A: netip.MustParseAddr(address).As4()},
+	}
+}
+
+func TestParseRootHintsPublic(t *testing.T) {
+	tests := []struct {
+		name   string
+		hints  []string
+		public bool
+	}{
+		{"default", defaultRootHints, true},
+		{"single root", []string{"198.41.0.4"}, true},
+		{"reordered with ports", []string{"202.12.27.33:53", " 198.41.0.4 "}, true},
+		{"IPv6 root", []string{"2001:503:ba3e::2:30"}, true},
+		{"mixed", []string{"198.41.0.4", "10.0.0.1"}, false},
+		{"loopback", []string{"127.0.0.1:5360"}, false},
+		{"link-local", []string{"169.254.0.1"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hints, err := parseRootHints(test.hints)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hints.public != test.public {
+				t.Errorf("public = %t, want %t", hints.public, test.public)
+			}
+		})
+	}
+}
+
+func TestDelegateReachable(t *testing.T) {
+	public, err := parseRootHints([]string{"198.41.0.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := parseRootHints([]string{"127.0.0.1:5360"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nameservers without glue are resolved through a stub, which answers
+	// with a documentation address.
+	stub := newStubServer(t, false)
+
+	tests := []struct {
+		name  string
+		hints rootHints
+		glue  []dnsmessage.Resource
+		want  []string
+	}{
+		{
+			name:  "public hints with glue",
+			hints: public,
+			glue: []dnsmessage.Resource{
+				glueRecord(t, "ns1.example.", "10.0.0.1"),
+				glueRecord(t, "ns2.example.", "198.41.0.4"),
+			},
+			want: []string{"198.41.0.4:53"},
+		},
+		{
+			name:  "public hints without reachable glue",
+			hints: public,
+			glue:  []dnsmessage.Resource{glueRecord(t, "ns1.example.", "10.0.0.1")},
+			want:  nil,
+		},
+		{
+			name:  "local hints with glue",
+			hints: local,
+			glue: []dnsmessage.Resource{
+				glueRecord(t, "ns1.example.", "10.0.0.1"),
+				glueRecord(t, "ns2.example.", "198.41.0.4"),
+			},
+			want: []string{"10.0.0.1:5360", "198.41.0.4:5360"},
+		},
+		{
+			name:  "local hints without glue",
+			hints: local,
+			want:  []string{"192.0.2.1:5360", "192.0.2.1:5360"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tr := &tracer{
+				ctx:    context.Background(),
+				hints:  test.hints,
+				server: newTestUpstreams(t, strategyFailover, stub.addr),
+			}
+
+			var step DNSTraceStep
+
+			servers := tr.delegate(&step, &dnsmessage.Message{Additionals: test.glue}, []string{"ns1.example.", "ns2.example."})
+
+			var got []string
+
+			for _, server := range servers {
+				got = append(got, server.address)
+			}
+
+			slices.Sort(got)
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
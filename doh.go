@@ -0,0 +1,640 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/text/message"
+)
+
+const (
+	dohMessageType = "application/dns-message"
+	dohJSONType    = "application/dns-json"
+
+	// maxDoHMessageSize is the largest DNS message that fits in a TCP frame,
+	// and so the largest accepted in a POST body.
+	maxDoHMessageSize = 65535
+
+	// maxDoHCacheEntries bounds the number of cached responses, and
+	// maxDoHCacheTTL how long any one of them is kept.
+	maxDoHCacheEntries = 4096
+	maxDoHCacheTTL     = 24 * time.Hour
+)
+
+var (
+	ErrInvalidDNSMessage = errors.New("invalid DNS message")
+	ErrUnsupportedMedia  = errors.New("unsupported media type")
+	ErrRateLimited       = errors.New("too many requests")
+)
+
+// dohCacheKey identifies a cached response. The DO and CD bits are part of
+// the key, as they change what the upstream server returns.
+type dohCacheKey struct {
+	name             string
+	qtype            dnsmessage.Type
+	dnssec           bool
+	checkingDisabled bool
+}
+
+type dohCacheEntry struct {
+	message *dnsmessage.Message
+	stored  time.Time
+	expires time.Time
+}
+
+// dohCache holds upstream responses until their TTLs run out.
+type dohCache struct {
+	mu      sync.Mutex
+	entries map[dohCacheKey]*dohCacheEntry
+}
+
+func newDoHCache() *dohCache {
+	return &dohCache{
+		entries: make(map[dohCacheKey]*dohCacheEntry),
+	}
+}
+
+// cacheTTL returns how long a response may be cached for. Negative answers
+// are kept for the lesser of the SOA record's TTL and minimum, as described
+// in RFC 2308, and responses without any records are not cached at all.
+func cacheTTL(msg *dnsmessage.Message) (uint32, bool) {
+	if msg.RCode != dnsmessage.RCodeSuccess && msg.RCode != dnsmessage.RCodeNameError {
+		return 0, false
+	}
+
+	ttl := uint32(maxDoHCacheTTL.Seconds())
+	found := false
+
+	for _, section := range [][]dnsmessage.Resource{msg.Answers, msg.Authorities, msg.Additionals} {
+		for _, resource := range section {
+			if resource.Header.Type == dnsmessage.TypeOPT {
+				continue
+			}
+
+			ttl = min(ttl, resource.Header.TTL)
+			found = true
+		}
+	}
+
+	if len(msg.Answers) == 0 {
+		found = false
+
+		for _, resource := range msg.Authorities {
+			if soa, ok := resource.Body.(*dnsmessage.SOAResource); ok {
+				ttl = min(ttl, soa.MinTTL)
+				found = true
+			}
+		}
+	}
+
+	return ttl, found && ttl > 0
+}
+
+// Get returns a copy of the cached response for key, with each TTL reduced
+// by the time spent in the cache.
+func (c *dohCache) Get(key dohCacheKey) (*dnsmessage.Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	now := time.Now()
+
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	age := uint32(now.Sub(entry.stored).Seconds())
+
+	age = min(age, uint32(entry.expires.Sub(entry.stored).Seconds()))
+
+	msg := *entry.message
+
+	// Only the resource headers are changed, so the bodies can be shared
+	// with the cached copy.
+	sections := []*[]dnsmessage.Resource{&msg.Answers, &msg.Authorities, &msg.Additionals}
+
+	for _, section := range sections {
+		resources := make([]dnsmessage.Resource, len(*section))
+
+		copy(resources, *section)
+
+		for i := range resources {
+			if resources[i].Header.Type != dnsmessage.TypeOPT {
+				resources[i].Header.TTL -= min(age, resources[i].Header.TTL)
+			}
+		}
+
+		*section = resources
+	}
+
+	return &msg, true
+}
+
+// Put caches a response for as long as its records allow.
+func (c *dohCache) Put(key dohCacheKey, msg *dnsmessage.Message) {
+	ttl, ok := cacheTTL(msg)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	entry := &dohCacheEntry{
+		message: msg,
+		stored:  now,
+		expires: now.Add(time.Duration(ttl) * time.Second),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxDoHCacheEntries {
+		var soonest dohCacheKey
+
+		var soonestExpiry time.Time
+
+		for k, v := range c.entries {
+			if !now.Before(v.expires) {
+				delete(c.entries, k)
+
+				continue
+			}
+
+			if soonestExpiry.IsZero() || v.expires.Before(soonestExpiry) {
+				soonest = k
+				soonestExpiry = v.expires
+			}
+		}
+
+		if len(c.entries) >= maxDoHCacheEntries {
+			delete(c.entries, soonest)
+		}
+	}
+
+	c.entries[key] = entry
+}
+
+// dohResolver answers DNS-over-HTTPS queries from the cache, or otherwise
+// from the upstream server.
+type dohResolver struct {
+	server *upstreamSet
+	cache  *dohCache
+}
+
+func (d *dohResolver) resolve(ctx context.Context, question dnsmessage.Question, dnssec, checkingDisabled bool) (*dnsmessage.Message, error) {
+	key := dohCacheKey{
+		name:             strings.ToLower(question.Name.String()),
+		qtype:            question.Type,
+		dnssec:           dnssec,
+		checkingDisabled: checkingDisabled,
+	}
+
+	if msg, ok := d.cache.Get(key); ok {
+		return msg, nil
+	}
+
+	req := dnsRequest{
+		server:           d.server,
+		name:             question.Name.String(),
+		qtype:            question.Type,
+		dnssec:           dnssec,
+		checkingDisabled: checkingDisabled,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+
+	reply, err := req.exchange(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d.cache.Put(key, reply.message)
+
+	return reply.message, nil
+}
+
+// dohResponse returns an empty response to query with the given rcode.
+func dohResponse(query *dnsmessage.Message, rcode dnsmessage.RCode) *dnsmessage.Message {
+	return &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 query.ID,
+			Response:           true,
+			OpCode:             query.OpCode,
+			RecursionDesired:   query.RecursionDesired,
+			RecursionAvailable: true,
+			CheckingDisabled:   query.CheckingDisabled,
+			RCode:              rcode,
+		},
+		Questions: query.Questions,
+	}
+}
+
+// clientOPT returns the OPT record sent by the client, if any.
+func clientOPT(query *dnsmessage.Message) (dnsmessage.Resource, bool) {
+	for _, resource := range query.Additionals {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			return resource, true
+		}
+	}
+
+	return dnsmessage.Resource{}, false
+}
+
+// answer resolves a query on behalf of a client. Problems with the query
+// itself are reported back in the DNS response, rather than as an error.
+func (d *dohResolver) answer(ctx context.Context, query *dnsmessage.Message) (*dnsmessage.Message, error) {
+	if query.Response || len(query.Questions) != 1 {
+		return dohResponse(query, dnsmessage.RCodeFormatError), nil
+	}
+
+	question := query.Questions[0]
+
+	switch {
+	case query.OpCode != 0,
+		question.Class != dnsmessage.ClassINET,
+		question.Type == dnsmessage.TypeAXFR,
+		question.Type == 251:
+		return dohResponse(query, dnsmessage.RCodeNotImplemented), nil
+	}
+
+	opt, edns := clientOPT(query)
+
+	msg, err := d.resolve(ctx, question, edns && opt.Header.DNSSECAllowed(), query.CheckingDisabled)
+	if err != nil {
+		return dohResponse(query, dnsmessage.RCodeServerFailure), err
+	}
+
+	response := *msg
+
+	response.ID = query.ID
+	response.RecursionDesired = query.RecursionDesired
+	response.CheckingDisabled = query.CheckingDisabled
+	response.Truncated = false
+	response.Questions = query.Questions
+
+	// Clients that did not use EDNS must not be sent an OPT record.
+	if !edns {
+		var additionals []dnsmessage.Resource
+
+		for _, resource := range response.Additionals {
+			if resource.Header.Type != dnsmessage.TypeOPT {
+				additionals = append(additionals, resource)
+			}
+		}
+
+		response.Additionals = additionals
+	}
+
+	return &response, nil
+}
+
+// DoHQuestion and DoHRecord follow the application/dns-json format offered
+// by public resolvers, so that existing clients can parse the output.
+type DoHQuestion struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+}
+
+type DoHRecord struct {
+	Name string `json:"name"`
+	Type uint16 `json:"type"`
+	TTL  uint32 `json:"TTL"`
+	Data string `json:"data"`
+}
+
+type DoHJSONResponse struct {
+	Status     uint16        `json:"Status"`
+	TC         bool          `json:"TC"`
+	RD         bool          `json:"RD"`
+	RA         bool          `json:"RA"`
+	AD         bool          `json:"AD"`
+	CD         bool          `json:"CD"`
+	Question   []DoHQuestion `json:"Question"`
+	Answer     []DoHRecord   `json:"Answer,omitempty"`
+	Authority  []DoHRecord   `json:"Authority,omitempty"`
+	Additional []DoHRecord   `json:"Additional,omitempty"`
+}
+
+func dohRecords(resources []dnsmessage.Resource) []DoHRecord {
+	var records []DoHRecord
+
+	for _, resource := range resources {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			continue
+		}
+
+		records = append(records, DoHRecord{
+			Name: resource.Header.Name.String(),
+			Type: uint16(resource.Header.Type),
+			TTL:  resource.Header.TTL,
+			Data: recordData(resource),
+		})
+	}
+
+	return records
+}
+
+func newDoHJSONResponse(msg *dnsmessage.Message) DoHJSONResponse {
+	response := DoHJSONResponse{
+		Status:     uint16(msg.RCode),
+		TC:         msg.Truncated,
+		RD:         msg.RecursionDesired,
+		RA:         msg.RecursionAvailable,
+		AD:         msg.AuthenticData,
+		CD:         msg.CheckingDisabled,
+		Question:   []DoHQuestion{},
+		Answer:     dohRecords(msg.Answers),
+		Authority:  dohRecords(msg.Authorities),
+		Additional: dohRecords(msg.Additionals),
+	}
+
+	for _, question := range msg.Questions {
+		response.Question = append(response.Question, DoHQuestion{
+			Name: question.Name.String(),
+			Type: uint16(question.Type),
+		})
+	}
+
+	// The extended rcode, if any, is carried in the OPT record.
+	for _, resource := range msg.Additionals {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			response.Status = uint16(resource.Header.ExtendedRCode(msg.RCode))
+		}
+	}
+
+	return response
+}
+
+// dohFlag reports whether a JSON API flag such as do or cd is set. Both
+// "1" and "true" are used by existing clients.
+func dohFlag(r *http.Request, key string) bool {
+	if !r.URL.Query().Has(key) {
+		return false
+	}
+
+	switch strings.ToLower(r.URL.Query().Get(key)) {
+	case "0", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+// parseDoHType accepts a record type as either a mnemonic or a number, as
+// the JSON API allows both.
+func parseDoHType(s string) (dnsmessage.Type, error) {
+	if s == "" {
+		return dnsmessage.TypeA, nil
+	}
+
+	if _, err := strconv.ParseUint(s, 10, 16); err == nil {
+		s = "TYPE" + s
+	}
+
+	return parseType(s)
+}
+
+// readDoHQuery extracts the wire-format query from the dns parameter of a
+// GET request, or from the body of a POST request.
+func readDoHQuery(r *http.Request) (*dnsmessage.Message, error) {
+	var data []byte
+
+	switch r.Method {
+	case http.MethodPost:
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || mediaType != dohMessageType {
+			return nil, ErrUnsupportedMedia
+		}
+
+		data, err = io.ReadAll(io.LimitReader(r.Body, maxDoHMessageSize+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) > maxDoHMessageSize {
+			return nil, ErrInvalidDNSMessage
+		}
+	default:
+		var err error
+
+		// RFC 8484 requires base64url without padding, but padded input is
+		// accepted as well since some clients send it.
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(r.URL.Query().Get("dns"), "="))
+		if err != nil {
+			return nil, ErrInvalidDNSMessage
+		}
+	}
+
+	var query dnsmessage.Message
+
+	err := query.Unpack(data)
+	if err != nil {
+		return nil, ErrInvalidDNSMessage
+	}
+
+	return &query, nil
+}
+
+// setCacheControl tells HTTP caches to keep a response no longer than its
+// shortest TTL, as required by RFC 8484.
+func setCacheControl(w http.ResponseWriter, msg *dnsmessage.Message) {
+	ttl, ok := cacheTTL(msg)
+	if !ok {
+		w.Header().Set("Cache-Control", "no-store")
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", ttl))
+}
+
+// parseDoHJSON builds a query from the name, type, do and cd parameters of
+// a JSON API request. It always carries an OPT record, so that any extended
+// rcode in the response is kept.
+func parseDoHJSON(r *http.Request) (*dnsmessage.Message, error) {
+	qtype, err := parseDoHType(r.URL.Query().Get("type"))
+	if err != nil {
+		return nil, err
+	}
+
+	name := r.URL.Query().Get("name")
+
+	if !validName(name) {
+		return nil, ErrInvalidName
+	}
+
+	qname, err := fqdn(name)
+	if err != nil {
+		return nil, ErrInvalidName
+	}
+
+	var opt dnsmessage.ResourceHeader
+
+	err = opt.SetEDNS0(dnsUDPSize, dnsmessage.RCodeSuccess, dohFlag(r, "do"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnsmessage.Message{
+		Header: dnsmessage.Header{
+			RecursionDesired: true,
+			CheckingDisabled: dohFlag(r, "cd"),
+		},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+		Additionals: []dnsmessage.Resource{{
+			Header: opt,
+			Body:   &dnsmessage.OPTResource{},
+		}},
+	}, nil
+}
+
+func serveDoH(d *dohResolver, limiter *rateLimiter, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		if !limiter.Allow(rateLimitKey(r)) {
+			serveDoHError(w, r, pr, ErrRateLimited, errorChannel)
+
+			return
+		}
+
+		// Requests with a name parameter instead of a dns parameter use the
+		// JSON API.
+		jsonAPI := r.Method == http.MethodGet && !r.URL.Query().Has("dns") && r.URL.Query().Has("name")
+
+		var query *dnsmessage.Message
+
+		var err error
+
+		if jsonAPI {
+			query, err = parseDoHJSON(r)
+		} else {
+			query, err = readDoHQuery(r)
+		}
+		if err != nil {
+			serveDoHError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		// Upstream failures are still answered, with SERVFAIL.
+		msg, err := d.answer(ctx, query)
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		}
+
+		var data []byte
+
+		if !jsonAPI {
+			data, err = msg.Pack()
+			if err != nil {
+				serveDoHError(w, r, pr, err, errorChannel)
+
+				return
+			}
+		}
+
+		securityHeaders(w)
+
+		setCacheControl(w, msg)
+
+		if jsonAPI {
+			w.Header().Set("Content-Type", dohJSONType)
+
+			err = json.NewEncoder(w).Encode(newDoHJSONResponse(msg))
+		} else {
+			w.Header().Set("Content-Type", dohMessageType)
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
+	}
+}
+
+func serveDoHError(w http.ResponseWriter, r *http.Request, pr *message.Printer, err error, errorChannel chan<- Error) {
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	var output string
+
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		w.Header().Set("Retry-After", "1")
+
+		w.WriteHeader(http.StatusTooManyRequests)
+
+		output = pr.Sprintf("Too many requests")
+	case errors.Is(err, ErrUnsupportedMedia):
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+
+		output = pr.Sprintf("Unsupported media type")
+	case errors.Is(err, ErrInvalidRecordType):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid record type requested")
+	case errors.Is(err, ErrInvalidName):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid name requested")
+	case errors.Is(err, ErrInvalidDNSMessage):
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid DNS message")
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+
+		output = pr.Sprintf("Lookup failed")
+	}
+
+	_, err = w.Write([]byte(output + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
+)
+
+var (
+	ErrInvalidBlockSize   = errors.New("invalid block size")
+	ErrInvalidAlignment   = errors.New("invalid alignment")
+	ErrInvalidReservation = errors.New("invalid reservation")
+	ErrNoFreeBlocks       = errors.New("no free blocks of the requested size")
+)
+
+// FreeRequest holds the options for a free space search, read from the query
+// string. A size of zero lists all free space instead of fixed-size blocks.
+type FreeRequest struct {
+	Size         int
+	Count        int
+	Align        int
+	ReserveFirst *big.Int
+	ReserveLast  *big.Int
+}
+
+type FreeSpace struct {
+	Parent   string   `json:"parent"`
+	Size     int      `json:"size,omitempty"`
+	Used     string   `json:"used"`
+	Free     string   `json:"free"`
+	Prefixes []string `json:"prefixes"`
+}
+
+func parseFreeRequest(r *http.Request, parent netip.Prefix) (FreeRequest, error) {
+	query := r.URL.Query()
+
+	request := FreeRequest{
+		Count:        1,
+		ReserveFirst: new(big.Int),
+		ReserveLast:  new(big.Int),
+	}
+
+	if v := query.Get("size"); v != "" {
+		size, err := strconv.Atoi(strings.TrimPrefix(v, "/"))
+		if err != nil || size < parent.Bits() || size > parent.Addr().BitLen() {
+			return FreeRequest{}, ErrInvalidBlockSize
+		}
+
+		request.Size = size
+		request.Align = size
+	}
+
+	if v := query.Get("count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil || count < 1 || count > maxSplitLimit {
+			return FreeRequest{}, ErrInvalidBlockSize
+		}
+
+		request.Count = count
+	}
+
+	// Blocks are always aligned to their own size, so only a coarser
+	// alignment has any effect.
+	if v := query.Get("align"); v != "" {
+		align, err := strconv.Atoi(strings.TrimPrefix(v, "/"))
+		if err != nil || request.Size == 0 || align < parent.Bits() || align > request.Size {
+			return FreeRequest{}, ErrInvalidAlignment
+		}
+
+		request.Align = align
+	}
+
+	for param, value := range map[string]*big.Int{
+		"reserve-first": request.ReserveFirst,
+		"reserve-last":  request.ReserveLast,
+	} {
+		if v := query.Get(param); v != "" {
+			_, ok := value.SetString(v, 10)
+			if !ok || value.Sign() < 0 || value.Cmp(prefixSize(parent)) >= 0 {
+				return FreeRequest{}, ErrInvalidReservation
+			}
+		}
+	}
+
+	return request, nil
+}
+
+// freeSpace returns the addresses within the parent prefix which are neither
+// used nor reserved.
+func freeSpace(parent netip.Prefix, used *PrefixSet, request FreeRequest) *PrefixSet {
+	is4 := parent.Addr().Is4()
+
+	first, last := prefixBounds(parent)
+
+	var reserved PrefixSet
+
+	if request.ReserveFirst.Sign() > 0 {
+		reserved.add(is4, addrRange{first, first.add(fromBig(request.ReserveFirst)).sub(uint128{0, 1})})
+	}
+
+	if request.ReserveLast.Sign() > 0 {
+		reserved.add(is4, addrRange{last.sub(fromBig(request.ReserveLast)).add(uint128{0, 1}), last})
+	}
+
+	var whole PrefixSet
+
+	whole.AddPrefix(parent)
+
+	return whole.Subtract(used).Subtract(&reserved)
+}
+
+// nextFree returns up to count free blocks with the given prefix length,
+// each starting on a multiple of the alignment.
+func nextFree(free *PrefixSet, is4 bool, size, align, count int) []netip.Prefix {
+	width := 128
+	if is4 {
+		width = 32
+	}
+
+	step := ones(width - align)
+	block := ones(width - size)
+
+	var blocks []netip.Prefix
+
+	for _, r := range free.ranges(is4) {
+		// Round the start of the range up to the next aligned address.
+		start := r.first.add(step).and(step.not())
+		if start.cmp(r.first) < 0 {
+			continue
+		}
+
+		for len(blocks) < count {
+			end := start.add(block)
+			if end.cmp(start) < 0 || end.cmp(r.last) > 0 {
+				break
+			}
+
+			blocks = append(blocks, netip.PrefixFrom(start.toAddr(is4), size))
+
+			next := end.add(uint128{0, 1}).add(step).and(step.not())
+			if next.cmp(end) <= 0 {
+				break
+			}
+
+			start = next
+		}
+
+		if len(blocks) == count {
+			break
+		}
+	}
+
+	return blocks
+}
+
+func findFreeSpace(parent netip.Prefix, entries []string, request FreeRequest) (FreeSpace, error) {
+	if len(entries) > maxAggregateEntries {
+		return FreeSpace{}, ErrTooManyEntries
+	}
+
+	var used PrefixSet
+
+	for _, entry := range entries {
+		err := parseSetEntry(&used, strings.TrimSpace(entry))
+		if err != nil {
+			return FreeSpace{}, err
+		}
+	}
+
+	var whole PrefixSet
+
+	whole.AddPrefix(parent)
+
+	free := freeSpace(parent, &used, request)
+
+	result := FreeSpace{
+		Parent: parent.String(),
+		Size:   request.Size,
+		Used:   whole.Intersect(&used).Size().String(),
+		Free:   free.Size().String(),
+	}
+
+	var prefixes []netip.Prefix
+
+	if request.Size == 0 {
+		prefixes = free.Prefixes()
+	} else {
+		prefixes = nextFree(free, parent.Addr().Is4(), request.Size, request.Align, request.Count)
+		if len(prefixes) == 0 {
+			return FreeSpace{}, ErrNoFreeBlocks
+		}
+	}
+
+	result.Prefixes = make([]string, len(prefixes))
+
+	for i, p := range prefixes {
+		result.Prefixes[i] = p.String()
+	}
+
+	return result, nil
+}
+
+func serveFreeError(w http.ResponseWriter, r *http.Request, pr *message.Printer, err error, errorChannel chan<- Error) {
+	switch {
+	case errors.Is(err, ErrInvalidEntry), errors.Is(err, ErrTooManyEntries):
+		serveAggregateError(w, r, pr, err, errorChannel)
+
+		return
+	case errors.Is(err, ErrInvalidPrefix):
+		serveSubnetError(w, r, err, errorChannel)
+
+		return
+	}
+
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	var output string
+
+	switch {
+	case errors.Is(err, ErrNoFreeBlocks):
+		w.WriteHeader(http.StatusUnprocessableEntity)
+
+		output = pr.Sprintf("No free blocks of the requested size")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+
+		output = pr.Sprintf("Invalid free space request")
+	}
+
+	_, err = w.Write([]byte(output + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
+
+func serveFreeSpace(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		parent, err := parsePrefix(strings.Trim(p.ByName("free"), "/"))
+		if err != nil {
+			serveFreeError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		request, err := parseFreeRequest(r, parent)
+		if err != nil {
+			serveFreeError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		entries, err := readEntries(http.MaxBytesReader(w, r.Body, maxAggregateBodySize), r.Header.Get("Content-Type"))
+		if err != nil {
+			serveFreeError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		result, err := findFreeSpace(parent, entries, request)
+		if err != nil {
+			serveFreeError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, [][2]string{
+				{"Parent", result.Parent},
+				{"Used", localizeNumber(pr, result.Used)},
+				{"Free", localizeNumber(pr, result.Free)},
+			}))
+
+			output.WriteString("\n")
+
+			for _, prefix := range result.Prefixes {
+				output.WriteString(prefix + "\n")
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
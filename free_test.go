@@ -0,0 +1,141 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"math/big"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestFindFreeSpace(t *testing.T) {
+	tests := []struct {
+		name    string
+		parent  string
+		used    []string
+		size    int
+		count   int
+		align   int
+		reserve [2]int64
+		want    []string
+		inUse   string
+		free    string
+	}{
+		{
+			name:   "all free space",
+			parent: "10.0.0.0/24",
+			used:   []string{"10.0.0.0/26"},
+			want:   []string{"10.0.0.64/26", "10.0.0.128/25"},
+			inUse:  "64",
+			free:   "192",
+		},
+		{
+			name:   "used outside the parent",
+			parent: "10.0.0.0/24",
+			used:   []string{"10.0.0.0/25", "10.0.1.0/24"},
+			want:   []string{"10.0.0.128/25"},
+			inUse:  "128",
+			free:   "128",
+		},
+		{
+			name:   "blocks",
+			parent: "10.0.0.0/24",
+			used:   []string{"10.0.0.0/26", "10.0.0.96/27"},
+			size:   27,
+			count:  3,
+			want:   []string{"10.0.0.64/27", "10.0.0.128/27", "10.0.0.160/27"},
+			inUse:  "96",
+			free:   "160",
+		},
+		{
+			name:   "aligned blocks",
+			parent: "10.0.0.0/24",
+			used:   []string{"10.0.0.0/26", "10.0.0.96/27"},
+			size:   27,
+			count:  2,
+			align:  25,
+			want:   []string{"10.0.0.128/27"},
+			inUse:  "96",
+			free:   "160",
+		},
+		{
+			name:    "reserved",
+			parent:  "10.0.0.0/29",
+			reserve: [2]int64{1, 1},
+			want:    []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"},
+			inUse:   "0",
+			free:    "6",
+		},
+		{
+			name:   "end of the address space",
+			parent: "255.255.255.252/30",
+			size:   31,
+			count:  4,
+			want:   []string{"255.255.255.252/31", "255.255.255.254/31"},
+			inUse:  "0",
+			free:   "4",
+		},
+		{
+			name:   "IPv6 /0",
+			parent: "::/0",
+			used:   []string{"::/1"},
+			size:   1,
+			count:  1,
+			want:   []string{"8000::/1"},
+			inUse:  "170141183460469231731687303715884105728",
+			free:   "170141183460469231731687303715884105728",
+		},
+		{
+			name:   "/128",
+			parent: "2001:db8::/127",
+			used:   []string{"2001:db8::"},
+			size:   128,
+			count:  1,
+			want:   []string{"2001:db8::1/128"},
+			inUse:  "1",
+			free:   "1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := FreeRequest{
+				Size:         test.size,
+				Count:        max(test.count, 1),
+				Align:        test.align,
+				ReserveFirst: big.NewInt(test.reserve[0]),
+				ReserveLast:  big.NewInt(test.reserve[1]),
+			}
+
+			if request.Align == 0 {
+				request.Align = test.size
+			}
+
+			result, err := findFreeSpace(netip.MustParsePrefix(test.parent), test.used, request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(result.Prefixes, test.want) {
+				t.Errorf("got %v, want %v", result.Prefixes, test.want)
+			}
+
+			if result.Used != test.inUse || result.Free != test.free {
+				t.Errorf("used %s, free %s, want %s, %s", result.Used, result.Free, test.inUse, test.free)
+			}
+		})
+	}
+
+	t.Run("no free blocks", func(t *testing.T) {
+		request := FreeRequest{Size: 25, Count: 1, Align: 25, ReserveFirst: new(big.Int), ReserveLast: new(big.Int)}
+
+		_, err := findFreeSpace(netip.MustParsePrefix("10.0.0.0/24"), []string{"10.0.0.0/25", "10.0.0.192/26"}, request)
+		if !errors.Is(err, ErrNoFreeBlocks) {
+			t.Errorf("got %v, want %v", err, ErrNoFreeBlocks)
+		}
+	})
+}
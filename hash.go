@@ -56,7 +56,7 @@ func serveHash(algorithm HashAlgorithm, errorChannel chan<- Error) httprouter.Ha
 
 				w.WriteHeader(http.StatusInternalServerError)
 
-				_, err := w.Write([]byte("Failed to hash string\n"))
+				_, err := w.Write([]byte(localizer(r).Sprintf("Failed to hash string") + "\n"))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -91,7 +91,7 @@ func serveHash(algorithm HashAlgorithm, errorChannel chan<- Error) httprouter.Ha
 
 			w.WriteHeader(http.StatusBadRequest)
 
-			_, err := w.Write([]byte("Invalid hash algorithm requested\n"))
+			_, err := w.Write([]byte(localizer(r).Sprintf("Invalid hash algorithm requested") + "\n"))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
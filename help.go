@@ -27,7 +27,7 @@ func serveUsage(module string, usage *sync.Map, errorChannel chan<- Error) httpr
 
 		var help []string
 
-		output.WriteString("Examples:\n")
+		output.WriteString(localizer(r).Sprintf("Examples:") + "\n")
 
 		usage.Range(func(key, value any) bool {
 			if key == module {
@@ -57,7 +57,7 @@ func serveUsage(module string, usage *sync.Map, errorChannel chan<- Error) httpr
 	}
 }
 
-func serveHelp(usage *sync.Map, errorChannel chan<- Error) httprouter.Handle {
+func serveHelp(usage *sync.Map, modules *Modules, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
@@ -69,11 +69,15 @@ func serveHelp(usage *sync.Map, errorChannel chan<- Error) httprouter.Handle {
 
 		output.WriteString(fmt.Sprintf("query v%s\n\n", ReleaseVersion))
 
-		output.WriteString("Examples:\n")
+		output.WriteString(localizer(r).Sprintf("Examples:") + "\n")
 
 		var help []string
 
 		usage.Range(func(key, value any) bool {
+			if !modules.Enabled(key.(string)) {
+				return true
+			}
+
 			help = append(help, value.([]string)...)
 
 			return true
@@ -101,6 +105,6 @@ func serveHelp(usage *sync.Map, errorChannel chan<- Error) httprouter.Handle {
 	}
 }
 
-func registerHelp(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
-	mux.GET("/", serveHelp(usage, errorChannel))
+func registerHelp(mux *httprouter.Router, usage *sync.Map, modules *Modules, errorChannel chan<- Error) {
+	mux.GET("/", serveHelp(usage, modules, errorChannel))
 }
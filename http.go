@@ -40,7 +40,7 @@ func serveHTTPStatusCode(errorChannel chan<- Error) httprouter.Handle {
 		if text == "" {
 			w.WriteHeader(http.StatusBadRequest)
 
-			_, err = w.Write([]byte("Invalid status code requested\n"))
+			_, err = w.Write([]byte(localizer(r).Sprintf("Invalid status code requested") + "\n"))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
@@ -0,0 +1,240 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	ErrNotIPv6Address = errors.New("not an IPv6 address")
+
+	nat64Prefix         = netip.MustParsePrefix("64:ff9b::/96")
+	solicitedNodePrefix = netip.MustParsePrefix("ff02::1:ff00:0/104")
+	sixToFourPrefix     = netip.MustParsePrefix("2002::/16")
+	teredoPrefix        = netip.MustParsePrefix("2001::/32")
+)
+
+var multicastScopes = map[byte]string{
+	0x1: "Interface-Local",
+	0x2: "Link-Local",
+	0x3: "Realm-Local",
+	0x4: "Admin-Local",
+	0x5: "Site-Local",
+	0x8: "Organization-Local",
+	0xe: "Global",
+}
+
+type IPv6Address struct {
+	Address        string `json:"address"`
+	Canonical      string `json:"canonical"`
+	Expanded       string `json:"expanded"`
+	Scope          string `json:"scope"`
+	MulticastScope string `json:"multicast_scope,omitempty"`
+	MulticastFlags string `json:"multicast_flags,omitempty"`
+	InterfaceID    string `json:"interface_id,omitempty"`
+	MAC            string `json:"mac,omitempty"`
+	Vendor         string `json:"vendor,omitempty"`
+	Embedding      string `json:"embedding,omitempty"`
+	EmbeddedIPv4   string `json:"embedded_ipv4,omitempty"`
+	TeredoServer   string `json:"teredo_server,omitempty"`
+	TeredoPort     int    `json:"teredo_port,omitempty"`
+	SolicitedNode  string `json:"solicited_node,omitempty"`
+}
+
+func (a IPv6Address) Fields() [][2]string {
+	fields := [][2]string{
+		{"Address", a.Address},
+		{"Canonical", a.Canonical},
+		{"Expanded", a.Expanded},
+		{"Scope", a.Scope},
+	}
+
+	for _, field := range []struct {
+		label string
+		value string
+	}{
+		{"Multicast scope", a.MulticastScope},
+		{"Multicast flags", a.MulticastFlags},
+		{"Interface ID", a.InterfaceID},
+		{"MAC", a.MAC},
+		{"Vendor", a.Vendor},
+		{"Embedding", a.Embedding},
+		{"Embedded IPv4", a.EmbeddedIPv4},
+		{"Teredo server", a.TeredoServer},
+	} {
+		if field.value != "" {
+			fields = append(fields, [2]string{field.label, field.value})
+		}
+	}
+
+	if a.TeredoPort != 0 {
+		fields = append(fields, [2]string{"Teredo port", strconv.Itoa(a.TeredoPort)})
+	}
+
+	if a.SolicitedNode != "" {
+		fields = append(fields, [2]string{"Solicited-node", a.SolicitedNode})
+	}
+
+	return fields
+}
+
+// multicastFlags describes the flag bits of a multicast address, as defined
+// in RFC 4291 and RFC 3956.
+func multicastFlags(flags byte) string {
+	var names []string
+
+	if flags&0x4 != 0 {
+		names = append(names, "Rendezvous Point")
+	}
+
+	if flags&0x2 != 0 {
+		names = append(names, "Prefix-based")
+	}
+
+	if flags&0x1 != 0 {
+		names = append(names, "Transient")
+	} else {
+		names = append(names, "Permanent")
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func inspectIPv6(input string) (IPv6Address, error) {
+	addr, err := netip.ParseAddr(input)
+	if err != nil || !addr.Is6() {
+		return IPv6Address{}, ErrNotIPv6Address
+	}
+
+	addr = addr.WithZone("")
+
+	b := addr.As16()
+
+	result := IPv6Address{
+		Address:   input,
+		Canonical: addr.String(),
+		Expanded:  addr.StringExpanded(),
+		Scope:     addressScope(addr),
+	}
+
+	if addr.IsMulticast() {
+		scope, ok := multicastScopes[b[1]&0x0f]
+		if !ok {
+			scope = fmt.Sprintf("Reserved (%x)", b[1]&0x0f)
+		}
+
+		result.MulticastScope = scope
+		result.MulticastFlags = multicastFlags(b[1] >> 4)
+
+		return result, nil
+	}
+
+	var embedded [4]byte
+
+	switch {
+	case addr.Is4In6():
+		result.Embedding = "IPv4-mapped"
+
+		copy(embedded[:], b[12:])
+	case nat64Prefix.Contains(addr):
+		result.Embedding = "NAT64"
+
+		copy(embedded[:], b[12:])
+	case sixToFourPrefix.Contains(addr):
+		result.Embedding = "6to4"
+
+		copy(embedded[:], b[2:6])
+	case teredoPrefix.Contains(addr):
+		// The client address and port are stored with every bit inverted.
+		result.Embedding = "Teredo"
+
+		for i := range embedded {
+			embedded[i] = b[12+i] ^ 0xff
+		}
+
+		result.TeredoServer = netip.AddrFrom4([4]byte(b[4:8])).String()
+		result.TeredoPort = int(binary.BigEndian.Uint16(b[10:12]) ^ 0xffff)
+	}
+
+	if result.Embedding != "" {
+		result.EmbeddedIPv4 = netip.AddrFrom4(embedded).String()
+	}
+
+	if !addr.IsUnspecified() && !addr.IsLoopback() && !addr.Is4In6() {
+		result.InterfaceID = fmt.Sprintf("%04x:%04x:%04x:%04x",
+			binary.BigEndian.Uint16(b[8:10]),
+			binary.BigEndian.Uint16(b[10:12]),
+			binary.BigEndian.Uint16(b[12:14]),
+			binary.BigEndian.Uint16(b[14:16]))
+
+		// Modified EUI-64 interface IDs are built from a MAC address by
+		// inserting ff:fe in the middle and flipping the universal/local bit.
+		if b[11] == 0xff && b[12] == 0xfe {
+			mac := net.HardwareAddr{b[8] ^ 0x02, b[9], b[10], b[13], b[14], b[15]}
+
+			result.MAC = mac.String()
+
+			if vendor, ok := lookupVendor(result.MAC); ok {
+				result.Vendor = vendor
+			}
+		}
+
+		solicited := solicitedNodePrefix.Addr().As16()
+
+		copy(solicited[13:], b[13:])
+
+		result.SolicitedNode = netip.AddrFrom16(solicited).String()
+	}
+
+	return result, nil
+}
+
+func serveIPv6Inspector(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		result, err := inspectIPv6(strings.Trim(p.ByName("inspect"), "/"))
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(formatFields(pr, result.Fields())))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
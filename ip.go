@@ -5,15 +5,147 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
 )
 
+const ipLookupTimeout = 5 * time.Second
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+type IPInfo struct {
+	Address           string   `json:"address"`
+	Version           int      `json:"version"`
+	Integer           string   `json:"integer"`
+	Hex               string   `json:"hex"`
+	Binary            string   `json:"binary"`
+	Classification    string   `json:"classification"`
+	RFC               string   `json:"rfc,omitempty"`
+	GloballyReachable bool     `json:"globally_reachable"`
+	ReverseName       string   `json:"reverse_name"`
+	Hostnames         []string `json:"hostnames,omitempty"`
+	ASN               string   `json:"asn,omitempty"`
+	Provider          string   `json:"provider,omitempty"`
+	Prefix            string   `json:"prefix,omitempty"`
+	Country           string   `json:"country,omitempty"`
+	Registry          string   `json:"registry,omitempty"`
+}
+
+func (i IPInfo) Fields(pr *message.Printer) [][2]string {
+	fields := [][2]string{
+		{"Address", i.Address},
+		{"Version", "IPv" + strconv.Itoa(i.Version)},
+		{"Integer", i.Integer},
+		{"Hexadecimal", i.Hex},
+		{"Binary", i.Binary},
+		{"Classification", i.Classification},
+	}
+
+	if i.RFC != "" {
+		fields = append(fields, [2]string{"Reference", i.RFC})
+	}
+
+	fields = append(fields,
+		[2]string{"Globally reachable", yesNo(pr, i.GloballyReachable)},
+		[2]string{"Reverse DNS name", i.ReverseName})
+
+	if len(i.Hostnames) > 0 {
+		fields = append(fields, [2]string{"Hostname(s)", strings.Join(i.Hostnames, ", ")})
+	}
+
+	for _, field := range []struct {
+		label string
+		value string
+	}{
+		{"ASN", i.ASN},
+		{"Provider", i.Provider},
+		{"Prefix", i.Prefix},
+		{"Country", i.Country},
+		{"Registry", i.Registry},
+	} {
+		if field.value != "" {
+			fields = append(fields, [2]string{field.label, field.value})
+		}
+	}
+
+	return fields
+}
+
+// ipInfo describes an address. PTR records are looked up with the resolver,
+// and the announcing network is added from the ASN backend when lookupASN is
+// set and the address is globally reachable.
+func ipInfo(ctx context.Context, input string, resolver *net.Resolver, backend ASNBackend, lookupASN bool) (IPInfo, error) {
+	addr, err := netip.ParseAddr(input)
+	if err != nil || addr.Zone() != "" {
+		return IPInfo{}, ErrInvalidAddress
+	}
+
+	addr = addr.Unmap()
+
+	b := addr.AsSlice()
+
+	result := IPInfo{
+		Address:        addr.String(),
+		Version:        4,
+		Integer:        fromAddr(addr).big().String(),
+		Hex:            fmt.Sprintf("0x%x", b),
+		Binary:         toBinary(b),
+		Classification: addressScope(addr),
+		ReverseName:    reverseName(addr, addr.BitLen()) + ".",
+	}
+
+	if addr.Is6() {
+		result.Version = 6
+	}
+
+	_, result.RFC, _, _ = lookupScope(addr)
+	result.GloballyReachable = globallyReachable(addr)
+
+	ctx, cancel := context.WithTimeout(ctx, ipLookupTimeout)
+	defer cancel()
+
+	// A missing PTR record is common enough that lookup errors are ignored.
+	hostnames, err := resolver.LookupAddr(ctx, addr.String())
+	if err == nil {
+		for _, hostname := range hostnames {
+			result.Hostnames = append(result.Hostnames, strings.TrimRight(hostname, "."))
+		}
+
+		sort.Strings(result.Hostnames)
+	}
+
+	if !lookupASN || !result.GloballyReachable {
+		return result, nil
+	}
+
+	records, err := backend.LookupAddrs(ctx, addr)
+	if err != nil {
+		return result, err
+	}
+
+	if records[0].Prefix.IsValid() {
+		result.ASN = records[0].ASString()
+		result.Provider = records[0].Name
+		result.Prefix = records[0].Prefix.String()
+		result.Country = records[0].Country
+		result.Registry = records[0].Registry
+	}
+
+	return result, nil
+}
+
 func realIP(r *http.Request, includePort bool) string {
 	fields := strings.SplitAfter(r.RemoteAddr, ":")
 
@@ -43,37 +175,98 @@ func realIP(r *http.Request, includePort bool) string {
 	}
 }
 
-func serveIP(errorChannel chan<- Error) httprouter.Handle {
+func serveIP(resolver *net.Resolver, backend ASNBackend, modules *Modules, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
-		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		if p.ByName("ip") == "" {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
 
-		securityHeaders(w)
+			securityHeaders(w)
 
-		if verbose {
-			fmt.Printf("%s | %s => %s\n",
-				startTime.Format(timeFormats["RFC3339"]),
-				realIP(r, true),
-				r.RequestURI)
+			if verbose {
+				fmt.Printf("%s | %s => %s\n",
+					startTime.Format(timeFormats["RFC3339"]),
+					realIP(r, true),
+					r.RequestURI)
+			}
+
+			_, err := w.Write([]byte(realIP(r, false) + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
 		}
 
-		_, err := w.Write([]byte(realIP(r, false) + "\n"))
+		pr := localizer(r)
+
+		// ASN data comes from the same backend as the DNS module, so it is
+		// only included while that module is enabled.
+		lookupASN := modules.Has("dns") && modules.Enabled("dns")
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		info, err := ipInfo(ctx, p.ByName("ip"), resolver, backend, lookupASN)
+		if errors.Is(err, ErrInvalidAddress) {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			securityHeaders(w)
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err = w.Write([]byte(pr.Sprintf("Invalid IP address requested") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		// Failing to find the announcing network still leaves the rest of the
+		// details worth returning.
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, info)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(formatFields(pr, info.Fields(pr))))
+		}
 		if err != nil {
 			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
 			return
 		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
 	}
 }
 
-func registerIP(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
+func registerIP(mux *httprouter.Router, usage *sync.Map, backend ASNBackend, server *upstreamSet, modules *Modules, errorChannel chan<- Error) {
 	const module = "ip"
 
-	mux.GET("/ip/", serveIP(errorChannel))
-	mux.GET("/ip/:ip", serveIP(errorChannel))
+	resolver := newResolver(server)
+
+	mux.GET("/ip/", serveIP(resolver, backend, modules, errorChannel))
+	mux.GET("/ip/:ip", serveIP(resolver, backend, modules, errorChannel))
 
 	usage.Store(module, []string{
 		"/ip/",
+		"/ip/8.8.8.8",
+		"/ip/2001:db8::1",
 	})
 }
@@ -0,0 +1,430 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Messages are keyed by their English text, which doubles as the fallback
+// for any language (or message) without a translation.
+var translations = map[language.Tag]map[string]string{
+	language.German: {
+		"%s of %s":          "%s von %s",
+		"%s via %s in %dms": "%s über %s in %dms",
+		"Additional":        "Zusätzlich",
+		"Address":           "Adresse",
+		"Addresses":         "Adressen",
+		"Allocated":         "Zugewiesen",
+		"Answer":            "Antwort",
+		"ASN":               "ASN",
+		"Authority":         "Autorität",
+		"Batch item count must be no greater than %d": "Die Anzahl der Batch-Einträge darf höchstens %d betragen",
+		"Binary":                      "Binär",
+		"Broadcast":                   "Broadcast",
+		"Cannot roll zero dice":       "Es kann nicht mit null Würfeln gewürfelt werden",
+		"Canonical":                   "Kanonisch",
+		"Chain of trust":              "Vertrauenskette",
+		"Class":                       "Klasse",
+		"Classification":              "Klassifizierung",
+		"Consistent":                  "Übereinstimmend",
+		"Contained":                   "Enthalten",
+		"Country":                     "Land",
+		"Decimal":                     "Dezimal",
+		"Delegated zone":              "Delegierte Zone",
+		"Dice cannot have zero sides": "Würfel können nicht null Seiten haben",
+		"Dice roll count must be no greater than %d": "Die Anzahl der Würfel darf höchstens %d betragen",
+		"Dice side count must be no greater than %d": "Die Anzahl der Seiten darf höchstens %d betragen",
+		"Differs":                                "Abweichend",
+		"Domain":                                 "Domain",
+		"EDNS":                                   "EDNS",
+		"Embedded IPv4":                          "Eingebettete IPv4",
+		"Embedding":                              "Einbettung",
+		"Entry count must be no greater than %d": "Die Anzahl der Einträge darf höchstens %d betragen",
+		"Examples:":                              "Beispiele:",
+		"Expanded":                               "Ausgeschrieben",
+		"Extra addresses":                        "Zusätzliche Adressen",
+		"Failed":                                 "Fehlgeschlagen",
+		"Failed to encode string":                "Zeichenkette konnte nicht kodiert werden",
+		"Failed to hash string":                  "Hash der Zeichenkette konnte nicht berechnet werden",
+		"Failure":                                "Fehler",
+		"Family":                                 "Familie",
+		"First":                                  "Erste",
+		"First usable":                           "Erste nutzbare",
+		"Flags":                                  "Flags",
+		"Free":                                   "Frei",
+		"Globally reachable":                     "Global erreichbar",
+		"glue":                                   "Glue",
+		"Hex (Full)":                             "Hex (vollständig)",
+		"Hex (Shortened)":                        "Hex (gekürzt)",
+		"Hexadecimal":                            "Hexadezimal",
+		"Hostname(s)":                            "Hostname(n)",
+		"Hosts":                                  "Hosts",
+		"Integer":                                "Ganzzahl",
+		"Interface ID":                           "Schnittstellen-ID",
+		"Interval must be between 1s and 1h":     "Das Intervall muss zwischen 1s und 1h liegen",
+		"Invalid aggregation request":            "Ungültige Aggregationsanfrage",
+		"Invalid AS number requested":            "Ungültige AS-Nummer angefordert",
+		"Invalid batch request":                  "Ungültige Batch-Anfrage",
+		"Invalid DKIM selector requested":        "Ungültiger DKIM-Selektor angefordert",
+		"Invalid DNS message":                    "Ungültige DNS-Nachricht",
+		"Invalid entry %s":                       "Ungültiger Eintrag %s",
+		"Invalid free space request":             "Ungültige Anfrage nach freiem Adressraum",
+		"Invalid hash algorithm requested":       "Ungültiger Hash-Algorithmus angefordert",
+		"Invalid IP address requested":           "Ungültige IP-Adresse angefordert",
+		"Invalid name requested":                 "Ungültiger Name angefordert",
+		"Invalid record type requested":          "Ungültiger Eintragstyp angefordert",
+		"Invalid room name":                      "Ungültiger Raumname",
+		"Invalid status code requested":          "Ungültiger Statuscode angefordert",
+		"Invalid subnet requested":               "Ungültiges Subnetz angefordert",
+		"Invalid timezone requested":             "Ungültige Zeitzone angefordert",
+		"Last":                                   "Letzte",
+		"Last usable":                            "Letzte nutzbare",
+		"Lookup failed":                          "Abfrage fehlgeschlagen",
+		"MAC":                                    "MAC",
+		"Mask":                                   "Maske",
+		"Maximum prefix count must be a positive integer": "Die maximale Anzahl an Präfixen muss eine positive ganze Zahl sein",
+		"Module disabled":                      "Modul deaktiviert",
+		"Multicast flags":                      "Multicast-Flags",
+		"Multicast scope":                      "Multicast-Bereich",
+		"Name":                                 "Name",
+		"Network":                              "Netzwerk",
+		"No":                                   "Nein",
+		"No AS found":                          "Kein AS gefunden",
+		"No free blocks of the requested size": "Keine freien Blöcke der angeforderten Größe",
+		"No OUI found for MAC %q":              "Keine OUI für MAC %q gefunden",
+		"No string provided to encode":         "Keine Zeichenkette zum Kodieren angegeben",
+		"not resolved":                         "nicht aufgelöst",
+		"Option":                               "Option",
+		"Overlaps":                             "Überschneidung",
+		"Page":                                 "Seite",
+		"Parent":                               "Übergeordnet",
+		"Parent zone":                          "Übergeordnete Zone",
+		"Prefix":                               "Präfix",
+		"Prefix A":                             "Präfix A",
+		"Prefix B":                             "Präfix B",
+		"Prefix length":                        "Präfixlänge",
+		"Prefixes":                             "Präfixe",
+		"Provider":                             "Anbieter",
+		"Query":                                "Abfrage",
+		"Range":                                "Bereich",
+		"Records for the %s zone":              "Einträge für die Zone %s",
+		"Reference":                            "Referenz",
+		"Registry":                             "Registry",
+		"Requested hosts do not fit in %s":     "Die angeforderten Hosts passen nicht in %s",
+		"resolved":                             "aufgelöst",
+		"Resolvers":                            "Resolver",
+		"Reverse DNS name":                     "Reverse-DNS-Name",
+		"Scope":                                "Bereich",
+		"Server":                               "Server",
+		"Solicited-node":                       "Solicited-Node",
+		"Status":                               "Status",
+		"Subnets":                              "Subnetze",
+		"Summary":                              "Zusammenfassung",
+		"Teredo port":                          "Teredo-Port",
+		"Teredo server":                        "Teredo-Server",
+		"Time":                                 "Zeit",
+		"Too many active streams":              "Zu viele aktive Streams",
+		"Too many requests":                    "Zu viele Anfragen",
+		"Total":                                "Gesamt",
+		"Total: ":                              "Summe: ",
+		"Unsupported media type":               "Nicht unterstützter Medientyp",
+		"Usable":                               "Nutzbar",
+		"Usable hosts":                         "Nutzbare Hosts",
+		"Used":                                 "Belegt",
+		"Vendor":                               "Hersteller",
+		"Version":                              "Version",
+		"Wildcard":                             "Wildcard",
+		"Yes":                                  "Ja",
+		"Zones":                                "Zonen",
+	},
+	language.Spanish: {
+		"%s of %s":          "%s de %s",
+		"%s via %s in %dms": "%s a través de %s en %dms",
+		"Additional":        "Adicional",
+		"Address":           "Dirección",
+		"Addresses":         "Direcciones",
+		"Allocated":         "Asignadas",
+		"Answer":            "Respuesta",
+		"ASN":               "ASN",
+		"Authority":         "Autoridad",
+		"Batch item count must be no greater than %d": "El número de elementos del lote no puede ser mayor que %d",
+		"Binary":                      "Binario",
+		"Broadcast":                   "Difusión",
+		"Cannot roll zero dice":       "No se pueden tirar cero dados",
+		"Canonical":                   "Canónica",
+		"Chain of trust":              "Cadena de confianza",
+		"Class":                       "Clase",
+		"Classification":              "Clasificación",
+		"Consistent":                  "Coherente",
+		"Contained":                   "Contenida",
+		"Country":                     "País",
+		"Decimal":                     "Decimal",
+		"Delegated zone":              "Zona delegada",
+		"Dice cannot have zero sides": "Los dados no pueden tener cero caras",
+		"Dice roll count must be no greater than %d": "El número de dados no puede ser mayor que %d",
+		"Dice side count must be no greater than %d": "El número de caras no puede ser mayor que %d",
+		"Differs":                                "Difiere",
+		"Domain":                                 "Dominio",
+		"EDNS":                                   "EDNS",
+		"Embedded IPv4":                          "IPv4 incrustada",
+		"Embedding":                              "Incrustación",
+		"Entry count must be no greater than %d": "El número de entradas no puede ser mayor que %d",
+		"Examples:":                              "Ejemplos:",
+		"Expanded":                               "Expandida",
+		"Extra addresses":                        "Direcciones adicionales",
+		"Failed":                                 "Falló",
+		"Failed to encode string":                "No se pudo codificar la cadena",
+		"Failed to hash string":                  "No se pudo calcular el hash de la cadena",
+		"Failure":                                "Fallo",
+		"Family":                                 "Familia",
+		"First":                                  "Primera",
+		"First usable":                           "Primera utilizable",
+		"Flags":                                  "Indicadores",
+		"Free":                                   "Libres",
+		"Globally reachable":                     "Accesible globalmente",
+		"glue":                                   "glue",
+		"Hex (Full)":                             "Hex (completo)",
+		"Hex (Shortened)":                        "Hex (abreviado)",
+		"Hexadecimal":                            "Hexadecimal",
+		"Hostname(s)":                            "Nombre(s) de host",
+		"Hosts":                                  "Hosts",
+		"Integer":                                "Entero",
+		"Interface ID":                           "ID de interfaz",
+		"Interval must be between 1s and 1h":     "El intervalo debe estar entre 1s y 1h",
+		"Invalid aggregation request":            "Solicitud de agregación no válida",
+		"Invalid AS number requested":            "Número de AS no válido solicitado",
+		"Invalid batch request":                  "Solicitud de lote no válida",
+		"Invalid DKIM selector requested":        "Selector DKIM no válido solicitado",
+		"Invalid DNS message":                    "Mensaje DNS no válido",
+		"Invalid entry %s":                       "Entrada no válida %s",
+		"Invalid free space request":             "Solicitud de espacio libre no válida",
+		"Invalid hash algorithm requested":       "Se solicitó un algoritmo de hash no válido",
+		"Invalid IP address requested":           "Dirección IP no válida solicitada",
+		"Invalid name requested":                 "Nombre no válido solicitado",
+		"Invalid record type requested":          "Tipo de registro no válido solicitado",
+		"Invalid room name":                      "Nombre de sala no válido",
+		"Invalid status code requested":          "Se solicitó un código de estado no válido",
+		"Invalid subnet requested":               "Se solicitó una subred no válida",
+		"Invalid timezone requested":             "Se solicitó una zona horaria no válida",
+		"Last":                                   "Última",
+		"Last usable":                            "Última utilizable",
+		"Lookup failed":                          "La consulta falló",
+		"MAC":                                    "MAC",
+		"Mask":                                   "Máscara",
+		"Maximum prefix count must be a positive integer": "El número máximo de prefijos debe ser un entero positivo",
+		"Module disabled":                      "Módulo deshabilitado",
+		"Multicast flags":                      "Indicadores de multidifusión",
+		"Multicast scope":                      "Ámbito de multidifusión",
+		"Name":                                 "Nombre",
+		"Network":                              "Red",
+		"No":                                   "No",
+		"No AS found":                          "No se encontró ningún AS",
+		"No free blocks of the requested size": "No hay bloques libres del tamaño solicitado",
+		"No OUI found for MAC %q":              "No se encontró ningún OUI para la MAC %q",
+		"No string provided to encode":         "No se proporcionó ninguna cadena para codificar",
+		"not resolved":                         "no resuelto",
+		"Option":                               "Opción",
+		"Overlaps":                             "Se superponen",
+		"Page":                                 "Página",
+		"Parent":                               "Padre",
+		"Parent zone":                          "Zona padre",
+		"Prefix":                               "Prefijo",
+		"Prefix A":                             "Prefijo A",
+		"Prefix B":                             "Prefijo B",
+		"Prefix length":                        "Longitud del prefijo",
+		"Prefixes":                             "Prefijos",
+		"Provider":                             "Proveedor",
+		"Query":                                "Consulta",
+		"Range":                                "Rango",
+		"Records for the %s zone":              "Registros para la zona %s",
+		"Reference":                            "Referencia",
+		"Registry":                             "Registro",
+		"Requested hosts do not fit in %s":     "Los hosts solicitados no caben en %s",
+		"resolved":                             "resuelto",
+		"Resolvers":                            "Resolutores",
+		"Reverse DNS name":                     "Nombre DNS inverso",
+		"Scope":                                "Ámbito",
+		"Server":                               "Servidor",
+		"Solicited-node":                       "Nodo solicitado",
+		"Status":                               "Estado",
+		"Subnets":                              "Subredes",
+		"Summary":                              "Resumen",
+		"Teredo port":                          "Puerto Teredo",
+		"Teredo server":                        "Servidor Teredo",
+		"Time":                                 "Tiempo",
+		"Too many active streams":              "Demasiadas transmisiones activas",
+		"Too many requests":                    "Demasiadas solicitudes",
+		"Total":                                "Total",
+		"Unsupported media type":               "Tipo de medio no admitido",
+		"Usable":                               "Utilizables",
+		"Usable hosts":                         "Hosts utilizables",
+		"Used":                                 "Usadas",
+		"Vendor":                               "Fabricante",
+		"Version":                              "Versión",
+		"Wildcard":                             "Comodín",
+		"Yes":                                  "Sí",
+		"Zones":                                "Zonas",
+	},
+}
+
+type CalendarNames struct {
+	Months      [12]string
+	ShortMonths [12]string
+	Days        [7]string
+	ShortDays   [7]string
+}
+
+var calendarNames = map[language.Base]CalendarNames{
+	language.MustParseBase("de"): {
+		Months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		ShortMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		Days:        [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		ShortDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+	},
+	language.MustParseBase("es"): {
+		Months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		ShortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		Days:        [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		ShortDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+	},
+}
+
+var messages = func() *catalog.Builder {
+	c := catalog.NewBuilder(catalog.Fallback(language.English))
+
+	for tag, values := range translations {
+		for key, value := range values {
+			c.SetString(tag, key, value)
+		}
+	}
+
+	return c
+}()
+
+// supportedLanguages are those with translations, in order of preference
+// when nothing better matches.
+var supportedLanguages = language.NewMatcher([]language.Tag{
+	language.English,
+	language.German,
+	language.Spanish,
+})
+
+// requestLanguage picks the best supported language from the client's
+// preferences. The matched tag keeps the region that was asked for, so that
+// numbers are still formatted accordingly. If none of them are supported, the first preference
+// is returned as-is, and messages fall back to English.
+func requestLanguage(r *http.Request) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 || tags[0] == language.Und {
+		return language.English
+	}
+
+	tag, _, confidence := supportedLanguages.Match(tags...)
+	if confidence == language.No {
+		return tags[0]
+	}
+
+	return tag
+}
+
+// localizer returns a printer for the client's preferred language. Messages
+// without a translation fall back to English, but numbers are still
+// formatted according to the requested locale.
+func localizer(r *http.Request) *message.Printer {
+	return message.NewPrinter(requestLanguage(r), message.Catalog(messages))
+}
+
+// groupDigits formats an arbitrarily large integer using the digit grouping
+// of the printer's locale.
+func groupDigits(pr *message.Printer, n *big.Int) string {
+	sample := pr.Sprintf("%d", 1000)
+
+	separator := strings.TrimSuffix(strings.TrimPrefix(sample, "1"), "000")
+
+	digits := n.String()
+
+	negative := strings.HasPrefix(digits, "-")
+	digits = strings.TrimPrefix(digits, "-")
+
+	var output strings.Builder
+
+	if negative {
+		output.WriteString("-")
+	}
+
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			output.WriteString(separator)
+		}
+
+		output.WriteRune(digit)
+	}
+
+	return output.String()
+}
+
+// localizeTime formats t according to layout, replacing month and weekday
+// names with those of the requested language where available.
+func localizeTime(t time.Time, layout string, tag language.Tag) string {
+	base, _ := tag.Base()
+
+	names, ok := calendarNames[base]
+	if !ok {
+		return t.Format(layout)
+	}
+
+	// Swap each name element in the layout for a placeholder that time.Format
+	// passes through untouched, then substitute the localized names after.
+	placeholders := []struct {
+		element     string
+		placeholder string
+		value       string
+	}{
+		{"January", "\x01", names.Months[t.Month()-1]},
+		{"Jan", "\x02", names.ShortMonths[t.Month()-1]},
+		{"Monday", "\x03", names.Days[t.Weekday()]},
+		{"Mon", "\x04", names.ShortDays[t.Weekday()]},
+	}
+
+	var converted strings.Builder
+
+	for i := 0; i < len(layout); {
+		matched := false
+
+		for _, p := range placeholders {
+			if strings.HasPrefix(layout[i:], p.element) {
+				converted.WriteString(p.placeholder)
+
+				i += len(p.element)
+
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			converted.WriteByte(layout[i])
+
+			i++
+		}
+	}
+
+	formatted := t.Format(converted.String())
+
+	for _, p := range placeholders {
+		formatted = strings.ReplaceAll(formatted, p.placeholder, p.value)
+	}
+
+	return formatted
+}
@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -22,6 +23,8 @@ import (
 //go:embed oui.txt
 var ouis embed.FS
 
+var ouiDatabase atomic.Pointer[sync.Map]
+
 func firstN(s string, n int) string {
 	i := 0
 	for j := range s {
@@ -115,43 +118,31 @@ func format(line string, re *regexp.Regexp) ([]string, string) {
 	return ouis, vendor
 }
 
-func parseOUIs(errorChannel chan<- Error) *sync.Map {
-	startTime := time.Now()
+func loadOUIs() (*sync.Map, int, error) {
+	retVal := sync.Map{}
 
 	whiteSpace := regexp.MustCompile(`\s+`)
 
-	retVal := sync.Map{}
-
 	var f fs.File
 	var err error
 
 	if ouiFile == "" {
 		f, err = ouis.Open("oui.txt")
-		if err != nil {
-			errorChannel <- Error{Message: err, Path: "parseOUIs()"}
-
-			return &retVal
-		}
 	} else {
 		f, err = os.Open(ouiFile)
-		if err != nil {
-			errorChannel <- Error{Message: err, Path: "parseOUIs()"}
-
-			return &retVal
-		}
 	}
-	defer func() {
-		err = f.Close()
-		if err != nil {
-			errorChannel <- Error{Message: err, Path: "parseOUIs()"}
-		}
-	}()
+	if err != nil {
+		return &retVal, 0, err
+	}
+	defer f.Close()
 
 	s := bufio.NewScanner(f)
 	b := make([]byte, 0, 64*1024)
 	s.Buffer(b, 1024*1024)
 	s.Split(bufio.ScanLines)
 
+	count := 0
+
 	for s.Scan() {
 		line := s.Text()
 
@@ -164,6 +155,25 @@ func parseOUIs(errorChannel chan<- Error) *sync.Map {
 		for i := range oui {
 			retVal.Store(oui[i], vendor)
 		}
+
+		count += len(oui)
+	}
+
+	if err = s.Err(); err != nil {
+		return &retVal, count, err
+	}
+
+	return &retVal, count, nil
+}
+
+func parseOUIs(errorChannel chan<- Error) *sync.Map {
+	startTime := time.Now()
+
+	retVal, _, err := loadOUIs()
+	if err != nil {
+		errorChannel <- Error{Message: err, Path: "parseOUIs()"}
+
+		return retVal
 	}
 
 	if verbose {
@@ -172,10 +182,41 @@ func parseOUIs(errorChannel chan<- Error) *sync.Map {
 			time.Since(startTime).Milliseconds())
 	}
 
-	return &retVal
+	return retVal
+}
+
+// reloadOUIs re-reads the OUI database and swaps it in for the running
+// handlers. The previous database is kept if the new one fails to load.
+func reloadOUIs() (int, error) {
+	retVal, count, err := loadOUIs()
+	if err != nil {
+		return 0, err
+	}
+
+	ouiDatabase.Store(retVal)
+
+	return count, nil
+}
+
+// lookupVendor returns the vendor assigned the longest matching OUI prefix
+// of a MAC address.
+func lookupVendor(mac string) (string, bool) {
+	ouis := ouiDatabase.Load()
+	if ouis == nil {
+		return "", false
+	}
+
+	for i := 12; i >= 6; i -= 2 {
+		v, ok := ouis.Load(strings.Join(chunks(firstN(strip(strings.ToUpper(mac)), i), 2), ":"))
+		if ok {
+			return v.(string), true
+		}
+	}
+
+	return "", false
 }
 
-func serveMAC(ouis *sync.Map, errorChannel chan<- Error) httprouter.Handle {
+func serveMAC(errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
@@ -185,20 +226,9 @@ func serveMAC(ouis *sync.Map, errorChannel chan<- Error) httprouter.Handle {
 
 		mac := strings.TrimPrefix(p.ByName("mac"), "/")
 
-		val := ""
-
-		for i := 12; i >= 6; i -= 2 {
-			v, ok := ouis.Load(strings.Join(chunks(firstN(strip(strings.ToUpper(mac)), i), 2), ":"))
-
-			if ok {
-				val = v.(string)
-
-				break
-			}
-		}
-
-		if val == "" {
-			val = fmt.Sprintf("No OUI found for MAC %q", mac)
+		val, ok := lookupVendor(mac)
+		if !ok {
+			val = localizer(r).Sprintf("No OUI found for MAC %q", mac)
 		}
 
 		if verbose {
@@ -220,9 +250,9 @@ func serveMAC(ouis *sync.Map, errorChannel chan<- Error) httprouter.Handle {
 func registerMAC(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
 	const module = "mac"
 
-	ouis := parseOUIs(errorChannel)
+	ouiDatabase.Store(parseOUIs(errorChannel))
 
-	mux.GET("/mac/:mac", serveMAC(ouis, errorChannel))
+	mux.GET("/mac/:mac", serveMAC(errorChannel))
 	mux.GET("/mac/", serveUsage(module, usage, errorChannel))
 
 	usage.Store(module, []string{
@@ -0,0 +1,1265 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
+)
+
+const (
+	// mailCheckTimeout bounds every check made for a domain, and
+	// mtaSTSTimeout the fetch of its MTA-STS policy.
+	mailCheckTimeout = 30 * time.Second
+	mtaSTSTimeout    = 10 * time.Second
+
+	// spfLookupLimit is the number of DNS lookups an SPF record may cause
+	// before evaluating it becomes a permanent error, per RFC 7208.
+	spfLookupLimit = 10
+
+	maxDKIMSelectors    = 10
+	maxMTASTSPolicySize = 64 * 1024
+
+	// MTA-STS policies may be cached for at most a year, and policies cached
+	// for less than a day offer little protection.
+	mtaSTSMaxAge   = 31557600
+	mtaSTSShortAge = 86400
+)
+
+const (
+	mailPass = "pass"
+	mailWarn = "warn"
+	mailFail = "fail"
+)
+
+var (
+	ErrInvalidSelector    = errors.New("invalid DKIM selector")
+	ErrUnreachableAddress = errors.New("address is not globally reachable")
+)
+
+var mtaSTSID = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+type MailExchanger struct {
+	Host       string   `json:"host"`
+	Preference uint16   `json:"preference"`
+	Addresses  []string `json:"addresses"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// MailCheck is the outcome of checking one item. Records holds what the
+// domain publishes, and Details each problem found along with anything else
+// worth knowing, such as the records an SPF record includes.
+type MailCheck struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Summary string   `json:"summary"`
+	Records []string `json:"records,omitempty"`
+	Details []string `json:"details,omitempty"`
+}
+
+type MailCheckResult struct {
+	Domain string          `json:"domain"`
+	Status string          `json:"status"`
+	MX     []MailExchanger `json:"mx"`
+	Checks []MailCheck     `json:"checks"`
+
+	targets []nameserverTarget
+	infos   map[netip.Addr]addressInfo
+}
+
+func newMailCheck(name string) MailCheck {
+	return MailCheck{Name: name, Status: mailPass}
+}
+
+func (c *MailCheck) note(format string, a ...any) {
+	c.Details = append(c.Details, fmt.Sprintf(format, a...))
+}
+
+func (c *MailCheck) warn(format string, a ...any) {
+	if c.Status == mailPass {
+		c.Status = mailWarn
+	}
+
+	c.note(format, a...)
+}
+
+func (c *MailCheck) fail(format string, a ...any) {
+	c.Status = mailFail
+
+	c.note(format, a...)
+}
+
+// worstStatus returns the most serious status of the given checks.
+func worstStatus(checks []MailCheck) string {
+	status := mailPass
+
+	for _, check := range checks {
+		switch {
+		case check.Status == mailFail:
+			return mailFail
+		case check.Status == mailWarn:
+			status = mailWarn
+		}
+	}
+
+	return status
+}
+
+// findRecords returns the TXT records at name which begin with the given
+// version tag. A name without any is not an error.
+func findRecords(ctx context.Context, resolver *net.Resolver, name, version string) ([]string, error) {
+	records, err := resolver.LookupTXT(ctx, name)
+
+	var dnsErr *net.DNSError
+
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+
+	for _, record := range records {
+		if hasVersion(record, version) {
+			found = append(found, record)
+		}
+	}
+
+	return found, nil
+}
+
+// hasVersion reports whether a record starts with a version tag, ignoring
+// case. The tag must be followed by a separator, so that v=spf10 is not
+// taken for v=spf1.
+func hasVersion(record, version string) bool {
+	if len(record) < len(version) || !strings.EqualFold(record[:len(version)], version) {
+		return false
+	}
+
+	rest := record[len(version):]
+
+	return rest == "" || rest[0] == ' ' || rest[0] == ';'
+}
+
+// parseTags splits the tag=value lists used by DMARC, DKIM, MTA-STS, TLS-RPT
+// and BIMI records. Tag names are lowercased.
+func parseTags(record string) (map[string]string, error) {
+	tags := make(map[string]string)
+
+	for part := range strings.SplitSeq(record, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("%q is not a tag=value pair", part)
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if _, ok := tags[name]; ok {
+			return nil, fmt.Errorf("tag %q is given more than once", name)
+		}
+
+		tags[name] = strings.TrimSpace(value)
+	}
+
+	return tags, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var entries []string
+
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// singleRecord reports a missing or duplicated record, returning false if
+// there isn't exactly one to check. A missing record is a failure if it is
+// required, and a warning otherwise.
+func (c *MailCheck) singleRecord(records []string, err error, name string, required bool) bool {
+	switch {
+	case err != nil:
+		c.Summary = "Lookup failed"
+
+		c.fail("Lookup of %s failed: %v", name, err)
+	case len(records) == 0 && required:
+		c.Summary = "Not published"
+
+		c.fail("No %s record at %s", c.Name, name)
+	case len(records) == 0:
+		c.Summary = "Not published"
+
+		c.warn("No %s record at %s", c.Name, name)
+	case len(records) > 1:
+		c.Records = records
+		c.Summary = "Multiple records"
+
+		c.fail("Only one %s record may be published at %s", c.Name, name)
+	default:
+		c.Records = records
+
+		return true
+	}
+
+	return false
+}
+
+func checkMX(targets []nameserverTarget, err error) MailCheck {
+	check := newMailCheck("MX")
+
+	var dnsErr *net.DNSError
+
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound, err == nil && len(targets) == 0:
+		check.Summary = "No MX records"
+
+		check.warn("No MX records, so mail is delivered to the domain's own address")
+
+		return check
+	case err != nil:
+		check.Summary = "Lookup failed"
+
+		check.fail("Lookup failed: %v", err)
+
+		return check
+	}
+
+	// A null MX record (RFC 7505) says the domain accepts no mail, and must
+	// be the only one.
+	if slices.ContainsFunc(targets, func(t nameserverTarget) bool { return t.host == "." }) {
+		if len(targets) > 1 {
+			check.Summary = "Null MX with other records"
+
+			check.fail("A null MX record must not be published alongside others")
+
+			return check
+		}
+
+		check.Summary = "Null MX, accepts no mail"
+
+		return check
+	}
+
+	check.Summary = fmt.Sprintf("%d mail exchangers", len(targets))
+
+	failed := 0
+
+	for _, target := range targets {
+		if target.err != nil {
+			failed++
+
+			check.warn("%s does not resolve: %v", strings.TrimRight(target.host, "."), target.err)
+		}
+	}
+
+	if failed == len(targets) {
+		check.Status = mailFail
+	}
+
+	return check
+}
+
+// spfMechanisms lists the mechanisms defined by RFC 7208, and whether each
+// one costs a DNS lookup when evaluated.
+var spfMechanisms = map[string]bool{
+	"all":     false,
+	"include": true,
+	"a":       true,
+	"mx":      true,
+	"ptr":     true,
+	"ip4":     false,
+	"ip6":     false,
+	"exists":  true,
+}
+
+type spfEvaluator struct {
+	ctx      context.Context
+	resolver *net.Resolver
+	check    *MailCheck
+	lookups  int
+}
+
+// spfModifier splits a name=value modifier, reporting false if the term is a
+// mechanism instead.
+func spfModifier(term string) (string, string, bool) {
+	name, value, found := strings.Cut(term, "=")
+	if !found || name == "" || strings.ContainsAny(name, ":/") {
+		return "", "", false
+	}
+
+	c := name[0] | 0x20
+	if c < 'a' || c > 'z' {
+		return "", "", false
+	}
+
+	return strings.ToLower(name), value, true
+}
+
+// validSPFNetwork checks the argument to an ip4 or ip6 mechanism.
+func validSPFNetwork(value string, v4 bool) bool {
+	address, bits, hasBits := strings.Cut(value, "/")
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil || addr.Is4() != v4 {
+		return false
+	}
+
+	if hasBits {
+		n, err := strconv.Atoi(bits)
+		if err != nil || n < 0 || n > addr.BitLen() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// evaluate walks an SPF record, following its includes and redirect, and
+// returns the qualifier of the all mechanism that applies to it. The path
+// holds each domain being evaluated, to catch records that include
+// themselves.
+func (e *spfEvaluator) evaluate(domain, record string, path []string) string {
+	var all, redirect string
+
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := spfModifier(term); ok {
+			// Unknown modifiers are ignored, per RFC 7208.
+			if name == "redirect" {
+				if redirect != "" {
+					e.check.fail("%s: redirect is given more than once", domain)
+				}
+
+				redirect = value
+			}
+
+			continue
+		}
+
+		if all != "" {
+			e.check.warn("%s: %s comes after all, so is never evaluated", domain, term)
+
+			continue
+		}
+
+		qualifier := "+"
+
+		mechanism := term
+
+		if strings.ContainsAny(mechanism[:1], "+-~?") {
+			qualifier, mechanism = mechanism[:1], mechanism[1:]
+		}
+
+		name, value := mechanism, ""
+
+		if i := strings.IndexAny(mechanism, ":/"); i != -1 {
+			name = mechanism[:i]
+
+			if mechanism[i] == ':' {
+				value = mechanism[i+1:]
+			}
+		}
+
+		name = strings.ToLower(name)
+
+		lookup, known := spfMechanisms[name]
+		if !known {
+			e.check.fail("%s: unknown mechanism %q", domain, term)
+
+			continue
+		}
+
+		if lookup {
+			e.lookups++
+		}
+
+		switch name {
+		case "all":
+			all = qualifier
+		case "include":
+			e.follow(term, value, path)
+		case "exists":
+			if value == "" {
+				e.check.fail("%s: %s needs a domain", domain, term)
+			}
+		case "ptr":
+			e.check.warn("%s: the ptr mechanism is deprecated", domain)
+		case "ip4", "ip6":
+			if !validSPFNetwork(value, name == "ip4") {
+				e.check.fail("%s: %q is not a valid %s network", domain, value, name)
+			}
+		}
+	}
+
+	if redirect == "" {
+		return all
+	}
+
+	if all != "" {
+		e.check.warn("%s: redirect is ignored, as the record has an all mechanism", domain)
+
+		return all
+	}
+
+	e.lookups++
+
+	return e.follow("redirect="+redirect, redirect, path)
+}
+
+// follow evaluates the record named by an include or redirect, returning the
+// qualifier of its all mechanism.
+func (e *spfEvaluator) follow(term, target string, path []string) string {
+	switch {
+	case target == "":
+		e.check.fail("%s needs a domain", term)
+
+		return ""
+	case strings.Contains(target, "%"):
+		e.check.note("%s: not followed, as it uses macros", term)
+
+		return ""
+	case e.lookups > spfLookupLimit:
+		return ""
+	case slices.ContainsFunc(path, func(domain string) bool { return strings.EqualFold(domain, target) }):
+		e.check.fail("%s: loops back to a record already being evaluated", term)
+
+		return ""
+	}
+
+	records, err := findRecords(e.ctx, e.resolver, target, "v=spf1")
+
+	switch {
+	case err != nil:
+		e.check.fail("%s: lookup failed: %v", term, err)
+
+		return ""
+	case len(records) == 0:
+		e.check.fail("%s: no SPF record found", term)
+
+		return ""
+	case len(records) > 1:
+		e.check.fail("%s: multiple SPF records found", term)
+
+		return ""
+	}
+
+	e.check.note("%s: %s", term, records[0])
+
+	return e.evaluate(target, records[0], append(slices.Clone(path), target))
+}
+
+func checkSPF(ctx context.Context, resolver *net.Resolver, domain string) MailCheck {
+	check := newMailCheck("SPF")
+
+	records, err := findRecords(ctx, resolver, domain, "v=spf1")
+	if !check.singleRecord(records, err, domain, true) {
+		return check
+	}
+
+	e := &spfEvaluator{ctx: ctx, resolver: resolver, check: &check}
+
+	all := e.evaluate(domain, records[0], []string{domain})
+
+	switch all {
+	case "+":
+		check.fail("+all lets any server send mail for the domain")
+	case "?":
+		check.warn("?all gives mail from other servers a neutral result")
+	case "":
+		check.warn("There is no all mechanism, so mail from other servers gets a neutral result")
+	}
+
+	policy := all + "all"
+	if all == "" {
+		policy = "no all"
+	}
+
+	if e.lookups > spfLookupLimit {
+		check.fail("Evaluating the record takes more than %d DNS lookups, which is a permanent error", spfLookupLimit)
+
+		check.Summary = fmt.Sprintf("%s, over %d DNS lookups", policy, spfLookupLimit)
+
+		return check
+	}
+
+	check.Summary = fmt.Sprintf("%s, %d of %d DNS lookups", policy, e.lookups, spfLookupLimit)
+
+	return check
+}
+
+// dmarcPolicy holds the parts of a DMARC record that BIMI depends on.
+type dmarcPolicy struct {
+	policy          string
+	subdomainPolicy string
+	percent         int
+}
+
+// enforced reports whether the policy applies to all mail, as BIMI requires.
+func (p dmarcPolicy) enforced() bool {
+	enforcing := func(policy string) bool {
+		return policy == "quarantine" || policy == "reject"
+	}
+
+	return enforcing(p.policy) && enforcing(p.subdomainPolicy) && p.percent == 100
+}
+
+// checkReportURIs validates the rua and ruf addresses of a DMARC record.
+// Reports sent to another domain must be authorised by that domain, as
+// described in RFC 7489.
+func checkReportURIs(ctx context.Context, resolver *net.Resolver, check *MailCheck, domain, tag, value string) {
+	for _, uri := range splitList(value) {
+		address, _, _ := strings.Cut(uri, "!")
+
+		if len(address) < 7 || !strings.EqualFold(address[:7], "mailto:") {
+			check.fail("%s: %s is not a mailto: address", tag, uri)
+
+			continue
+		}
+
+		_, host, found := strings.Cut(address[7:], "@")
+		if !found || host == "" {
+			check.fail("%s: %s is not a valid address", tag, uri)
+
+			continue
+		}
+
+		if isSubdomain(host, domain) || isSubdomain(domain, host) {
+			continue
+		}
+
+		name := domain + "._report._dmarc." + host
+
+		records, err := findRecords(ctx, resolver, name, "v=DMARC1")
+		if err == nil && len(records) == 0 {
+			check.warn("%s: %s has not authorised reports for %s (no record at %s)", tag, host, domain, name)
+		}
+	}
+}
+
+func checkDMARC(ctx context.Context, resolver *net.Resolver, domain string) (MailCheck, dmarcPolicy) {
+	check := newMailCheck("DMARC")
+
+	policy := dmarcPolicy{percent: 100}
+
+	name := "_dmarc." + domain
+
+	records, err := findRecords(ctx, resolver, name, "v=DMARC1")
+	if !check.singleRecord(records, err, name, true) {
+		return check, policy
+	}
+
+	tags, err := parseTags(records[0])
+	if err != nil {
+		check.Summary = "Invalid record"
+
+		check.fail("Invalid record: %v", err)
+
+		return check, policy
+	}
+
+	policy.policy = strings.ToLower(tags["p"])
+
+	switch policy.policy {
+	case "none":
+		check.warn("p=none only monitors mail, and does not stop spoofing")
+	case "quarantine", "reject":
+	case "":
+		check.fail("No policy (p) is given")
+	default:
+		check.fail("Unknown policy p=%s", tags["p"])
+	}
+
+	policy.subdomainPolicy = policy.policy
+
+	if sp, ok := tags["sp"]; ok {
+		policy.subdomainPolicy = strings.ToLower(sp)
+
+		switch policy.subdomainPolicy {
+		case "none":
+			if policy.policy != "none" {
+				check.warn("sp=none leaves subdomains unprotected")
+			}
+		case "quarantine", "reject":
+		default:
+			check.fail("Unknown subdomain policy sp=%s", sp)
+		}
+	}
+
+	if pct, ok := tags["pct"]; ok {
+		n, err := strconv.Atoi(pct)
+
+		switch {
+		case err != nil || n < 0 || n > 100:
+			check.fail("pct=%s is not a percentage", pct)
+		case n < 100:
+			policy.percent = n
+
+			check.warn("pct=%d applies the policy to only some mail", n)
+		}
+	}
+
+	for _, tag := range []string{"adkim", "aspf"} {
+		if mode, ok := tags[tag]; ok && mode != "r" && mode != "s" {
+			check.fail("%s=%s must be either r or s", tag, mode)
+		}
+	}
+
+	if tags["rua"] == "" {
+		check.warn("No aggregate report address (rua) is given, so failures go unnoticed")
+	} else {
+		checkReportURIs(ctx, resolver, &check, domain, "rua", tags["rua"])
+	}
+
+	if tags["ruf"] != "" {
+		checkReportURIs(ctx, resolver, &check, domain, "ruf", tags["ruf"])
+	}
+
+	check.Summary = "p=" + tags["p"]
+
+	if policy.percent < 100 {
+		check.Summary += fmt.Sprintf(", pct=%d", policy.percent)
+	}
+
+	return check, policy
+}
+
+// newPolicyClient returns the client used to fetch MTA-STS policies. As the
+// policy host is chosen by whoever asks for the check, only globally
+// reachable addresses are connected to. Redirects are not followed, as
+// RFC 8461 requires.
+func newPolicyClient(resolver *net.Resolver) *http.Client {
+	dialer := &net.Dialer{
+		Resolver: resolver,
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !globallyReachable(addrPort.Addr()) {
+				return ErrUnreachableAddress
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: mtaSTSTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: mtaSTSTimeout,
+	}
+}
+
+func fetchMTASTSPolicy(ctx context.Context, client *http.Client, domain string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://mta-sts."+domain+"/.well-known/mta-sts.txt", nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMTASTSPolicySize+1))
+	if err != nil {
+		return "", err
+	}
+
+	if len(body) > maxMTASTSPolicySize {
+		return "", fmt.Errorf("policy is larger than %d bytes", maxMTASTSPolicySize)
+	}
+
+	return string(body), nil
+}
+
+// mxMatches reports whether an MX host is covered by a pattern from an
+// MTA-STS policy. A leading wildcard matches exactly one label.
+func mxMatches(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		_, rest, found := strings.Cut(host, ".")
+
+		return found && rest == suffix
+	}
+
+	return host == pattern
+}
+
+func checkMTASTS(ctx context.Context, resolver *net.Resolver, client *http.Client, domain string, hosts []string) MailCheck {
+	check := newMailCheck("MTA-STS")
+
+	name := "_mta-sts." + domain
+
+	records, err := findRecords(ctx, resolver, name, "v=STSv1")
+	if !check.singleRecord(records, err, name, false) {
+		return check
+	}
+
+	tags, err := parseTags(records[0])
+	if err != nil {
+		check.fail("Invalid record: %v", err)
+	} else if !mtaSTSID.MatchString(tags["id"]) {
+		check.fail("id=%s must be 1 to 32 letters and digits", tags["id"])
+	}
+
+	policy, err := fetchMTASTSPolicy(ctx, client, domain)
+	if err != nil {
+		check.Summary = "Policy unavailable"
+
+		check.fail("Policy could not be fetched from mta-sts.%s: %v", domain, err)
+
+		return check
+	}
+
+	fields := make(map[string]string)
+
+	var patterns []string
+
+	for line := range strings.SplitSeq(policy, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		check.Records = append(check.Records, line)
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			check.fail("Invalid policy line %q", line)
+
+			continue
+		}
+
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		if key == "mx" {
+			patterns = append(patterns, value)
+		} else {
+			fields[key] = value
+		}
+	}
+
+	if fields["version"] != "STSv1" {
+		check.fail("Policy version must be STSv1")
+	}
+
+	mode := fields["mode"]
+
+	switch mode {
+	case "enforce":
+	case "testing":
+		check.warn("Policy is in testing mode, so failures are only reported")
+	case "none":
+		check.warn("Policy mode is none, so it is not applied")
+	default:
+		check.fail("Unknown policy mode %q", mode)
+	}
+
+	maxAge, err := strconv.Atoi(fields["max_age"])
+
+	switch {
+	case err != nil || maxAge < 0 || maxAge > mtaSTSMaxAge:
+		check.fail("max_age must be between 0 and %d seconds", mtaSTSMaxAge)
+	case maxAge < mtaSTSShortAge:
+		check.warn("max_age of %d seconds is short, so senders soon forget the policy", maxAge)
+	}
+
+	if mode == "enforce" || mode == "testing" {
+		if len(patterns) == 0 {
+			check.fail("Policy lists no mx patterns")
+		}
+
+		for _, host := range hosts {
+			if slices.ContainsFunc(patterns, func(pattern string) bool { return mxMatches(pattern, host) }) {
+				continue
+			}
+
+			if mode == "enforce" {
+				check.fail("%s is not covered by the policy, so mail to it will not be delivered", host)
+			} else {
+				check.warn("%s is not covered by the policy", host)
+			}
+		}
+	}
+
+	check.Summary = "mode: " + mode
+
+	return check
+}
+
+func checkTLSRPT(ctx context.Context, resolver *net.Resolver, domain string) MailCheck {
+	check := newMailCheck("TLS-RPT")
+
+	name := "_smtp._tls." + domain
+
+	records, err := findRecords(ctx, resolver, name, "v=TLSRPTv1")
+	if !check.singleRecord(records, err, name, false) {
+		return check
+	}
+
+	tags, err := parseTags(records[0])
+	if err != nil {
+		check.Summary = "Invalid record"
+
+		check.fail("Invalid record: %v", err)
+
+		return check
+	}
+
+	uris := splitList(tags["rua"])
+
+	if len(uris) == 0 {
+		check.fail("No report address (rua) is given")
+	}
+
+	for _, uri := range uris {
+		lower := strings.ToLower(uri)
+
+		if !strings.HasPrefix(lower, "mailto:") && !strings.HasPrefix(lower, "https:") {
+			check.fail("%s is neither a mailto: nor an https: address", uri)
+		}
+	}
+
+	check.Summary = "Reports to " + strings.Join(uris, ", ")
+
+	return check
+}
+
+// validHTTPS reports whether s is an absolute https:// URL.
+func validHTTPS(s string) bool {
+	u, err := url.Parse(s)
+
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
+
+func checkBIMI(ctx context.Context, resolver *net.Resolver, domain string, policy dmarcPolicy) MailCheck {
+	check := newMailCheck("BIMI")
+
+	name := "default._bimi." + domain
+
+	records, err := findRecords(ctx, resolver, name, "v=BIMI1")
+	if !check.singleRecord(records, err, name, false) {
+		return check
+	}
+
+	tags, err := parseTags(records[0])
+	if err != nil {
+		check.Summary = "Invalid record"
+
+		check.fail("Invalid record: %v", err)
+
+		return check
+	}
+
+	logo, evidence := tags["l"], tags["a"]
+
+	// A record with neither tag declines to publish a logo.
+	if logo == "" && evidence == "" {
+		check.Summary = "Declined"
+
+		return check
+	}
+
+	switch {
+	case !validHTTPS(logo):
+		check.fail("Logo location l=%s is not an https:// URL", logo)
+	case !strings.HasSuffix(strings.ToLower(logo), ".svg"):
+		check.warn("Logo should be an SVG file")
+	}
+
+	switch {
+	case evidence == "":
+		check.warn("No mark certificate (a) is given, which most mailbox providers require")
+	case !validHTTPS(evidence):
+		check.fail("Mark certificate location a=%s is not an https:// URL", evidence)
+	}
+
+	if !policy.enforced() {
+		check.warn("Logos are only shown for domains whose DMARC policy quarantines or rejects all mail")
+	}
+
+	check.Summary = "Logo at " + logo
+
+	return check
+}
+
+// rsaKeyBits returns the size of an RSA key published in a DKIM record,
+// which is usually a SubjectPublicKeyInfo, but sometimes a bare PKCS #1 key.
+func rsaKeyBits(der []byte) (int, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err == nil {
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, errors.New("key is not an RSA key")
+		}
+
+		return rsaKey.N.BitLen(), nil
+	}
+
+	rsaKey, err := x509.ParsePKCS1PublicKey(der)
+	if err != nil {
+		return 0, errors.New("key could not be parsed")
+	}
+
+	return rsaKey.N.BitLen(), nil
+}
+
+func checkDKIM(ctx context.Context, resolver *net.Resolver, domain, selector string) MailCheck {
+	check := newMailCheck("DKIM (" + selector + ")")
+
+	name := selector + "._domainkey." + domain
+
+	records, err := resolver.LookupTXT(ctx, name)
+
+	var dnsErr *net.DNSError
+
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		check.Summary = "Not published"
+
+		check.fail("No key is published at %s", name)
+
+		return check
+	}
+
+	if !check.singleRecord(records, err, name, true) {
+		return check
+	}
+
+	tags, err := parseTags(records[0])
+	if err != nil {
+		check.Summary = "Invalid record"
+
+		check.fail("Invalid record: %v", err)
+
+		return check
+	}
+
+	if version, ok := tags["v"]; ok && version != "DKIM1" {
+		check.fail("Unknown version v=%s", version)
+	}
+
+	if slices.Contains(strings.Split(tags["t"], ":"), "y") {
+		check.warn("Key is in testing mode (t=y), so verifiers may ignore its signatures")
+	}
+
+	if hashes, ok := tags["h"]; ok && !slices.Contains(strings.Split(hashes, ":"), "sha256") {
+		check.warn("Key only allows signatures using h=%s, and not sha256", hashes)
+	}
+
+	data, ok := tags["p"]
+
+	switch {
+	case !ok:
+		check.Summary = "No key"
+
+		check.fail("No public key (p) is given")
+
+		return check
+	case data == "":
+		check.Summary = "Revoked"
+
+		check.fail("Key has been revoked")
+
+		return check
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+	if err != nil {
+		check.Summary = "Invalid key"
+
+		check.fail("Public key is not valid base64")
+
+		return check
+	}
+
+	keyType := strings.ToLower(tags["k"])
+
+	switch keyType {
+	case "", "rsa":
+		bits, err := rsaKeyBits(key)
+		if err != nil {
+			check.Summary = "Invalid key"
+
+			check.fail("Public key is invalid: %v", err)
+
+			return check
+		}
+
+		switch {
+		case bits < 1024:
+			check.fail("%d-bit RSA keys are too weak to be trusted", bits)
+		case bits < 2048:
+			check.warn("%d-bit RSA keys are weak, so use at least 2048 bits", bits)
+		}
+
+		check.Summary = fmt.Sprintf("RSA, %d bits", bits)
+	case "ed25519":
+		if len(key) != 32 {
+			check.fail("Ed25519 keys must be 32 bytes long")
+		}
+
+		check.Summary = "Ed25519"
+	default:
+		check.Summary = "Unknown key type"
+
+		check.fail("Unknown key type k=%s", keyType)
+	}
+
+	return check
+}
+
+type mailChecker struct {
+	backend  ASNBackend
+	resolver *net.Resolver
+	client   *http.Client
+}
+
+func newMailChecker(backend ASNBackend, resolver *net.Resolver) *mailChecker {
+	return &mailChecker{
+		backend:  backend,
+		resolver: resolver,
+		client:   newPolicyClient(resolver),
+	}
+}
+
+// check runs every check for a domain at once. MTA-STS and BIMI are checked
+// once the MX and DMARC records they depend on are known.
+func (m *mailChecker) check(ctx context.Context, domain string, selectors []string) MailCheckResult {
+	result := MailCheckResult{Domain: domain, MX: []MailExchanger{}}
+
+	var mx, spf, dmarc, mtaSTS, tlsRPT, bimi MailCheck
+
+	dkim := make([]MailCheck, len(selectors))
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() {
+		targets, infos, err := lookupMX(ctx, domain, m.backend, m.resolver)
+
+		result.targets, result.infos = targets, infos
+
+		mx = checkMX(targets, err)
+
+		var hosts []string
+
+		for _, target := range targets {
+			if target.host != "." {
+				hosts = append(hosts, strings.TrimSuffix(target.host, "."))
+			}
+		}
+
+		mtaSTS = checkMTASTS(ctx, m.resolver, m.client, domain, hosts)
+	})
+
+	wg.Go(func() {
+		spf = checkSPF(ctx, m.resolver, domain)
+	})
+
+	wg.Go(func() {
+		var policy dmarcPolicy
+
+		dmarc, policy = checkDMARC(ctx, m.resolver, domain)
+
+		bimi = checkBIMI(ctx, m.resolver, domain, policy)
+	})
+
+	wg.Go(func() {
+		tlsRPT = checkTLSRPT(ctx, m.resolver, domain)
+	})
+
+	for i, selector := range selectors {
+		wg.Go(func() {
+			dkim[i] = checkDKIM(ctx, m.resolver, domain, selector)
+		})
+	}
+
+	wg.Wait()
+
+	for _, target := range result.targets {
+		exchanger := MailExchanger{
+			Host:       strings.TrimSuffix(target.host, "."),
+			Preference: target.priority,
+			Addresses:  []string{},
+		}
+
+		for _, addr := range target.addrs {
+			exchanger.Addresses = append(exchanger.Addresses, addr.String())
+		}
+
+		if target.err != nil && target.host != "." {
+			exchanger.Error = target.err.Error()
+		}
+
+		result.MX = append(result.MX, exchanger)
+	}
+
+	result.Checks = append([]MailCheck{mx, spf, dmarc}, dkim...)
+	result.Checks = append(result.Checks, mtaSTS, tlsRPT, bimi)
+
+	result.Status = worstStatus(result.Checks)
+
+	return result
+}
+
+// parseSelectors reads the DKIM selectors to check, given as one or more
+// comma-separated dkim parameters.
+func parseSelectors(r *http.Request) ([]string, error) {
+	var selectors []string
+
+	for _, value := range r.URL.Query()["dkim"] {
+		for _, selector := range splitList(value) {
+			if !validName(selector) || strings.HasSuffix(selector, ".") {
+				return nil, fmt.Errorf("%w %q", ErrInvalidSelector, selector)
+			}
+
+			if !slices.Contains(selectors, selector) {
+				selectors = append(selectors, selector)
+			}
+		}
+	}
+
+	if len(selectors) > maxDKIMSelectors {
+		return nil, fmt.Errorf("%w: no more than %d may be checked", ErrInvalidSelector, maxDKIMSelectors)
+	}
+
+	return selectors, nil
+}
+
+// Text renders what was found for each item, followed by a summary of them
+// all.
+func (result MailCheckResult) Text(pr *message.Printer) string {
+	var output strings.Builder
+
+	output.WriteString(formatFields(pr, [][2]string{
+		{"Domain", result.Domain},
+		{"Status", result.Status},
+	}))
+
+	for _, check := range result.Checks {
+		output.WriteString("\n; " + check.Name + "\n")
+
+		for _, record := range check.Records {
+			output.WriteString(record + "\n")
+		}
+
+		for _, detail := range check.Details {
+			output.WriteString(detail + "\n")
+		}
+
+		if check.Name == "MX" && len(result.targets) > 0 && result.targets[0].host != "." {
+			writeMX(&output, result.targets, result.infos)
+		}
+	}
+
+	output.WriteString("\n; " + pr.Sprintf("Summary") + "\n")
+
+	tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+	for _, check := range result.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, check.Status, check.Summary)
+	}
+
+	tw.Flush()
+
+	return output.String()
+}
+
+func serveMailCheck(checker *mailChecker, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		domain := strings.ToLower(strings.TrimSuffix(p.ByName("domain"), "."))
+
+		if domain == "" || !validName(domain) {
+			serveDNSQueryError(w, r, pr, ErrInvalidName, errorChannel)
+
+			return
+		}
+
+		selectors, err := parseSelectors(r)
+		if err != nil {
+			serveDNSQueryError(w, r, pr, err, errorChannel)
+
+			return
+		}
+
+		ctx, answered := withAnsweredBy(r.Context())
+
+		ctx, cancel := context.WithTimeout(ctx, mailCheckTimeout)
+		defer cancel()
+
+		result := checker.check(ctx, domain, selectors)
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			_, err = w.Write([]byte(result.Text(pr)))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s%s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI,
+				answered)
+		}
+	}
+}
@@ -16,37 +16,54 @@ import (
 )
 
 const (
-	ReleaseVersion string = "1.25.1"
+	ReleaseVersion string = "1.50.0"
 )
 
 var (
-	all          bool
-	bind         string
-	exitOnError  bool
-	maxDiceRolls int
-	maxDiceSides int
-	ouiFile      string
-	dns          bool
-	dnsResolver  string
-	hashing      bool
-	httpStatus   bool
-	ip           bool
-	mac          bool
-	qr           bool
-	qrSize       int
-	roll         bool
-	subnet       bool
-	timezones    bool
-	tlsCert      string
-	tlsKey       string
-	port         uint16
-	profile      bool
-	whoami       bool
-	verbose      bool
-	version      bool
+	adminToken    string
+	all           bool
+	asnLookups    bool
+	asnDB         string
+	asnFallback   bool
+	batch         bool
+	batchWorkers  int
+	bind          string
+	exitOnError   bool
+	maxBatchItems int
+	maxDiceRolls  int
+	maxDiceSides  int
+	maxStreams    int
+	ouiFile       string
+	dns           bool
+	dnsResolvers  []string
+	dnsRootHints  []string
+	dnsStrategy   string
+	dnsAnchors    string
+	dnsCompare    []string
+	dohRate       int
+	hashing       bool
+	httpStatus    bool
+	ip            bool
+	mac           bool
+	qr            bool
+	qrSize        int
+	roll          bool
+	subnet        bool
+	timezones     bool
+	trustProxy    bool
+	tlsCert       string
+	tlsKey        string
+	port          uint16
+	profile       bool
+	whoami        bool
+	verbose       bool
+	version       bool
 
 	requiredArgs = []string{
+		"admin-token",
 		"all",
+		"asn",
+		"batch",
 		"dns",
 		"hash",
 		"http-status",
@@ -54,6 +71,7 @@ var (
 		"mac",
 		"qr",
 		"roll",
+		"subnet",
 		"time",
 		"whoami",
 	}
@@ -72,10 +90,18 @@ func main() {
 				return errors.New("TLS certificate and keyfile must both be specified to enable HTTPS")
 			case qrSize < 256 || qrSize > 2048:
 				return ErrInvalidQRSize
+			case batchWorkers < 1:
+				return ErrInvalidBatchWorkers
+			case maxBatchItems < 1:
+				return ErrInvalidMaxBatchItems
 			case maxDiceRolls < 1:
 				return ErrInvalidMaxDiceCount
 			case maxDiceSides < 1:
 				return ErrInvalidMaxDiceSides
+			case maxStreams < 1:
+				return ErrInvalidMaxStreams
+			case dnsStrategy != strategyFailover && dnsStrategy != strategyFastest:
+				return ErrInvalidDNSStrategy
 			}
 
 			return nil
@@ -85,17 +111,30 @@ func main() {
 		},
 	}
 
+	cmd.Flags().StringVar(&adminToken, "admin-token", "", "bearer token required to access the admin API (disabled if empty)")
 	cmd.Flags().BoolVar(&all, "all", false, "enable all features")
+	cmd.Flags().BoolVar(&asnLookups, "asn", false, "enable ASN lookups")
+	cmd.Flags().StringVar(&asnDB, "asn-db", "", "path to an iptoasn TSV or MaxMind ASN database (queries Team Cymru if empty)")
+	cmd.Flags().BoolVar(&asnFallback, "asn-fallback", true, "query Team Cymru for addresses missing from the ASN database")
+	cmd.Flags().BoolVar(&batch, "batch", false, "enable batch requests")
+	cmd.Flags().IntVar(&batchWorkers, "batch-workers", 8, "number of batch items to process concurrently")
 	cmd.Flags().StringVarP(&bind, "bind", "b", "0.0.0.0", "address to bind to")
 	cmd.Flags().BoolVar(&dns, "dns", false, "enable DNS lookup")
-	cmd.Flags().StringVar(&dnsResolver, "dns-resolver", "", "custom DNS server IP and port to query (e.g. 8.8.8.8:53)")
+	cmd.Flags().StringSliceVar(&dnsCompare, "dns-compare", []string{"cloudflare=1.1.1.1", "google=8.8.8.8", "quad9=9.9.9.9"}, "DNS servers to compare answers from, as name=server (e.g. internal=10.0.0.53)")
+	cmd.Flags().StringSliceVar(&dnsResolvers, "dns-resolver", []string{}, "DNS servers to query, as ip:port, tls://host:port or https://host/path (e.g. tls://1.1.1.1)")
+	cmd.Flags().StringSliceVar(&dnsRootHints, "dns-root-hints", defaultRootHints, "root server addresses to start DNS traces from, as ip or ip:port")
+	cmd.Flags().StringVar(&dnsStrategy, "dns-strategy", strategyFailover, "how to choose between DNS servers (failover, fastest)")
+	cmd.Flags().StringVar(&dnsAnchors, "dns-trust-anchors", "", "path to DS or DNSKEY records to trust for DNSSEC validation (uses the root zone KSKs if empty)")
+	cmd.Flags().IntVar(&dohRate, "doh-rate", 20, "maximum DNS-over-HTTPS queries per second from each client")
 	cmd.Flags().BoolVar(&exitOnError, "exit-on-error", false, "shut down webserver on error, instead of just printing the error")
 	cmd.Flags().BoolVar(&hashing, "hash", false, "enable hashing")
 	cmd.Flags().BoolVar(&httpStatus, "http-status", false, "enable HTTP response status codes")
 	cmd.Flags().BoolVar(&ip, "ip", false, "enable IP lookups")
 	cmd.Flags().BoolVar(&mac, "mac", false, "enable MAC lookups")
+	cmd.Flags().IntVar(&maxBatchItems, "max-batch-items", 50, "maximum number of items per batch request")
 	cmd.Flags().IntVar(&maxDiceRolls, "max-dice-rolls", 1024, "maximum number of dice per roll")
 	cmd.Flags().IntVar(&maxDiceSides, "max-dice-sides", 1024, "maximum number of sides per die")
+	cmd.Flags().IntVar(&maxStreams, "max-streams", 64, "maximum number of concurrent event streams")
 	cmd.Flags().StringVar(&ouiFile, "oui-file", "", "path to Wireshark manufacturer database file")
 	cmd.Flags().Uint16VarP(&port, "port", "p", 8080, "port to listen on")
 	cmd.Flags().BoolVar(&profile, "profile", false, "register net/http/pprof handlers")
@@ -106,6 +145,7 @@ func main() {
 	cmd.Flags().BoolVar(&timezones, "time", false, "enable time lookup")
 	cmd.Flags().StringVar(&tlsCert, "tls-cert", "", "path to TLS certificate")
 	cmd.Flags().StringVar(&tlsKey, "tls-key", "", "path to TLS keyfile")
+	cmd.Flags().BoolVar(&trustProxy, "trust-proxy-headers", false, "rate limit clients by the Cf-Connecting-Ip and X-Real-Ip headers, when behind a proxy that sets them")
 	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "log tool usage to stdout")
 	cmd.Flags().BoolVarP(&version, "version", "V", false, "display version and exit")
 	cmd.Flags().BoolVar(&whoami, "whoami", false, "enable whoami endpoint")
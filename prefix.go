@@ -0,0 +1,615 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"math/bits"
+	"net/netip"
+	"slices"
+)
+
+var (
+	ErrInvalidPrefix = errors.New("not a valid prefix")
+	ErrInvalidRange  = errors.New("not a valid address range")
+)
+
+// uint128 holds an IPv4 or IPv6 address as an integer, so that address
+// arithmetic works the same way for both families.
+type uint128 struct {
+	hi uint64
+	lo uint64
+}
+
+func fromAddr(a netip.Addr) uint128 {
+	if a.Is4() {
+		b := a.As4()
+
+		return uint128{0, uint64(binary.BigEndian.Uint32(b[:]))}
+	}
+
+	b := a.As16()
+
+	return uint128{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])}
+}
+
+func fromBig(n *big.Int) uint128 {
+	var b [16]byte
+
+	n.FillBytes(b[:])
+
+	return uint128{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])}
+}
+
+// ones returns a value with the lowest n bits set.
+func ones(n int) uint128 {
+	switch {
+	case n <= 0:
+		return uint128{}
+	case n < 64:
+		return uint128{0, 1<<n - 1}
+	case n < 128:
+		return uint128{1<<(n-64) - 1, ^uint64(0)}
+	default:
+		return uint128{^uint64(0), ^uint64(0)}
+	}
+}
+
+func (u uint128) toAddr(is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+
+		binary.BigEndian.PutUint32(b[:], uint32(u.lo))
+
+		return netip.AddrFrom4(b)
+	}
+
+	var b [16]byte
+
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+
+	return netip.AddrFrom16(b)
+}
+
+func (u uint128) big() *big.Int {
+	var b [16]byte
+
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+
+	return new(big.Int).SetBytes(b[:])
+}
+
+func (u uint128) isZero() bool {
+	return u.hi == 0 && u.lo == 0
+}
+
+func (u uint128) cmp(v uint128) int {
+	switch {
+	case u.hi < v.hi:
+		return -1
+	case u.hi > v.hi:
+		return 1
+	case u.lo < v.lo:
+		return -1
+	case u.lo > v.lo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (u uint128) add(v uint128) uint128 {
+	lo, carry := bits.Add64(u.lo, v.lo, 0)
+	hi, _ := bits.Add64(u.hi, v.hi, carry)
+
+	return uint128{hi, lo}
+}
+
+func (u uint128) sub(v uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	hi, _ := bits.Sub64(u.hi, v.hi, borrow)
+
+	return uint128{hi, lo}
+}
+
+func (u uint128) and(v uint128) uint128 {
+	return uint128{u.hi & v.hi, u.lo & v.lo}
+}
+
+func (u uint128) or(v uint128) uint128 {
+	return uint128{u.hi | v.hi, u.lo | v.lo}
+}
+
+func (u uint128) xor(v uint128) uint128 {
+	return uint128{u.hi ^ v.hi, u.lo ^ v.lo}
+}
+
+func (u uint128) not() uint128 {
+	return uint128{^u.hi, ^u.lo}
+}
+
+func (u uint128) lsh(n int) uint128 {
+	switch {
+	case n <= 0:
+		return u
+	case n >= 128:
+		return uint128{}
+	case n >= 64:
+		return uint128{u.lo << (n - 64), 0}
+	default:
+		return uint128{u.hi<<n | u.lo>>(64-n), u.lo << n}
+	}
+}
+
+// trailingZeros returns the number of trailing zero bits, capped at width.
+func (u uint128) trailingZeros(width int) int {
+	var n int
+
+	if u.lo != 0 {
+		n = bits.TrailingZeros64(u.lo)
+	} else {
+		n = 64 + bits.TrailingZeros64(u.hi)
+	}
+
+	return min(n, width)
+}
+
+// bitLength returns the number of bits needed to represent u.
+func (u uint128) bitLength() int {
+	if u.hi != 0 {
+		return 64 + bits.Len64(u.hi)
+	}
+
+	return bits.Len64(u.lo)
+}
+
+// prefixBounds returns the first and last addresses of a prefix.
+func prefixBounds(p netip.Prefix) (uint128, uint128) {
+	first := fromAddr(p.Masked().Addr())
+
+	return first, first.or(ones(p.Addr().BitLen() - p.Bits()))
+}
+
+// prefixSize returns the number of addresses in a prefix.
+func prefixSize(p netip.Prefix) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+}
+
+// parsePrefix accepts CIDR notation, returning the masked prefix.
+func parsePrefix(s string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, ErrInvalidPrefix
+	}
+
+	return p.Masked(), nil
+}
+
+// addrRange is an inclusive range of addresses from a single family.
+type addrRange struct {
+	first uint128
+	last  uint128
+}
+
+// PrefixSet is a set of addresses, stored for each family as a sorted list of
+// non-overlapping, non-adjacent ranges.
+type PrefixSet struct {
+	v4 []addrRange
+	v6 []addrRange
+
+	sorted bool
+}
+
+func (s *PrefixSet) family(is4 bool) *[]addrRange {
+	if is4 {
+		return &s.v4
+	}
+
+	return &s.v6
+}
+
+func (s *PrefixSet) add(is4 bool, r addrRange) {
+	ranges := s.family(is4)
+
+	*ranges = append(*ranges, r)
+
+	s.sorted = false
+}
+
+// AddRange adds every address from first to last, inclusive.
+func (s *PrefixSet) AddRange(first, last netip.Addr) error {
+	if !first.IsValid() || !last.IsValid() || first.Is4() != last.Is4() || last.Less(first) {
+		return ErrInvalidRange
+	}
+
+	s.add(first.Is4(), addrRange{fromAddr(first), fromAddr(last)})
+
+	return nil
+}
+
+func (s *PrefixSet) AddPrefix(p netip.Prefix) {
+	first, last := prefixBounds(p)
+
+	s.add(p.Addr().Is4(), addrRange{first, last})
+}
+
+func (s *PrefixSet) AddAddr(a netip.Addr) {
+	s.AddPrefix(netip.PrefixFrom(a, a.BitLen()))
+}
+
+// ranges returns the sorted, merged ranges for one address family.
+func (s *PrefixSet) ranges(is4 bool) []addrRange {
+	s.normalize()
+
+	return *s.family(is4)
+}
+
+// normalize sorts each family's ranges, merging any that overlap or touch.
+func (s *PrefixSet) normalize() {
+	if s.sorted {
+		return
+	}
+
+	for _, ranges := range []*[]addrRange{&s.v4, &s.v6} {
+		slices.SortFunc(*ranges, func(a, b addrRange) int {
+			return a.first.cmp(b.first)
+		})
+
+		merged := (*ranges)[:0]
+
+		for _, r := range *ranges {
+			if n := len(merged); n > 0 {
+				previous := &merged[n-1]
+
+				// The second comparison guards against wrapping past the
+				// final address of the family.
+				if next := previous.last.add(uint128{0, 1}); r.first.cmp(next) <= 0 || next.isZero() {
+					if r.last.cmp(previous.last) > 0 {
+						previous.last = r.last
+					}
+
+					continue
+				}
+			}
+
+			merged = append(merged, r)
+		}
+
+		*ranges = merged
+	}
+
+	s.sorted = true
+}
+
+// Size returns the number of addresses in the set.
+func (s *PrefixSet) Size() *big.Int {
+	s.normalize()
+
+	size := new(big.Int)
+
+	for _, r := range slices.Concat(s.v4, s.v6) {
+		size.Add(size, r.last.sub(r.first).big())
+		size.Add(size, big.NewInt(1))
+	}
+
+	return size
+}
+
+// Prefixes returns the smallest list of prefixes covering exactly the
+// addresses in the set, with IPv4 prefixes ahead of IPv6 prefixes.
+func (s *PrefixSet) Prefixes() []netip.Prefix {
+	s.normalize()
+
+	var prefixes []netip.Prefix
+
+	for _, r := range s.v4 {
+		prefixes = append(prefixes, rangePrefixes(r, true)...)
+	}
+
+	for _, r := range s.v6 {
+		prefixes = append(prefixes, rangePrefixes(r, false)...)
+	}
+
+	return prefixes
+}
+
+// rangePrefixes splits a range into the largest aligned prefixes it contains.
+func rangePrefixes(r addrRange, is4 bool) []netip.Prefix {
+	width := 128
+	if is4 {
+		width = 32
+	}
+
+	var prefixes []netip.Prefix
+
+	first := r.first
+
+	for {
+		// The largest block that both starts at first and fits before last.
+		hostBits := width
+
+		if span := r.last.sub(first).add(uint128{0, 1}); !span.isZero() {
+			hostBits = min(span.bitLength()-1, width)
+		}
+
+		hostBits = min(hostBits, first.trailingZeros(width))
+
+		prefixes = append(prefixes, netip.PrefixFrom(first.toAddr(is4), width-hostBits))
+
+		end := first.add(ones(hostBits))
+		if end.cmp(r.last) >= 0 {
+			return prefixes
+		}
+
+		first = end.add(uint128{0, 1})
+	}
+}
+
+// summaryNode is one prefix in the working list used by Summarize.
+type summaryNode struct {
+	first, last uint128
+	is4         bool
+	removed     bool
+	prev, next  *summaryNode
+}
+
+// summaryMerge is a candidate replacement of two neighbouring prefixes, along
+// with anything else in between them, by their smallest common supernet.
+type summaryMerge struct {
+	left, right *summaryNode
+	first, last uint128
+	cost        uint128
+}
+
+type summaryQueue []summaryMerge
+
+func (q summaryQueue) Len() int { return len(q) }
+
+func (q summaryQueue) Less(i, j int) bool {
+	if c := q[i].cost.cmp(q[j].cost); c != 0 {
+		return c < 0
+	}
+
+	return q[i].first.cmp(q[j].first) < 0
+}
+
+func (q summaryQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *summaryQueue) Push(x any) { *q = append(*q, x.(summaryMerge)) }
+
+func (q *summaryQueue) Pop() any {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+
+	return x
+}
+
+// mergeCandidate returns the supernet covering left and right, and the number
+// of addresses outside the existing prefixes that it would add.
+func mergeCandidate(left, right *summaryNode) summaryMerge {
+	width := 128
+	if left.is4 {
+		width = 32
+	}
+
+	hostBits := min(left.first.xor(right.last).bitLength(), width)
+
+	first := left.first.and(ones(hostBits).not())
+	last := first.or(ones(hostBits))
+
+	// The merge swallows any other prefixes that fall inside the supernet.
+	// Every count here is stored as a size minus one so that it fits in 128
+	// bits; the wraparound arithmetic still yields the correct difference.
+	cost := last.sub(first)
+
+	for n := left; n != nil && n.first.cmp(first) >= 0; n = n.prev {
+		cost = cost.sub(n.last.sub(n.first)).sub(uint128{0, 1})
+	}
+
+	for n := right; n != nil && n.last.cmp(last) <= 0; n = n.next {
+		cost = cost.sub(n.last.sub(n.first)).sub(uint128{0, 1})
+	}
+
+	return summaryMerge{left, right, first, last, cost.add(uint128{0, 1})}
+}
+
+// Summarize approximates the set with at most maxPrefixes prefixes, by
+// repeatedly merging the neighbouring pair whose common supernet adds the
+// fewest addresses. It returns the prefixes along with the number of addresses
+// they cover beyond those in the set. Each address family needs at least one
+// prefix, so a set containing both may end up with two prefixes even when
+// maxPrefixes is 1.
+func (s *PrefixSet) Summarize(maxPrefixes int) ([]netip.Prefix, *big.Int) {
+	var (
+		queue summaryQueue
+		count int
+	)
+
+	s.normalize()
+
+	var heads []*summaryNode
+
+	for _, is4 := range []bool{true, false} {
+		var head, tail *summaryNode
+
+		for _, r := range *s.family(is4) {
+			for _, p := range rangePrefixes(r, is4) {
+				first, last := prefixBounds(p)
+
+				n := &summaryNode{first: first, last: last, is4: is4, prev: tail}
+
+				if tail == nil {
+					head = n
+				} else {
+					tail.next = n
+
+					queue = append(queue, mergeCandidate(tail, n))
+				}
+
+				tail = n
+
+				count++
+			}
+		}
+
+		heads = append(heads, head)
+	}
+
+	heap.Init(&queue)
+
+	for count > maxPrefixes && queue.Len() > 0 {
+		m := heap.Pop(&queue).(summaryMerge)
+
+		if m.left.removed || m.right.removed || m.left.next != m.right {
+			continue
+		}
+
+		// Earlier merges may have changed which prefixes this one would
+		// swallow, so its cost is checked again before it is applied.
+		if current := mergeCandidate(m.left, m.right); current.cost != m.cost {
+			heap.Push(&queue, current)
+
+			continue
+		}
+
+		prev, next := m.left.prev, m.right.next
+
+		for ; prev != nil && prev.first.cmp(m.first) >= 0; prev = prev.prev {
+			prev.removed = true
+
+			count--
+		}
+
+		for ; next != nil && next.last.cmp(m.last) <= 0; next = next.next {
+			next.removed = true
+
+			count--
+		}
+
+		m.left.removed, m.right.removed = true, true
+
+		count -= 2
+
+		n := &summaryNode{first: m.first, last: m.last, is4: m.left.is4, prev: prev, next: next}
+
+		count++
+
+		if prev == nil {
+			if n.is4 {
+				heads[0] = n
+			} else {
+				heads[1] = n
+			}
+		} else {
+			prev.next = n
+
+			heap.Push(&queue, mergeCandidate(prev, n))
+		}
+
+		if next != nil {
+			next.prev = n
+
+			heap.Push(&queue, mergeCandidate(n, next))
+		}
+	}
+
+	var summary PrefixSet
+
+	for _, head := range heads {
+		for n := head; n != nil; n = n.next {
+			summary.add(n.is4, addrRange{n.first, n.last})
+		}
+	}
+
+	prefixes := summary.Prefixes()
+
+	return prefixes, new(big.Int).Sub(summary.Size(), s.Size())
+}
+
+// Intersect returns a set holding the addresses found in both sets.
+func (s *PrefixSet) Intersect(t *PrefixSet) *PrefixSet {
+	s.normalize()
+	t.normalize()
+
+	var result PrefixSet
+
+	for _, is4 := range []bool{true, false} {
+		a, b := *s.family(is4), *t.family(is4)
+
+		for i, j := 0, 0; i < len(a) && j < len(b); {
+			first, last := a[i].first, a[i].last
+
+			if b[j].first.cmp(first) > 0 {
+				first = b[j].first
+			}
+
+			if b[j].last.cmp(last) < 0 {
+				last = b[j].last
+			}
+
+			if first.cmp(last) <= 0 {
+				result.add(is4, addrRange{first, last})
+			}
+
+			if a[i].last.cmp(b[j].last) < 0 {
+				i++
+			} else {
+				j++
+			}
+		}
+	}
+
+	return &result
+}
+
+// Subtract returns a set holding the addresses in s that are not in t.
+func (s *PrefixSet) Subtract(t *PrefixSet) *PrefixSet {
+	s.normalize()
+	t.normalize()
+
+	var result PrefixSet
+
+	for _, is4 := range []bool{true, false} {
+		a, b := *s.family(is4), *t.family(is4)
+
+		j := 0
+
+		for _, r := range a {
+			for j < len(b) && b[j].last.cmp(r.first) < 0 {
+				j++
+			}
+
+			first := r.first
+			covered := false
+
+			for k := j; k < len(b) && b[k].first.cmp(r.last) <= 0; k++ {
+				if b[k].first.cmp(first) > 0 {
+					result.add(is4, addrRange{first, b[k].first.sub(uint128{0, 1})})
+				}
+
+				if b[k].last.cmp(r.last) >= 0 {
+					covered = true
+
+					break
+				}
+
+				first = b[k].last.add(uint128{0, 1})
+			}
+
+			if !covered {
+				result.add(is4, addrRange{first, r.last})
+			}
+		}
+	}
+
+	return &result
+}
@@ -0,0 +1,132 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func newTestSet(t *testing.T, entries ...string) *PrefixSet {
+	t.Helper()
+
+	var set PrefixSet
+
+	for _, entry := range entries {
+		err := parseSetEntry(&set, entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &set
+}
+
+func prefixStrings(prefixes []netip.Prefix) []string {
+	var s []string
+
+	for _, p := range prefixes {
+		s = append(s, p.String())
+	}
+
+	return s
+}
+
+func TestPrefixSetSummarize(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		max     int
+		want    []string
+		extra   string
+	}{
+		{"adjacent", []string{"10.0.0.0/25", "10.0.0.128/25"}, 8, []string{"10.0.0.0/24"}, "0"},
+		{"overlapping", []string{"10.0.0.0/24", "10.0.0.64/26", "10.0.1.0/24"}, 8, []string{"10.0.0.0/23"}, "0"},
+		{"unaligned range", []string{"10.0.0.1-10.0.0.6"}, 8, []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}, "0"},
+		{"IPv4 /0", []string{"0.0.0.0/1", "128.0.0.0/1"}, 8, []string{"0.0.0.0/0"}, "0"},
+		{"IPv6 /0", []string{"::/0", "2001:db8::/32"}, 8, []string{"::/0"}, "0"},
+		{"/32", []string{"255.255.255.255/32", "255.255.255.254"}, 8, []string{"255.255.255.254/31"}, "0"},
+		{"/128", []string{"2001:db8::1/128", "2001:db8::"}, 8, []string{"2001:db8::/127"}, "0"},
+		{"IPv4-mapped", []string{"10.0.0.0/24", "::ffff:10.0.0.0/120"}, 8, []string{"10.0.0.0/24", "::ffff:10.0.0.0/120"}, "0"},
+		{"gap", []string{"10.0.0.0/24", "10.0.2.0/24"}, 1, []string{"10.0.0.0/22"}, "512"},
+		{"cheapest merge", []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.4.0/24"}, 2, []string{"10.0.0.0/23", "10.0.4.0/24"}, "128"},
+		{"single prefix", []string{"10.0.0.0/24", "10.0.1.0/25", "10.0.4.0/24"}, 1, []string{"10.0.0.0/21"}, "1408"},
+		{"IPv6 gap", []string{"2001:db8::/64", "2001:db8:0:2::/64"}, 1, []string{"2001:db8::/62"}, "36893488147419103232"},
+		{"both families", []string{"10.0.0.0/24", "2001:db8::/64"}, 1, []string{"10.0.0.0/24", "2001:db8::/64"}, "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefixes, extra := newTestSet(t, test.entries...).Summarize(test.max)
+
+			if got := prefixStrings(prefixes); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			if extra.String() != test.extra {
+				t.Errorf("extra addresses = %s, want %s", extra, test.extra)
+			}
+		})
+	}
+}
+
+func TestPrefixSetSubtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		remove  []string
+		want    []string
+	}{
+		{"middle", []string{"10.0.0.0/24"}, []string{"10.0.0.64/26"}, []string{"10.0.0.0/26", "10.0.0.128/25"}},
+		{"overlapping", []string{"10.0.0.0/23"}, []string{"10.0.0.128/25", "10.0.0.0/24", "10.0.1.0/26"}, []string{"10.0.1.64/26", "10.0.1.128/25"}},
+		{"disjoint", []string{"10.0.0.0/24"}, []string{"10.0.1.0/24"}, []string{"10.0.0.0/24"}},
+		{"everything", []string{"0.0.0.0/0"}, []string{"0.0.0.0/0"}, nil},
+		{"IPv6 /0", []string{"::/0"}, []string{"::/1"}, []string{"8000::/1"}},
+		{"last address", []string{"255.255.255.248/29"}, []string{"255.255.255.255"}, []string{"255.255.255.248/30", "255.255.255.252/31", "255.255.255.254/32"}},
+		{"/128", []string{"2001:db8::/127"}, []string{"2001:db8::1"}, []string{"2001:db8::/128"}},
+		{"IPv4-mapped", []string{"10.0.0.0/24"}, []string{"::ffff:10.0.0.0/120"}, []string{"10.0.0.0/24"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := newTestSet(t, test.entries...).Subtract(newTestSet(t, test.remove...))
+
+			if got := prefixStrings(result.Prefixes()); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPrefixSetIntersect(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  []string
+		want  []string
+		total string
+	}{
+		{"nested", []string{"10.0.0.0/8"}, []string{"10.1.0.0/16"}, []string{"10.1.0.0/16"}, "65536"},
+		{"partial", []string{"10.0.0.0-10.0.0.200"}, []string{"10.0.0.128/25"}, []string{"10.0.0.128/26", "10.0.0.192/29", "10.0.0.200/32"}, "73"},
+		{"IPv6 /0", []string{"::/0"}, []string{"2001:db8::/32", "0.0.0.0/0"}, []string{"2001:db8::/32"}, "79228162514264337593543950336"},
+		{"/32", []string{"0.0.0.0/0"}, []string{"255.255.255.255/32"}, []string{"255.255.255.255/32"}, "1"},
+		{"disjoint", []string{"10.0.0.0/24"}, []string{"10.0.1.0/24"}, nil, "0"},
+		{"IPv4-mapped", []string{"10.0.0.0/8"}, []string{"::ffff:10.0.0.1"}, nil, "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := newTestSet(t, test.a...).Intersect(newTestSet(t, test.b...))
+
+			if got := prefixStrings(result.Prefixes()); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			if size := result.Size().String(); size != test.total {
+				t.Errorf("size = %s, want %s", size, test.total)
+			}
+		})
+	}
+}
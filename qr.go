@@ -42,7 +42,7 @@ func serveQRCode(errorChannel chan<- Error) httprouter.Handle {
 
 				w.WriteHeader(http.StatusInternalServerError)
 
-				_, err := w.Write([]byte("Failed to encode string\n"))
+				_, err := w.Write([]byte(localizer(r).Sprintf("Failed to encode string") + "\n"))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -54,7 +54,7 @@ func serveQRCode(errorChannel chan<- Error) httprouter.Handle {
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 
-			_, err := w.Write([]byte("No string provided to encode\n"))
+			_, err := w.Write([]byte(localizer(r).Sprintf("No string provided to encode") + "\n"))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
@@ -68,7 +68,7 @@ func serveQRCode(errorChannel chan<- Error) httprouter.Handle {
 
 			w.WriteHeader(http.StatusInternalServerError)
 
-			_, err := w.Write([]byte("Failed to encode string\n"))
+			_, err := w.Write([]byte(localizer(r).Sprintf("Failed to encode string") + "\n"))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
@@ -93,13 +93,13 @@ func serveQRCode(errorChannel chan<- Error) httprouter.Handle {
 				return
 			}
 		} else {
-			png, err := qrCode.PNG(qrSize)
+			png, err := qrCode.PNG(int(qrSizeLimit.Load()))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
 				w.WriteHeader(http.StatusInternalServerError)
 
-				_, err := w.Write([]byte("Failed to encode string\n"))
+				_, err := w.Write([]byte(localizer(r).Sprintf("Failed to encode string") + "\n"))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxRateLimitClients is the number of clients tracked at once, past which
+// the least recently seen client is forgotten.
+const maxRateLimitClients = 4096
+
+type tokenBucket struct {
+	client string
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter allows each client a number of requests per second, set by a
+// runtime-adjustable limit, with bursts of up to one second's worth.
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit *Limit
+
+	// buckets holds an element of order for each client, which is kept
+	// sorted from the most to the least recently seen.
+	buckets map[string]*list.Element
+	order   *list.List
+}
+
+func newRateLimiter(limit *Limit) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// rateLimitKey returns the address a request is rate limited by. The
+// headers set by reverse proxies are only used if trusted, as clients
+// could otherwise pick a new address for every request.
+func rateLimitKey(r *http.Request) string {
+	if trustProxy {
+		return realIP(r, false)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// refill returns the tokens a bucket holds at the given time.
+func (b *tokenBucket) refill(now time.Time, rate float64) float64 {
+	return min(rate, b.tokens+now.Sub(b.last).Seconds()*rate)
+}
+
+// Allow reports whether client may make another request, using up one token
+// from its bucket if so.
+func (l *rateLimiter) Allow(client string) bool {
+	rate := float64(l.limit.Load())
+
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	element, ok := l.buckets[client]
+	if ok {
+		l.order.MoveToFront(element)
+	} else {
+		// The least recently seen client has had the longest for its
+		// bucket to refill, so it loses the least by being forgotten.
+		if l.order.Len() >= maxRateLimitClients {
+			oldest := l.order.Remove(l.order.Back()).(*tokenBucket)
+
+			delete(l.buckets, oldest.client)
+		}
+
+		element = l.order.PushFront(&tokenBucket{client: client, tokens: rate, last: now})
+
+		l.buckets[client] = element
+	}
+
+	bucket := element.Value.(*tokenBucket)
+
+	bucket.tokens = bucket.refill(now, rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	return true
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func newTestRateLimiter(t *testing.T, rate int64) *rateLimiter {
+	t.Helper()
+
+	limit := &Limit{Name: "test-rate", Min: 1}
+
+	err := limit.Store(rate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return newRateLimiter(limit)
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter := newTestRateLimiter(t, 3)
+
+	for i := range 3 {
+		if !limiter.Allow("192.0.2.1") {
+			t.Fatalf("request %d was rejected within the burst", i+1)
+		}
+	}
+
+	if limiter.Allow("192.0.2.1") {
+		t.Error("request past the burst was allowed")
+	}
+
+	if !limiter.Allow("192.0.2.2") {
+		t.Error("another client's request was rejected")
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	limiter := newTestRateLimiter(t, 1)
+
+	limiter.Allow("first")
+	limiter.Allow("second")
+
+	// Seeing the first client again leaves the second as the least
+	// recently seen, so it is forgotten first.
+	limiter.Allow("first")
+
+	for i := range maxRateLimitClients {
+		limiter.Allow(strconv.Itoa(i))
+	}
+
+	if got := len(limiter.buckets); got != maxRateLimitClients {
+		t.Errorf("tracking %d clients, want %d", got, maxRateLimitClients)
+	}
+
+	if got := limiter.order.Len(); got != maxRateLimitClients {
+		t.Errorf("ordering %d clients, want %d", got, maxRateLimitClients)
+	}
+
+	if _, ok := limiter.buckets["second"]; ok {
+		t.Error("the least recently seen client was kept")
+	}
+
+	limiter.Allow("new")
+
+	if _, ok := limiter.buckets["first"]; ok {
+		t.Error("the least recently seen client was kept")
+	}
+
+	if _, ok := limiter.buckets["new"]; !ok {
+		t.Error("a new client was not tracked")
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	t.Cleanup(func() {
+		trustProxy = false
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		trust      bool
+		want       string
+	}{
+		{"IPv4", "192.0.2.1:1234", false, "192.0.2.1"},
+		{"IPv6", "[2001:db8::1]:1234", false, "2001:db8::1"},
+		{"trusted headers", "192.0.2.1:1234", true, "198.51.100.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trustProxy = test.trust
+
+			r := httptest.NewRequest("GET", "/dns-query", nil)
+			r.RemoteAddr = test.remoteAddr
+			r.Header.Set("X-Real-Ip", "198.51.100.1")
+
+			if got := rateLimitKey(r); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
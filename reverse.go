@@ -0,0 +1,181 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ClasslessDelegation struct {
+	Parent  string   `json:"parent"`
+	Zone    string   `json:"zone"`
+	Records []string `json:"records"`
+}
+
+type ReverseZones struct {
+	Prefix     string               `json:"prefix"`
+	Zones      []string             `json:"zones"`
+	Delegation *ClasslessDelegation `json:"classless_delegation,omitempty"`
+}
+
+// reverseName returns the reverse DNS name for the first bits of an address,
+// which must fall on an octet boundary for IPv4 or a nibble boundary for IPv6.
+func reverseName(addr netip.Addr, bits int) string {
+	var labels []string
+
+	if addr.Is4() {
+		octets := addr.As4()
+
+		for i := bits/8 - 1; i >= 0; i-- {
+			labels = append(labels, strconv.Itoa(int(octets[i])))
+		}
+
+		return strings.Join(append(labels, "in-addr.arpa"), ".")
+	}
+
+	b := addr.As16()
+
+	for i := bits/4 - 1; i >= 0; i-- {
+		nibble := b[i/2] & 0x0f
+		if i%2 == 0 {
+			nibble = b[i/2] >> 4
+		}
+
+		labels = append(labels, strconv.FormatUint(uint64(nibble), 16))
+	}
+
+	return strings.Join(append(labels, "ip6.arpa"), ".")
+}
+
+// reverseZones returns the reverse DNS zones needed to delegate a prefix.
+// Prefixes between label boundaries are rounded down to the zones covering
+// them, apart from IPv4 prefixes longer than /24, which are delegated from
+// their parent /24 zone using the classless scheme from RFC 2317.
+func reverseZones(p netip.Prefix) ReverseZones {
+	result := ReverseZones{
+		Prefix: p.String(),
+	}
+
+	is4 := p.Addr().Is4()
+
+	if is4 && p.Bits() > 24 && p.Bits() < 32 {
+		first, last := prefixBounds(p)
+
+		parent := reverseName(p.Addr(), 24)
+		zone := fmt.Sprintf("%d/%d.%s", first.lo&0xff, p.Bits(), parent)
+
+		result.Zones = []string{zone}
+
+		result.Delegation = &ClasslessDelegation{
+			Parent: parent,
+			Zone:   zone,
+		}
+
+		for host := first.lo & 0xff; host <= last.lo&0xff; host++ {
+			result.Delegation.Records = append(result.Delegation.Records,
+				fmt.Sprintf("%d.%s. CNAME %d.%s.", host, parent, host, zone))
+		}
+
+		return result
+	}
+
+	step := 4
+	if is4 {
+		step = 8
+	}
+
+	// Round the prefix length up to the next label boundary, and list each
+	// of the zones at that length.
+	bits := (p.Bits() + step - 1) / step * step
+
+	first, _ := prefixBounds(p)
+	size := uint128{0, 1}.lsh(p.Addr().BitLen() - bits)
+
+	for i := 0; i < 1<<(bits-p.Bits()); i++ {
+		result.Zones = append(result.Zones, reverseName(first.toAddr(is4), bits))
+
+		first = first.add(size)
+	}
+
+	return result
+}
+
+func serveReverseZones(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		operands, err := parseOperands(p.ByName("reverse"))
+		if err == nil && len(operands) != 1 {
+			err = ErrInvalidPrefix
+		}
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		zones := reverseZones(operands[0])
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, zones)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			fields := [][2]string{
+				{"Prefix", zones.Prefix},
+				{"Zones", localizeNumber(pr, strconv.Itoa(len(zones.Zones)))},
+			}
+
+			if zones.Delegation != nil {
+				fields = append(fields,
+					[2]string{"Parent zone", zones.Delegation.Parent},
+					[2]string{"Delegated zone", zones.Delegation.Zone})
+			}
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, fields))
+
+			output.WriteString("\n")
+
+			for _, zone := range zones.Zones {
+				output.WriteString(zone + "\n")
+			}
+
+			if zones.Delegation != nil {
+				output.WriteString("\n; " + pr.Sprintf("Records for the %s zone", zones.Delegation.Parent) + "\n")
+
+				for _, record := range zones.Delegation.Records {
+					output.WriteString(record + "\n")
+				}
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
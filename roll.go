@@ -5,6 +5,7 @@ Copyright © 2026 Seednode <seednode@seedno.de>
 package main
 
 import (
+	"bytes"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -17,16 +18,151 @@ import (
 	"time"
 
 	"github.com/julienschmidt/httprouter"
-	"golang.org/x/text/language"
-	"golang.org/x/text/message"
+)
+
+const (
+	maxDiceRooms      = 1024
+	diceRoomHistory   = 100
+	diceRoomQueueSize = 16
 )
 
 var (
 	number                 = regexp.MustCompile(`\d+`)
+	roomName               = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
 	ErrInvalidMaxDiceCount = errors.New("max dice roll count must be a positive integer")
 	ErrInvalidMaxDiceSides = errors.New("max dice side count must be a positive integer")
 )
 
+type DiceRoomEvent struct {
+	ID   uint64
+	Data string
+}
+
+// DiceRoom broadcasts every roll made in it to all subscribers, and keeps a
+// short history so that reconnecting clients can catch up.
+type DiceRoom struct {
+	mu          sync.Mutex
+	lastID      uint64
+	lastActive  time.Time
+	history     []DiceRoomEvent
+	subscribers map[chan DiceRoomEvent]struct{}
+}
+
+func (d *DiceRoom) Broadcast(data string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.lastID++
+	d.lastActive = time.Now()
+
+	event := DiceRoomEvent{ID: d.lastID, Data: data}
+
+	d.history = append(d.history, event)
+	if len(d.history) > diceRoomHistory {
+		d.history = d.history[len(d.history)-diceRoomHistory:]
+	}
+
+	for subscriber := range d.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			// Drop subscribers that can't keep up, rather than stalling rolls.
+			delete(d.subscribers, subscriber)
+
+			close(subscriber)
+		}
+	}
+}
+
+// Subscribe returns a channel of future events, along with any events
+// newer than lastEventID that the client missed.
+func (d *DiceRoom) Subscribe(lastEventID string) (chan DiceRoomEvent, []DiceRoomEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	events := make(chan DiceRoomEvent, diceRoomQueueSize)
+
+	d.subscribers[events] = struct{}{}
+
+	d.lastActive = time.Now()
+
+	var backlog []DiceRoomEvent
+
+	last, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err == nil {
+		for _, event := range d.history {
+			if event.ID > last {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	return events, backlog
+}
+
+func (d *DiceRoom) Unsubscribe(events chan DiceRoomEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.subscribers[events]
+	if ok {
+		delete(d.subscribers, events)
+
+		close(events)
+	}
+}
+
+type DiceRooms struct {
+	mu    sync.Mutex
+	rooms map[string]*DiceRoom
+}
+
+// Room returns the named room, creating it if necessary. Once maxDiceRooms
+// is reached, the least recently active room without subscribers is evicted.
+func (d *DiceRooms) Room(name string) *DiceRoom {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.rooms == nil {
+		d.rooms = make(map[string]*DiceRoom)
+	}
+
+	room, ok := d.rooms[name]
+	if ok {
+		return room
+	}
+
+	if len(d.rooms) >= maxDiceRooms {
+		var oldest string
+		var oldestActive time.Time
+
+		for k, v := range d.rooms {
+			v.mu.Lock()
+			idle := len(v.subscribers) == 0
+			lastActive := v.lastActive
+			v.mu.Unlock()
+
+			if idle && (oldest == "" || lastActive.Before(oldestActive)) {
+				oldest = k
+				oldestActive = lastActive
+			}
+		}
+
+		if oldest != "" {
+			delete(d.rooms, oldest)
+		}
+	}
+
+	room = &DiceRoom{
+		lastActive:  time.Now(),
+		subscribers: make(map[chan DiceRoomEvent]struct{}),
+	}
+
+	d.rooms[name] = room
+
+	return room
+}
+
 func rollDice(count, die int64) ([]int64, []int64, error) {
 	var i int64
 
@@ -49,30 +185,34 @@ func rollDice(count, die int64) ([]int64, []int64, error) {
 	return rolls, results, nil
 }
 
-func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
+func serveDiceRoll(rooms *DiceRooms, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
 		wantsVerbose := r.URL.Query().Has("verbose")
 
-		langHeaders := strings.Split(r.Header.Get("Accept-Language"), ",")
+		room := r.URL.Query().Get("room")
+
+		if room != "" && !roomName.MatchString(room) {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
 
-		lang := language.Tag{}
+			securityHeaders(w)
 
-		for _, value := range langHeaders {
-			i, _ := language.Parse(value)
+			w.WriteHeader(http.StatusBadRequest)
 
-			if (i != language.Tag{}) {
-				lang = i
-				break
+			_, err := w.Write([]byte(localizer(r).Sprintf("Invalid room name") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
+
+			return
 		}
 
 		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
 
 		securityHeaders(w)
 
-		pr := message.NewPrinter(lang)
+		pr := localizer(r)
 
 		var total int64 = 0
 		var length int = 0
@@ -118,8 +258,11 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 				longestDie = thisDie
 			}
 
+			maxRolls := diceRollLimit.Load()
+			maxSides := diceSideLimit.Load()
+
 			switch {
-			case count > int64(maxDiceRolls):
+			case count > maxRolls:
 				if verbose {
 					fmt.Printf("%s | %s => %s (too many dice)\n",
 						startTime.Format(timeFormats["RFC3339"]),
@@ -129,7 +272,7 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 
 				w.WriteHeader(http.StatusBadRequest)
 
-				_, err = w.Write(fmt.Appendf(nil, "Dice roll count must be no greater than %d", maxDiceRolls))
+				_, err = w.Write([]byte(pr.Sprintf("Dice roll count must be no greater than %d", maxRolls)))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -145,13 +288,13 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 
 				w.WriteHeader(http.StatusBadRequest)
 
-				_, err = w.Write([]byte("Cannot roll zero dice"))
+				_, err = w.Write([]byte(pr.Sprintf("Cannot roll zero dice")))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
 
 				return
-			case die > int64(maxDiceSides):
+			case die > maxSides:
 				if verbose {
 					fmt.Printf("%s | %s => %s (too many sides)\n",
 						startTime.Format(timeFormats["RFC3339"]),
@@ -161,7 +304,7 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 
 				w.WriteHeader(http.StatusBadRequest)
 
-				_, err = w.Write(fmt.Appendf(nil, "Dice side count must be no greater than %d", maxDiceSides))
+				_, err = w.Write([]byte(pr.Sprintf("Dice side count must be no greater than %d", maxSides)))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -177,7 +320,7 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 
 				w.WriteHeader(http.StatusBadRequest)
 
-				_, err = w.Write([]byte("Dice cannot have zero sides"))
+				_, err = w.Write([]byte(pr.Sprintf("Dice cannot have zero sides")))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
@@ -203,16 +346,13 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 		padDiceTo := longestDie + 1
 		padValueTo := longestDie
 
+		var output bytes.Buffer
+
 		for i := 0; i < len(rolledDice); i++ {
 			total += rolledResults[i]
 
 			if wantsVerbose {
-				written, err := w.Write(fmt.Appendf(nil, "%*d | %*s -> %*d\n", padCountTo, i+1, padDiceTo, fmt.Sprintf("d%d", rolledDice[i]), padValueTo, rolledResults[i]))
-				if err != nil {
-					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-
-					return
-				}
+				written, _ := output.Write(fmt.Appendf(nil, "%*d | %*s -> %*d\n", padCountTo, i+1, padDiceTo, fmt.Sprintf("d%d", rolledDice[i]), padValueTo, rolledResults[i]))
 
 				if written > length {
 					length = written
@@ -221,12 +361,7 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 		}
 
 		if wantsVerbose {
-			_, err := w.Write(fmt.Appendf(nil, "%s\nTotal: ", strings.Repeat("-", length-1)))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
-
-				return
-			}
+			output.WriteString(strings.Repeat("-", length-1) + "\n" + pr.Sprintf("Total: "))
 		}
 
 		if verbose {
@@ -238,7 +373,13 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 
 		result, _ := strconv.Atoi(strconv.FormatInt(total, 10))
 
-		_, err := w.Write([]byte(pr.Sprintf("%*d\n", length-8, result)))
+		output.WriteString(pr.Sprintf("%*d\n", length-8, result))
+
+		if room != "" {
+			rooms.Room(room).Broadcast(trimmed + "\n" + output.String())
+		}
+
+		_, err := w.Write(output.Bytes())
 		if err != nil {
 			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
@@ -247,14 +388,87 @@ func serveDiceRoll(errorChannel chan<- Error) httprouter.Handle {
 	}
 }
 
+func serveDiceRoom(rooms *DiceRooms, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		name := p.ByName("roll")
+
+		if !roomName.MatchString(name) {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			securityHeaders(w)
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err := w.Write([]byte(localizer(r).Sprintf("Invalid room name") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		room := rooms.Room(name)
+
+		events, backlog := room.Subscribe(lastEventID(r))
+		defer room.Unsubscribe(events)
+
+		stream, done, err := openStream(w)
+		if err != nil {
+			serveStreamError(w, r, err, errorChannel)
+
+			return
+		}
+		defer done()
+
+		if verbose {
+			fmt.Printf("%s | %s => %s (stream opened)\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+
+		for _, event := range backlog {
+			err = stream.Event(strconv.FormatUint(event.ID, 10), "roll", event.Data)
+			if err != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for err == nil {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				err = stream.Event(strconv.FormatUint(event.ID, 10), "roll", event.Data)
+			case <-heartbeat.C:
+				err = stream.Heartbeat()
+			}
+		}
+	}
+}
+
 func registerRoll(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
 	const module = "roll"
 
-	mux.GET("/roll/:roll", serveDiceRoll(errorChannel))
+	rooms := &DiceRooms{}
+
+	mux.GET("/roll/:roll", serveDiceRoll(rooms, errorChannel))
+	mux.GET("/roll/:roll/stream", serveDiceRoom(rooms, errorChannel))
 	mux.GET("/roll/", serveUsage(module, usage, errorChannel))
 
 	usage.Store(module, []string{
+		"/roll/4d6?room=table1",
 		"/roll/5d20",
+		"/roll/table1/stream",
 		"/roll/d6?verbose",
 		"/roll/4d6,5d8,d4?verbose",
 	})
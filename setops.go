@@ -0,0 +1,242 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
+)
+
+type Containment struct {
+	Prefix   string `json:"prefix"`
+	Address  string `json:"address"`
+	Contains bool   `json:"contains"`
+}
+
+type Overlap struct {
+	A        string `json:"a"`
+	B        string `json:"b"`
+	Overlaps bool   `json:"overlaps"`
+	First    string `json:"first,omitempty"`
+	Last     string `json:"last,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+}
+
+type SetResult struct {
+	A         string   `json:"a"`
+	B         string   `json:"b"`
+	Count     int      `json:"count"`
+	Addresses string   `json:"addresses"`
+	Prefixes  []string `json:"prefixes"`
+}
+
+// setOperation computes the result of an operation on two prefixes, returning
+// the value to encode as JSON, the labelled fields to print as text, and any
+// prefixes to list below those fields.
+type setOperation func(a, b netip.Prefix, pr *message.Printer) (any, [][2]string, []string)
+
+// parseOperands reads a list of addresses and prefixes from a path. An
+// address followed by a segment made up only of digits is treated as a
+// prefix, while a bare address becomes a single-address prefix.
+func parseOperands(path string) ([]netip.Prefix, error) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var operands []netip.Prefix
+
+	for i := 0; i < len(segments); i++ {
+		addr, err := netip.ParseAddr(segments[i])
+		if err != nil || addr.Zone() != "" {
+			return nil, ErrInvalidPrefix
+		}
+
+		bits := addr.BitLen()
+
+		if i+1 < len(segments) && segments[i+1] != "" && strings.Trim(segments[i+1], "0123456789") == "" {
+			bits, err = strconv.Atoi(segments[i+1])
+			if err != nil {
+				return nil, ErrInvalidPrefix
+			}
+
+			i++
+		}
+
+		p := netip.PrefixFrom(addr, bits)
+		if !p.IsValid() {
+			return nil, ErrInvalidPrefix
+		}
+
+		operands = append(operands, p.Masked())
+	}
+
+	return operands, nil
+}
+
+// operandString omits the prefix length from single addresses.
+func operandString(p netip.Prefix) string {
+	if p.IsSingleIP() {
+		return p.Addr().String()
+	}
+
+	return p.String()
+}
+
+func yesNo(pr *message.Printer, value bool) string {
+	if value {
+		return pr.Sprintf("Yes")
+	}
+
+	return pr.Sprintf("No")
+}
+
+func contains(a, b netip.Prefix, pr *message.Printer) (any, [][2]string, []string) {
+	result := Containment{
+		Prefix:   operandString(a),
+		Address:  operandString(b),
+		Contains: a.Bits() <= b.Bits() && a.Contains(b.Addr()),
+	}
+
+	return result, [][2]string{
+		{"Prefix", result.Prefix},
+		{"Address", result.Address},
+		{"Contained", yesNo(pr, result.Contains)},
+	}, nil
+}
+
+func overlap(a, b netip.Prefix, pr *message.Printer) (any, [][2]string, []string) {
+	result := Overlap{
+		A:        operandString(a),
+		B:        operandString(b),
+		Overlaps: a.Overlaps(b),
+	}
+
+	fields := [][2]string{
+		{"Prefix A", result.A},
+		{"Prefix B", result.B},
+		{"Overlaps", yesNo(pr, result.Overlaps)},
+	}
+
+	if result.Overlaps {
+		// Two overlapping prefixes always share the whole of the smaller one.
+		smaller := a
+		if b.Bits() > a.Bits() {
+			smaller = b
+		}
+
+		first, last := prefixBounds(smaller)
+
+		result.First = first.toAddr(smaller.Addr().Is4()).String()
+		result.Last = last.toAddr(smaller.Addr().Is4()).String()
+		result.Prefix = smaller.String()
+
+		fields = append(fields,
+			[2]string{"First", result.First},
+			[2]string{"Last", result.Last},
+			[2]string{"Prefix", result.Prefix})
+	}
+
+	return result, fields, nil
+}
+
+func setResult(a, b netip.Prefix, set *PrefixSet, pr *message.Printer) (any, [][2]string, []string) {
+	prefixes := set.Prefixes()
+
+	result := SetResult{
+		A:         operandString(a),
+		B:         operandString(b),
+		Count:     len(prefixes),
+		Addresses: set.Size().String(),
+		Prefixes:  make([]string, len(prefixes)),
+	}
+
+	for i, p := range prefixes {
+		result.Prefixes[i] = p.String()
+	}
+
+	return result, [][2]string{
+		{"Prefix A", result.A},
+		{"Prefix B", result.B},
+		{"Prefixes", localizeNumber(pr, strconv.Itoa(result.Count))},
+		{"Addresses", localizeNumber(pr, result.Addresses)},
+	}, result.Prefixes
+}
+
+func exclude(a, b netip.Prefix, pr *message.Printer) (any, [][2]string, []string) {
+	var x, y PrefixSet
+
+	x.AddPrefix(a)
+	y.AddPrefix(b)
+
+	return setResult(a, b, x.Subtract(&y), pr)
+}
+
+func intersect(a, b netip.Prefix, pr *message.Printer) (any, [][2]string, []string) {
+	var x, y PrefixSet
+
+	x.AddPrefix(a)
+	y.AddPrefix(b)
+
+	return setResult(a, b, x.Intersect(&y), pr)
+}
+
+func serveSetOperation(param string, operation setOperation, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		operands, err := parseOperands(p.ByName(param))
+		if err == nil && len(operands) != 2 {
+			err = ErrInvalidPrefix
+		}
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		result, fields, prefixes := operation(operands[0], operands[1], pr)
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, result)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, fields))
+
+			if len(prefixes) > 0 {
+				output.WriteString("\n")
+
+				for _, prefix := range prefixes {
+					output.WriteString(prefix + "\n")
+				}
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	defaultSplitLimit = 256
+	maxSplitLimit     = 4096
+	maxVLSMSubnets    = 1024
+)
+
+var (
+	ErrInvalidPrefixLength = errors.New("invalid prefix length")
+	ErrInvalidPage         = errors.New("invalid page")
+	ErrInvalidHostCount    = errors.New("invalid host count")
+	ErrInsufficientSpace   = errors.New("requested hosts do not fit in parent prefix")
+)
+
+type SubnetSplit struct {
+	Parent       string   `json:"parent"`
+	PrefixLength int      `json:"prefix_length"`
+	Total        string   `json:"total"`
+	Page         string   `json:"page"`
+	Pages        string   `json:"pages"`
+	Subnets      []string `json:"subnets"`
+}
+
+type VLSMAllocation struct {
+	Hosts       uint64 `json:"hosts"`
+	Prefix      string `json:"prefix"`
+	Size        string `json:"size"`
+	Usable      string `json:"usable"`
+	FirstUsable string `json:"first_usable"`
+	LastUsable  string `json:"last_usable"`
+}
+
+type VLSMPlan struct {
+	Parent      string           `json:"parent"`
+	Allocated   string           `json:"allocated"`
+	Free        string           `json:"free"`
+	Allocations []VLSMAllocation `json:"allocations"`
+}
+
+// parsePaging reads the ?page= and ?limit= query parameters.
+func parsePaging(r *http.Request) (*big.Int, int, error) {
+	page := big.NewInt(1)
+
+	if v := r.URL.Query().Get("page"); v != "" {
+		_, ok := page.SetString(v, 10)
+		if !ok || page.Sign() < 1 {
+			return nil, 0, ErrInvalidPage
+		}
+	}
+
+	limit := defaultSplitLimit
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxSplitLimit {
+			return nil, 0, ErrInvalidPage
+		}
+	}
+
+	return page, limit, nil
+}
+
+func splitSubnet(parent netip.Prefix, newLength int, page *big.Int, limit int) (SubnetSplit, error) {
+	if newLength < parent.Bits() || newLength > parent.Addr().BitLen() {
+		return SubnetSplit{}, ErrInvalidPrefixLength
+	}
+
+	total := new(big.Int).Lsh(big.NewInt(1), uint(newLength-parent.Bits()))
+
+	pages := new(big.Int).Add(total, big.NewInt(int64(limit-1)))
+	pages.Div(pages, big.NewInt(int64(limit)))
+
+	if page.Cmp(pages) > 0 {
+		return SubnetSplit{}, ErrInvalidPage
+	}
+
+	start := new(big.Int).Sub(page, big.NewInt(1))
+	start.Mul(start, big.NewInt(int64(limit)))
+
+	remaining := new(big.Int).Sub(total, start)
+	if remaining.IsInt64() && remaining.Int64() < int64(limit) {
+		limit = int(remaining.Int64())
+	}
+
+	hostBits := parent.Addr().BitLen() - newLength
+
+	first, _ := prefixBounds(parent)
+
+	current := first.add(fromBig(start).lsh(hostBits))
+	step := uint128{0, 1}.lsh(hostBits)
+
+	split := SubnetSplit{
+		Parent:       parent.String(),
+		PrefixLength: newLength,
+		Total:        total.String(),
+		Page:         page.String(),
+		Pages:        pages.String(),
+		Subnets:      make([]string, 0, limit),
+	}
+
+	for range limit {
+		split.Subnets = append(split.Subnets, netip.PrefixFrom(current.toAddr(parent.Addr().Is4()), newLength).String())
+
+		current = current.add(step)
+	}
+
+	return split, nil
+}
+
+// hostBitsFor returns the number of host bits needed to fit the given number
+// of hosts, reserving the network and broadcast addresses for IPv4.
+func hostBitsFor(hosts uint64, is4 bool) int {
+	if is4 {
+		hosts += 2
+	}
+
+	return bits.Len64(hosts - 1)
+}
+
+// planVLSM allocates a subnet for each host count from the parent prefix.
+// Allocating the largest blocks first keeps every block aligned to its own
+// size, so the blocks pack together without gaps.
+func planVLSM(parent netip.Prefix, hosts []uint64) (VLSMPlan, error) {
+	is4 := parent.Addr().Is4()
+	available := parent.Addr().BitLen() - parent.Bits()
+
+	order := make([]int, len(hosts))
+	for i := range order {
+		order[i] = i
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		return hostBitsFor(hosts[b], is4) - hostBitsFor(hosts[a], is4)
+	})
+
+	first, last := prefixBounds(parent)
+
+	cursor := first
+	exhausted := false
+
+	allocated := new(big.Int)
+
+	allocations := make([]VLSMAllocation, len(hosts))
+
+	for _, i := range order {
+		hostBits := hostBitsFor(hosts[i], is4)
+
+		if exhausted || hostBits > available {
+			return VLSMPlan{}, ErrInsufficientSpace
+		}
+
+		end := cursor.add(ones(hostBits))
+		if end.cmp(last) > 0 || end.cmp(cursor) < 0 {
+			return VLSMPlan{}, ErrInsufficientSpace
+		}
+
+		prefix := netip.PrefixFrom(cursor.toAddr(is4), parent.Addr().BitLen()-hostBits)
+
+		size := prefixSize(prefix)
+		usable := new(big.Int).Set(size)
+
+		firstUsable, lastUsable := cursor.toAddr(is4), end.toAddr(is4)
+
+		if is4 {
+			usable.Sub(usable, big.NewInt(2))
+
+			firstUsable, lastUsable = firstUsable.Next(), lastUsable.Prev()
+		}
+
+		allocations[i] = VLSMAllocation{
+			Hosts:       hosts[i],
+			Prefix:      prefix.String(),
+			Size:        size.String(),
+			Usable:      usable.String(),
+			FirstUsable: firstUsable.String(),
+			LastUsable:  lastUsable.String(),
+		}
+
+		allocated.Add(allocated, size)
+
+		if end.cmp(last) == 0 {
+			exhausted = true
+		}
+
+		cursor = end.add(uint128{0, 1})
+	}
+
+	return VLSMPlan{
+		Parent:      parent.String(),
+		Allocated:   allocated.String(),
+		Free:        new(big.Int).Sub(prefixSize(parent), allocated).String(),
+		Allocations: allocations,
+	}, nil
+}
+
+func parseHostCounts(s string) ([]uint64, error) {
+	var hosts []uint64
+
+	for field := range strings.SplitSeq(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		count, err := strconv.ParseUint(field, 10, 64)
+		if err != nil || count < 1 || count > 1<<62 {
+			return nil, ErrInvalidHostCount
+		}
+
+		hosts = append(hosts, count)
+	}
+
+	if len(hosts) == 0 || len(hosts) > maxVLSMSubnets {
+		return nil, ErrInvalidHostCount
+	}
+
+	return hosts, nil
+}
+
+func serveSubnetSplit(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		path := strings.Trim(p.ByName("split"), "/")
+
+		i := strings.LastIndex(path, "/")
+		if i == -1 {
+			serveSubnetError(w, r, ErrInvalidPrefix, errorChannel)
+
+			return
+		}
+
+		parent, err := parsePrefix(path[:i])
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		newLength, err := strconv.Atoi(path[i+1:])
+		if err != nil {
+			serveSubnetError(w, r, ErrInvalidPrefixLength, errorChannel)
+
+			return
+		}
+
+		page, limit, err := parsePaging(r)
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		split, err := splitSubnet(parent, newLength, page, limit)
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, split)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, [][2]string{
+				{"Parent", split.Parent},
+				{"Subnets", fmt.Sprintf("%s x /%d", localizeNumber(pr, split.Total), split.PrefixLength)},
+				{"Page", pr.Sprintf("%s of %s", localizeNumber(pr, split.Page), localizeNumber(pr, split.Pages))},
+			}))
+
+			output.WriteString("\n")
+
+			for _, subnet := range split.Subnets {
+				output.WriteString(subnet + "\n")
+			}
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
+
+func serveVLSM(errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		pr := localizer(r)
+
+		parent, err := parsePrefix(strings.Trim(p.ByName("vlsm"), "/"))
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		hosts, err := parseHostCounts(r.URL.Query().Get("hosts"))
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		plan, err := planVLSM(parent, hosts)
+		if errors.Is(err, ErrInsufficientSpace) {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			securityHeaders(w)
+
+			w.WriteHeader(http.StatusUnprocessableEntity)
+
+			_, err = w.Write([]byte(pr.Sprintf("Requested hosts do not fit in %s", parent) + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		securityHeaders(w)
+
+		if outputFormat(r) == "json" {
+			err = writeJSON(w, plan)
+		} else {
+			w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+			var output strings.Builder
+
+			output.WriteString(formatFields(pr, [][2]string{
+				{"Parent", plan.Parent},
+				{"Allocated", localizeNumber(pr, plan.Allocated)},
+				{"Free", localizeNumber(pr, plan.Free)},
+			}))
+
+			output.WriteString("\n")
+
+			tw := tabwriter.NewWriter(&output, 0, 0, 2, ' ', 0)
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", pr.Sprintf("Hosts"), pr.Sprintf("Prefix"), pr.Sprintf("Usable"), pr.Sprintf("Range"))
+
+			for _, allocation := range plan.Allocations {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s - %s\n",
+					localizeNumber(pr, strconv.FormatUint(allocation.Hosts, 10)),
+					allocation.Prefix,
+					localizeNumber(pr, allocation.Usable),
+					allocation.FirstUsable,
+					allocation.LastUsable)
+			}
+
+			tw.Flush()
+
+			_, err = w.Write([]byte(output.String()))
+		}
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
+	}
+}
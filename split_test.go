@@ -0,0 +1,88 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestPlanVLSM(t *testing.T) {
+	tests := []struct {
+		name      string
+		parent    string
+		hosts     []uint64
+		want      []string
+		allocated string
+		free      string
+	}{
+		{"largest first", "10.0.0.0/24", []uint64{20, 100, 50}, []string{"10.0.0.192/27", "10.0.0.0/25", "10.0.0.128/26"}, "224", "32"},
+		{"exact fit", "10.0.0.0/24", []uint64{126, 126}, []string{"10.0.0.0/25", "10.0.0.128/25"}, "256", "0"},
+		{"single host", "192.0.2.0/24", []uint64{1}, []string{"192.0.2.0/30"}, "4", "252"},
+		{"IPv4 /0", "0.0.0.0/0", []uint64{1 << 31}, []string{"0.0.0.0/0"}, "4294967296", "0"},
+		{"IPv6", "2001:db8::/64", []uint64{1, 2, 4}, []string{"2001:db8::6/128", "2001:db8::4/127", "2001:db8::/126"}, "7", "18446744073709551609"},
+		{"end of the address space", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffc/126", []uint64{1, 1, 1, 1}, []string{
+			"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffc/128",
+			"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffd/128",
+			"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/128",
+			"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff/128",
+		}, "4", "0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := planVLSM(netip.MustParsePrefix(test.parent), test.hosts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			for _, allocation := range plan.Allocations {
+				got = append(got, allocation.Prefix)
+			}
+
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+
+			if plan.Allocated != test.allocated || plan.Free != test.free {
+				t.Errorf("allocated %s, free %s, want %s, %s", plan.Allocated, plan.Free, test.allocated, test.free)
+			}
+		})
+	}
+
+	t.Run("usable", func(t *testing.T) {
+		plan, err := planVLSM(netip.MustParsePrefix("192.0.2.0/24"), []uint64{60})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := VLSMAllocation{Hosts: 60, Prefix: "192.0.2.0/26", Size: "64", Usable: "62", FirstUsable: "192.0.2.1", LastUsable: "192.0.2.62"}
+
+		if got := plan.Allocations[0]; got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	for _, test := range []struct {
+		name   string
+		parent string
+		hosts  []uint64
+	}{
+		{"too large", "10.0.0.0/24", []uint64{300}},
+		{"too many", "10.0.0.0/24", []uint64{126, 126, 1}},
+		{"past the end of the address space", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffc/126", []uint64{1, 1, 1, 1, 1}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := planVLSM(netip.MustParsePrefix(test.parent), test.hosts)
+			if !errors.Is(err, ErrInsufficientSpace) {
+				t.Errorf("got %v, want %v", err, ErrInsufficientSpace)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	streamHeartbeat    = 15 * time.Second
+	streamRetry        = 5 * time.Second
+	streamWriteTimeout = 30 * time.Second
+)
+
+var (
+	ErrInvalidMaxStreams = errors.New("max stream count must be a positive integer")
+	ErrTooManyStreams    = errors.New("too many active streams")
+
+	activeStreams atomic.Int64
+)
+
+// EventStream writes Server-Sent Events to a single client.
+//
+// The server's WriteTimeout would otherwise cut every stream off after a few
+// minutes, so the write deadline is pushed forward before each write instead.
+type EventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// openStream reserves one of the available stream slots and sends the event
+// stream headers. The returned function must be called to release the slot.
+func openStream(w http.ResponseWriter) (*EventStream, func(), error) {
+	if activeStreams.Add(1) > streamLimit.Load() {
+		activeStreams.Add(-1)
+
+		return nil, nil, ErrTooManyStreams
+	}
+
+	s := &EventStream{
+		w:  w,
+		rc: http.NewResponseController(w),
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	securityHeaders(w)
+
+	w.WriteHeader(http.StatusOK)
+
+	err := s.write(fmt.Sprintf("retry: %d\n\n", streamRetry.Milliseconds()))
+	if err != nil {
+		activeStreams.Add(-1)
+
+		return nil, nil, err
+	}
+
+	return s, func() { activeStreams.Add(-1) }, nil
+}
+
+func (s *EventStream) write(data string) error {
+	err := s.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	_, err = s.w.Write([]byte(data))
+	if err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
+
+func (s *EventStream) Event(id, event, data string) error {
+	var output strings.Builder
+
+	if id != "" {
+		output.WriteString("id: " + id + "\n")
+	}
+
+	if event != "" {
+		output.WriteString("event: " + event + "\n")
+	}
+
+	for line := range strings.SplitSeq(strings.TrimSuffix(data, "\n"), "\n") {
+		output.WriteString("data: " + line + "\n")
+	}
+
+	output.WriteString("\n")
+
+	return s.write(output.String())
+}
+
+func (s *EventStream) Heartbeat() error {
+	return s.write(": heartbeat\n\n")
+}
+
+func lastEventID(r *http.Request) string {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("lastEventId")
+	}
+
+	return id
+}
+
+func serveStreamError(w http.ResponseWriter, r *http.Request, err error, errorChannel chan<- Error) {
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	if errors.Is(err, ErrTooManyStreams) {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(streamRetry.Seconds())))
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+
+		_, err = w.Write([]byte(localizer(r).Sprintf("Too many active streams") + "\n"))
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		}
+
+		return
+	}
+
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+}
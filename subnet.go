@@ -8,14 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
 )
 
 const (
@@ -40,33 +42,58 @@ const (
       <table>
         <tr>
 		  <th></th>
-		  <th>Binary</th>
-		  <th>Decimal</th>
+		  <th>{{call .T "Binary"}}</th>
+		  <th>{{call .T "Decimal"}}</th>
 		</tr>
 		<tr>
-		  <th>Address</th>
+		  <th>{{call .T "Address"}}</th>
 		  <td>{{.Address_Binary}}</td>
 		  <td>{{.Address_Decimal}}</td>
 		</tr>
 		<tr>
-		  <th>Mask</th>
+		  <th>{{call .T "Mask"}}</th>
 		  <td>{{.Mask_Binary}}</td>
 		  <td>{{.Mask_Decimal}}</td>
 		</tr>
 		<tr>
-		  <th>First</th>
+		  <th>{{call .T "First"}}</th>
 		  <td>{{.First_Binary}}</td>
 		  <td>{{.First_Decimal}}</td>
 		</tr>
 		<tr>
-		  <th>Last</th>
+		  <th>{{call .T "Last"}}</th>
 		  <td>{{.Last_Binary}}</td>
 		  <td>{{.Last_Decimal}}</td>
 		</tr>
 		<tr>
-		  <th>Total</th>
+		  <th>{{call .T "Wildcard"}}</th>
+		  <td>{{.Wildcard_Binary}}</td>
+		  <td>{{.Wildcard_Decimal}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Prefix length"}}</th>
+		  <td colspan="2">{{.Subnet.PrefixLength}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Usable hosts"}}</th>
+		  <td colspan="2">{{.Subnet.FirstUsable}} - {{.Subnet.LastUsable}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Total"}}</th>
 		  <td colspan="2">{{.Total}}</td>
 		</tr>
+		<tr>
+		  <th>{{call .T "Usable"}}</th>
+		  <td colspan="2">{{.Usable}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Class"}}</th>
+		  <td colspan="2">{{.Subnet.Class}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Scope"}}</th>
+		  <td colspan="2">{{.Subnet.Scope}}</td>
+		</tr>
 	  </table>
 	</p>
   </body>
@@ -94,38 +121,56 @@ const (
       <table>
         <tr>
 		  <th></th>
-		  <th>Binary</th>
-		  <th>Hex (Full)</th>
-		  <th>Hex (Shortened)</th>
+		  <th>{{call .T "Binary"}}</th>
+		  <th>{{call .T "Hex (Full)"}}</th>
+		  <th>{{call .T "Hex (Shortened)"}}</th>
 		</tr>
 		<tr>
-		  <th>Address</th>
+		  <th>{{call .T "Address"}}</th>
 		  <td>{{.Address_Binary}}</td>
 		  <td>{{.Address_Hex}}</td>
 		  <td>{{.Address_Short}}</td>
 		</tr>
 		<tr>
-		  <th>Mask</th>
+		  <th>{{call .T "Mask"}}</th>
 		  <td>{{.Mask_Binary}}</td>
 		  <td>{{.Mask_Hex}}</td>
 		  <td>{{.Mask_Short}}</td>
 		</tr>
 		<tr>
-		  <th>First</th>
+		  <th>{{call .T "First"}}</th>
 		  <td>{{.First_Binary}}</td>
 		  <td>{{.First_Hex}}</td>
 		  <td>{{.First_Short}}</td>
 		</tr>
 		<tr>
-		  <th>Last</th>
+		  <th>{{call .T "Last"}}</th>
 		  <td>{{.Last_Binary}}</td>
 		  <td>{{.Last_Hex}}</td>
 		  <td>{{.Last_Short}}</td>
 		</tr>
 		<tr>
-		  <th>Total</th>
+		  <th>{{call .T "Wildcard"}}</th>
+		  <td>{{.Wildcard_Binary}}</td>
+		  <td>{{.Wildcard_Hex}}</td>
+		  <td>{{.Wildcard_Short}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Prefix length"}}</th>
+		  <td colspan="3">{{.Subnet.PrefixLength}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Usable hosts"}}</th>
+		  <td colspan="3">{{.Subnet.FirstUsable}} - {{.Subnet.LastUsable}}</td>
+		</tr>
+		<tr>
+		  <th>{{call .T "Total"}}</th>
 		  <td colspan="3">{{.Total}}</td>
 		</tr>
+		<tr>
+		  <th>{{call .T "Scope"}}</th>
+		  <td colspan="3">{{.Subnet.Scope}}</td>
+		</tr>
 	  </table>
 	</p>
   </body>
@@ -133,105 +178,291 @@ const (
 `
 )
 
+var (
+	ErrInvalidCIDR = errors.New("not valid CIDR notation")
+	ErrInvalidMask = errors.New("not a valid netmask or wildcard mask")
+	ErrNotIPv4     = errors.New("not a valid IPv4 address")
+	ErrNotIPv6     = errors.New("not a valid IPv6 address")
+)
+
 type Template4 struct {
+	T                func(message.Reference, ...any) string
+	Version          string
+	Address_Binary   string
+	Address_Decimal  string
+	Mask_Binary      string
+	Mask_Decimal     string
+	First_Binary     string
+	First_Decimal    string
+	Last_Binary      string
+	Last_Decimal     string
+	Wildcard_Binary  string
+	Wildcard_Decimal string
+	Total            string
+	Usable           string
+	Subnet           Subnet
+}
+
+type Template6 struct {
+	T               func(message.Reference, ...any) string
 	Version         string
 	Address_Binary  string
-	Address_Decimal string
+	Address_Hex     string
+	Address_Short   string
 	Mask_Binary     string
-	Mask_Decimal    string
+	Mask_Hex        string
+	Mask_Short      string
 	First_Binary    string
-	First_Decimal   string
+	First_Hex       string
+	First_Short     string
 	Last_Binary     string
-	Last_Decimal    string
+	Last_Hex        string
+	Last_Short      string
+	Wildcard_Binary string
+	Wildcard_Hex    string
+	Wildcard_Short  string
 	Total           string
+	Subnet          Subnet
 }
 
-type Template6 struct {
-	Version        string
-	Address_Binary string
-	Address_Hex    string
-	Address_Short  string
-	Mask_Binary    string
-	Mask_Hex       string
-	Mask_Short     string
-	First_Binary   string
-	First_Hex      string
-	First_Short    string
-	Last_Binary    string
-	Last_Hex       string
-	Last_Short     string
-	Total          string
+// Subnet holds the details of a prefix common to both address families, and
+// is used for plain-text and JSON output.
+type Subnet struct {
+	Family       string `json:"family"`
+	Address      string `json:"address"`
+	Prefix       string `json:"prefix"`
+	PrefixLength int    `json:"prefix_length"`
+	Mask         string `json:"mask"`
+	Wildcard     string `json:"wildcard"`
+	Network      string `json:"network"`
+	Broadcast    string `json:"broadcast,omitempty"`
+	FirstUsable  string `json:"first_usable"`
+	LastUsable   string `json:"last_usable"`
+	Total        string `json:"total"`
+	Usable       string `json:"usable"`
+	Class        string `json:"class,omitempty"`
+	Scope        string `json:"scope"`
 }
 
-func toBinary(b []byte) string {
-	var s strings.Builder
+func (s Subnet) Fields(pr *message.Printer) [][2]string {
+	fields := [][2]string{
+		{"Family", s.Family},
+		{"Address", s.Address},
+		{"Prefix", s.Prefix},
+		{"Prefix length", strconv.Itoa(s.PrefixLength)},
+		{"Mask", s.Mask},
+		{"Wildcard", s.Wildcard},
+		{"Network", s.Network},
+	}
 
-	for i := range b {
-		s.WriteString(fmt.Sprintf("%08b", b[i]))
+	if s.Broadcast != "" {
+		fields = append(fields, [2]string{"Broadcast", s.Broadcast})
+	}
 
-		if i < (len(b) - 1) {
-			s.WriteString(" ")
-		}
+	fields = append(fields,
+		[2]string{"First usable", s.FirstUsable},
+		[2]string{"Last usable", s.LastUsable},
+		[2]string{"Total", localizeNumber(pr, s.Total)},
+		[2]string{"Usable", localizeNumber(pr, s.Usable)},
+	)
+
+	if s.Class != "" {
+		fields = append(fields, [2]string{"Class", s.Class})
 	}
 
-	return s.String()
+	return append(fields, [2]string{"Scope", s.Scope})
 }
 
-func subtract(a, b []byte) string {
-	var c, d, e big.Int
+// scopes lists the special-purpose address blocks from the IANA IPv4 and IPv6
+// Special-Purpose Address Registries, along with the multicast and global
+// unicast ranges. Blocks may nest, in which case the most specific applies.
+var scopes = []struct {
+	prefix netip.Prefix
+	name   string
+	rfc    string
+	global bool
+}{
+	{netip.MustParsePrefix("0.0.0.0/8"), "This network", "RFC 791", false},
+	{netip.MustParsePrefix("10.0.0.0/8"), "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("100.64.0.0/10"), "Shared Address Space", "RFC 6598", false},
+	{netip.MustParsePrefix("127.0.0.0/8"), "Loopback", "RFC 1122", false},
+	{netip.MustParsePrefix("169.254.0.0/16"), "Link-Local", "RFC 3927", false},
+	{netip.MustParsePrefix("172.16.0.0/12"), "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("192.0.0.0/24"), "IETF Protocol Assignments", "RFC 6890", false},
+	{netip.MustParsePrefix("192.0.0.0/29"), "IPv4 Service Continuity Prefix", "RFC 7335", false},
+	{netip.MustParsePrefix("192.0.0.9/32"), "Port Control Protocol Anycast", "RFC 7723", true},
+	{netip.MustParsePrefix("192.0.0.10/32"), "Traversal Using Relays around NAT Anycast", "RFC 8155", true},
+	{netip.MustParsePrefix("192.0.2.0/24"), "Documentation", "RFC 5737", false},
+	{netip.MustParsePrefix("192.31.196.0/24"), "AS112-v4", "RFC 7535", true},
+	{netip.MustParsePrefix("192.52.193.0/24"), "AMT", "RFC 7450", true},
+	{netip.MustParsePrefix("192.88.99.0/24"), "Deprecated (6to4 Relay Anycast)", "RFC 7526", false},
+	{netip.MustParsePrefix("192.168.0.0/16"), "Private-Use", "RFC 1918", false},
+	{netip.MustParsePrefix("192.175.48.0/24"), "Direct Delegation AS112 Service", "RFC 7534", true},
+	{netip.MustParsePrefix("198.18.0.0/15"), "Benchmarking", "RFC 2544", false},
+	{netip.MustParsePrefix("198.51.100.0/24"), "Documentation", "RFC 5737", false},
+	{netip.MustParsePrefix("203.0.113.0/24"), "Documentation", "RFC 5737", false},
+	{netip.MustParsePrefix("224.0.0.0/4"), "Multicast", "RFC 5771", false},
+	{netip.MustParsePrefix("255.255.255.255/32"), "Limited Broadcast", "RFC 8190", false},
+	{netip.MustParsePrefix("240.0.0.0/4"), "Reserved", "RFC 1112", false},
+	{netip.MustParsePrefix("::/128"), "Unspecified", "RFC 4291", false},
+	{netip.MustParsePrefix("::1/128"), "Loopback", "RFC 4291", false},
+	{netip.MustParsePrefix("::ffff:0:0/96"), "IPv4-mapped", "RFC 4291", false},
+	{netip.MustParsePrefix("64:ff9b::/96"), "IPv4-IPv6 Translation", "RFC 6052", true},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), "IPv4-IPv6 Translation", "RFC 8215", false},
+	{netip.MustParsePrefix("100::/64"), "Discard-Only", "RFC 6666", false},
+	{netip.MustParsePrefix("2001::/23"), "IETF Protocol Assignments", "RFC 2928", false},
+	{netip.MustParsePrefix("2001::/32"), "Teredo", "RFC 4380", false},
+	{netip.MustParsePrefix("2001:1::1/128"), "Port Control Protocol Anycast", "RFC 7723", true},
+	{netip.MustParsePrefix("2001:1::2/128"), "Traversal Using Relays around NAT Anycast", "RFC 8155", true},
+	{netip.MustParsePrefix("2001:2::/48"), "Benchmarking", "RFC 5180", false},
+	{netip.MustParsePrefix("2001:3::/32"), "AMT", "RFC 7450", true},
+	{netip.MustParsePrefix("2001:4:112::/48"), "AS112-v6", "RFC 7535", true},
+	{netip.MustParsePrefix("2001:20::/28"), "ORCHIDv2", "RFC 7343", true},
+	{netip.MustParsePrefix("2001:db8::/32"), "Documentation", "RFC 3849", false},
+	{netip.MustParsePrefix("2002::/16"), "6to4", "RFC 3056", false},
+	{netip.MustParsePrefix("2620:4f:8000::/48"), "Direct Delegation AS112 Service", "RFC 7534", true},
+	{netip.MustParsePrefix("3fff::/20"), "Documentation", "RFC 9637", false},
+	{netip.MustParsePrefix("5f00::/16"), "Segment Routing (SRv6) SIDs", "RFC 9602", false},
+	{netip.MustParsePrefix("fc00::/7"), "Unique-Local", "RFC 4193", false},
+	{netip.MustParsePrefix("fe80::/10"), "Link-Local Unicast", "RFC 4291", false},
+	{netip.MustParsePrefix("ff00::/8"), "Multicast", "RFC 4291", false},
+	{netip.MustParsePrefix("2000::/3"), "Global Unicast", "RFC 4291", true},
+}
 
-	c.SetBytes(a)
-	d.SetBytes(b)
+// lookupScope returns the most specific special-purpose block containing the
+// address, or false if there is none.
+func lookupScope(addr netip.Addr) (string, string, bool, bool) {
+	best := -1
 
-	comp := c.Cmp(&d)
-	switch comp {
-	case -1:
-		e.Sub(&d, &c)
-	case 0:
-		e = *big.NewInt(0)
-	case 1:
-		e.Sub(&c, &d)
+	for i, scope := range scopes {
+		if scope.prefix.Contains(addr) && (best == -1 || scope.prefix.Bits() > scopes[best].prefix.Bits()) {
+			best = i
+		}
 	}
 
-	return e.Add(&e, big.NewInt(1)).String()
+	if best == -1 {
+		return "", "", false, false
+	}
+
+	return scopes[best].name, scopes[best].rfc, scopes[best].global, true
 }
 
-func and(a, b []byte) (net.IP, error) {
-	if len(a) != len(b) {
-		return nil, fmt.Errorf("length %d does not equal length %d", len(a), len(b))
+// globallyReachable reports whether an address is globally reachable.
+// Addresses outside of every special-purpose block are only globally
+// reachable for IPv4, as unallocated IPv6 space is reserved.
+func globallyReachable(addr netip.Addr) bool {
+	_, _, global, ok := lookupScope(addr.Unmap())
+	if ok {
+		return global
 	}
 
-	result := make([]byte, len(a))
+	return addr.Unmap().Is4()
+}
+
+func addressScope(addr netip.Addr) string {
+	name, _, _, ok := lookupScope(addr)
+	if ok {
+		return name
+	}
 
-	for i := range a {
-		result[i] = a[i] & b[i]
+	if addr.Is4() {
+		return "Public"
 	}
 
-	return result, nil
+	return "Reserved"
 }
 
-func or(a, b []byte) (net.IP, error) {
-	if len(a) != len(b) {
-		return nil, fmt.Errorf("length %d does not equal length %d", len(a), len(b))
+func addressClass(addr netip.Addr) string {
+	first := addr.As4()[0]
+
+	switch {
+	case first < 128:
+		return "A"
+	case first < 192:
+		return "B"
+	case first < 224:
+		return "C"
+	case first < 240:
+		return "D (Multicast)"
+	default:
+		return "E (Reserved)"
 	}
+}
+
+// subnetAddresses returns the first and last addresses of a prefix, along
+// with its netmask and wildcard mask.
+func subnetAddresses(p netip.Prefix) (netip.Addr, netip.Addr, netip.Addr, netip.Addr) {
+	is4 := p.Addr().Is4()
+
+	first, last := prefixBounds(p)
+	hostBits := ones(p.Addr().BitLen() - p.Bits())
+
+	return first.toAddr(is4), last.toAddr(is4), hostBits.not().toAddr(is4), hostBits.toAddr(is4)
+}
+
+func describeSubnet(p netip.Prefix) Subnet {
+	firstAddr, lastAddr, mask, wildcard := subnetAddresses(p)
+
+	total := prefixSize(p)
+	usable := new(big.Int).Set(total)
+
+	subnet := Subnet{
+		Address:      p.Addr().String(),
+		Prefix:       p.Masked().String(),
+		PrefixLength: p.Bits(),
+		Mask:         mask.String(),
+		Wildcard:     wildcard.String(),
+		Network:      firstAddr.String(),
+		FirstUsable:  firstAddr.String(),
+		LastUsable:   lastAddr.String(),
+		Scope:        addressScope(firstAddr),
+	}
+
+	if p.Addr().Is4() {
+		subnet.Family = "IPv4"
+		subnet.Class = addressClass(firstAddr)
+
+		// Point-to-point (/31) and host (/32) routes have no network or
+		// broadcast addresses to exclude.
+		if p.Bits() <= 30 {
+			subnet.Broadcast = lastAddr.String()
+			subnet.FirstUsable = firstAddr.Next().String()
+			subnet.LastUsable = lastAddr.Prev().String()
+
+			usable.Sub(usable, big.NewInt(2))
+		}
+	} else {
+		subnet.Family = "IPv6"
+	}
+
+	subnet.Total = total.String()
+	subnet.Usable = usable.String()
 
-	result := make([]byte, len(a))
+	return subnet
+}
 
-	for i := range a {
-		result[i] = a[i] | b[i]
+// localizeNumber formats a decimal integer string for the printer's locale.
+func localizeNumber(pr *message.Printer, value string) string {
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return value
 	}
 
-	return result, nil
+	return groupDigits(pr, n)
 }
 
-func invert(b []byte) net.IP {
-	inverted := make([]byte, len(b))
+func toBinary(b []byte) string {
+	var s strings.Builder
 
 	for i := range b {
-		inverted[i] = b[i] ^ ((2 << 7) - 1)
+		s.WriteString(fmt.Sprintf("%08b", b[i]))
+
+		if i < (len(b) - 1) {
+			s.WriteString(" ")
+		}
 	}
 
-	return inverted
+	return s.String()
 }
 
 func toHex(b []byte) string {
@@ -246,72 +477,119 @@ func toHex(b []byte) string {
 		b[12], b[13], b[14], b[15])
 }
 
-func toDecimal(b []byte) string {
-	var s strings.Builder
-
-	for i := range b {
-		s.WriteString(fmt.Sprintf("%d", b[i]))
+func calculateV4Subnet(cidr string, pr *message.Printer) (Template4, error) {
+	p, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return Template4{}, ErrInvalidCIDR
+	}
 
-		if i != len(b)-1 {
-			s.WriteString(".")
-		}
+	if !p.Addr().Is4() {
+		return Template4{}, ErrNotIPv4
 	}
 
-	return s.String()
+	first, last, mask, wildcard := subnetAddresses(p)
+
+	subnet := describeSubnet(p)
+
+	return Template4{
+		T:                pr.Sprintf,
+		Version:          ReleaseVersion,
+		Address_Binary:   toBinary(p.Addr().AsSlice()),
+		Address_Decimal:  p.Addr().String(),
+		Mask_Binary:      toBinary(mask.AsSlice()),
+		Mask_Decimal:     mask.String(),
+		First_Binary:     toBinary(first.AsSlice()),
+		First_Decimal:    first.String(),
+		Last_Binary:      toBinary(last.AsSlice()),
+		Last_Decimal:     last.String(),
+		Wildcard_Binary:  toBinary(wildcard.AsSlice()),
+		Wildcard_Decimal: wildcard.String(),
+		Total:            localizeNumber(pr, subnet.Total),
+		Usable:           localizeNumber(pr, subnet.Usable),
+		Subnet:           subnet,
+	}, nil
 }
 
-func calculateV4Subnet(cidr string) (Template4, error) {
-	ip, net, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return Template4{}, errors.New("not valid CIDR notation")
-	}
+func serveV4Subnet(template *template.Template, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
 
-	as4 := ip.To4()
+		pr := localizer(r)
+
+		data, err := calculateV4Subnet(strings.TrimPrefix(p.ByName("v4"), "/"), pr)
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
 
-	if as4 == nil {
-		return Template4{}, errors.New("not a valid IPv6 address")
+		err = writeSubnet(w, r, template, data, data.Subnet)
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			return
+		}
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
+		}
 	}
+}
 
-	first, err := and(as4, net.Mask)
+func calculateV6Subnet(cidr string, pr *message.Printer) (Template6, error) {
+	p, err := netip.ParsePrefix(cidr)
 	if err != nil {
-		return Template4{}, err
+		return Template6{}, ErrInvalidCIDR
 	}
 
-	last, err := or(as4, invert(net.Mask))
-	if err != nil {
-		return Template4{}, err
+	if p.Addr().Is4() || p.Addr().Is4In6() {
+		return Template6{}, ErrNotIPv6
 	}
 
-	return Template4{
+	first, last, mask, wildcard := subnetAddresses(p)
+
+	subnet := describeSubnet(p)
+
+	return Template6{
+		T:               pr.Sprintf,
 		Version:         ReleaseVersion,
-		Address_Binary:  toBinary(as4),
-		Address_Decimal: toDecimal(as4),
-		Mask_Binary:     toBinary(net.Mask),
-		Mask_Decimal:    toDecimal(net.Mask),
-		First_Binary:    toBinary(first),
-		First_Decimal:   toDecimal(first),
-		Last_Binary:     toBinary(last),
-		Last_Decimal:    toDecimal(last),
-		Total:           subtract(first, last),
+		Address_Binary:  toBinary(p.Addr().AsSlice()),
+		Address_Hex:     toHex(p.Addr().AsSlice()),
+		Address_Short:   p.Addr().String(),
+		Mask_Binary:     toBinary(mask.AsSlice()),
+		Mask_Hex:        toHex(mask.AsSlice()),
+		Mask_Short:      "n/a",
+		First_Binary:    toBinary(first.AsSlice()),
+		First_Hex:       toHex(first.AsSlice()),
+		First_Short:     first.String(),
+		Last_Binary:     toBinary(last.AsSlice()),
+		Last_Hex:        toHex(last.AsSlice()),
+		Last_Short:      last.String(),
+		Wildcard_Binary: toBinary(wildcard.AsSlice()),
+		Wildcard_Hex:    toHex(wildcard.AsSlice()),
+		Wildcard_Short:  wildcard.String(),
+		Total:           localizeNumber(pr, subnet.Total),
+		Subnet:          subnet,
 	}, nil
 }
 
-func serveV4Subnet(template *template.Template, errorChannel chan<- Error) httprouter.Handle {
+func serveV6Subnet(template *template.Template, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
-		w.Header().Set("Content-Type", "text/html;charset=UTF-8")
-
-		securityHeaders(w)
+		pr := localizer(r)
 
-		data, err := calculateV4Subnet(strings.TrimPrefix(p.ByName("v4"), "/"))
+		data, err := calculateV6Subnet(strings.TrimPrefix(p.ByName("v6"), "/"), pr)
 		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			serveSubnetError(w, r, err, errorChannel)
 
 			return
 		}
 
-		err = template.Execute(w, data)
+		err = writeSubnet(w, r, template, data, data.Subnet)
 		if err != nil {
 			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
@@ -327,62 +605,128 @@ func serveV4Subnet(template *template.Template, errorChannel chan<- Error) httpr
 	}
 }
 
-func calculateV6Subnet(cidr string) (Template6, error) {
-	ip, net, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return Template6{}, errors.New("not valid CIDR notation")
+// maskBits returns the prefix length described by a netmask such as
+// 255.255.252.0, or by a wildcard mask such as 0.0.3.255. A mask with its
+// highest bit set is read as a netmask, and any other as a wildcard mask, so
+// 0.0.0.0 and 255.255.255.255 both describe a single address.
+func maskBits(mask netip.Addr) (int, error) {
+	width := mask.BitLen()
+
+	m := fromAddr(mask)
+
+	if m.and(uint128{0, 1}.lsh(width - 1)).isZero() {
+		if !m.and(m.add(uint128{0, 1})).isZero() {
+			return 0, ErrInvalidMask
+		}
+
+		return width - m.bitLength(), nil
 	}
 
-	as4 := ip.To4()
+	inverted := m.not().and(ones(width))
 
-	if as4 != nil {
-		return Template6{}, errors.New("not a valid IPv6 address")
+	if !inverted.and(inverted.add(uint128{0, 1})).isZero() {
+		return 0, ErrInvalidMask
 	}
 
-	first, err := and(ip, net.Mask)
-	if err != nil {
-		return Template6{}, err
+	return width - inverted.bitLength(), nil
+}
+
+// parseSubnet accepts CIDR notation, an address followed by a netmask or
+// wildcard mask, or a bare address, which is treated as a host route. The
+// address and mask may be separated by a slash, a space, or a comma.
+func parseSubnet(input string) (netip.Prefix, error) {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == '/' || r == ' ' || r == ','
+	})
+
+	if len(fields) < 1 || len(fields) > 2 {
+		return netip.Prefix{}, ErrInvalidCIDR
 	}
 
-	last, err := or(ip, invert(net.Mask))
-	if err != nil {
-		return Template6{}, err
+	addr, err := netip.ParseAddr(fields[0])
+	if err != nil || addr.Zone() != "" {
+		return netip.Prefix{}, ErrInvalidCIDR
 	}
 
-	return Template6{
-		Version:        ReleaseVersion,
-		Address_Binary: toBinary(ip),
-		Address_Hex:    toHex(ip),
-		Address_Short:  ip.String(),
-		Mask_Binary:    toBinary(net.Mask),
-		Mask_Hex:       toHex(net.Mask),
-		Mask_Short:     "n/a",
-		First_Binary:   toBinary(first),
-		First_Hex:      toHex(first),
-		First_Short:    first.String(),
-		Last_Binary:    toBinary(last),
-		Last_Hex:       toHex(last),
-		Last_Short:     last.String(),
-		Total:          subtract(first, last),
-	}, nil
+	bits := addr.BitLen()
+
+	if len(fields) == 2 {
+		if strings.Trim(fields[1], "0123456789") == "" {
+			bits, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return netip.Prefix{}, ErrInvalidCIDR
+			}
+		} else {
+			mask, err := netip.ParseAddr(fields[1])
+			if err != nil || mask.BitLen() != addr.BitLen() || mask.Is4In6() {
+				return netip.Prefix{}, ErrInvalidMask
+			}
+
+			bits, err = maskBits(mask)
+			if err != nil {
+				return netip.Prefix{}, err
+			}
+		}
+	}
+
+	// IPv4-mapped IPv6 addresses are described as the IPv4 address they hold.
+	if addr.Is4In6() {
+		if bits < 96 {
+			return netip.Prefix{}, ErrNotIPv6
+		}
+
+		addr, bits = addr.Unmap(), bits-96
+	}
+
+	p := netip.PrefixFrom(addr, bits)
+	if !p.IsValid() {
+		return netip.Prefix{}, ErrInvalidCIDR
+	}
+
+	return p, nil
 }
 
-func serveV6Subnet(template *template.Template, errorChannel chan<- Error) httprouter.Handle {
+// serveSubnet describes a prefix of either family, detecting which from the
+// address given.
+func serveSubnet(template4, template6 *template.Template, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
-		w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+		pr := localizer(r)
+
+		prefix, err := parseSubnet(p.ByName("subnet") + p.ByName("rest"))
+		if err != nil {
+			serveSubnetError(w, r, err, errorChannel)
+
+			return
+		}
+
+		var (
+			template *template.Template
+			data     any
+			subnet   Subnet
+		)
+
+		if prefix.Addr().Is4() {
+			var data4 Template4
 
-		securityHeaders(w)
+			data4, err = calculateV4Subnet(prefix.String(), pr)
 
-		data, err := calculateV6Subnet(strings.TrimPrefix(p.ByName("v6"), "/"))
+			template, data, subnet = template4, data4, data4.Subnet
+		} else {
+			var data6 Template6
+
+			data6, err = calculateV6Subnet(prefix.String(), pr)
+
+			template, data, subnet = template6, data6, data6.Subnet
+		}
 		if err != nil {
-			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			serveSubnetError(w, r, err, errorChannel)
 
 			return
 		}
 
-		err = template.Execute(w, data)
+		err = writeSubnet(w, r, template, data, subnet)
 		if err != nil {
 			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
@@ -398,6 +742,76 @@ func serveV6Subnet(template *template.Template, errorChannel chan<- Error) httpr
 	}
 }
 
+// routeSubnet dispatches each of the subnet tools, which share a single
+// route so that anything else can be passed to serveSubnet. Each tool
+// receives the remainder of the path under its own parameter name.
+func routeSubnet(template4, template6 *template.Template, errorChannel chan<- Error) httprouter.Handle {
+	tools := map[string]httprouter.Handle{
+		"v4":        serveV4Subnet(template4, errorChannel),
+		"v6":        serveV6Subnet(template6, errorChannel),
+		"split":     serveSubnetSplit(errorChannel),
+		"vlsm":      serveVLSM(errorChannel),
+		"contains":  serveSetOperation("contains", contains, errorChannel),
+		"overlap":   serveSetOperation("overlap", overlap, errorChannel),
+		"exclude":   serveSetOperation("exclude", exclude, errorChannel),
+		"intersect": serveSetOperation("intersect", intersect, errorChannel),
+		"range":     serveRange(errorChannel),
+		"reverse":   serveReverseZones(errorChannel),
+		"inspect":   serveIPv6Inspector(errorChannel),
+	}
+
+	subnet := serveSubnet(template4, template6, errorChannel)
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		tool := p.ByName("subnet")
+
+		handler, ok := tools[tool]
+		if !ok {
+			subnet(w, r, p)
+
+			return
+		}
+
+		handler(w, r, httprouter.Params{{Key: tool, Value: p.ByName("rest")}})
+	}
+}
+
+func serveSubnetError(w http.ResponseWriter, r *http.Request, err error, errorChannel chan<- Error) {
+	errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+	w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+	securityHeaders(w)
+
+	w.WriteHeader(http.StatusBadRequest)
+
+	_, err = w.Write([]byte(localizer(r).Sprintf("Invalid subnet requested") + "\n"))
+	if err != nil {
+		errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+	}
+}
+
+// writeSubnet renders a subnet as HTML, plain text or JSON, depending on the
+// format requested by the client.
+func writeSubnet(w http.ResponseWriter, r *http.Request, template *template.Template, data any, subnet Subnet) error {
+	securityHeaders(w)
+
+	switch outputFormat(r) {
+	case "json":
+		return writeJSON(w, subnet)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+		_, err := w.Write([]byte(formatFields(localizer(r), subnet.Fields(localizer(r)))))
+
+		return err
+	default:
+		w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+
+		return template.Execute(w, data)
+	}
+}
+
 func registerSubnetting(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
 	const module = "subnet"
 
@@ -416,13 +830,33 @@ func registerSubnetting(mux *httprouter.Router, usage *sync.Map, errorChannel ch
 	}
 
 	mux.GET("/subnet/", serveUsage(module, usage, errorChannel))
-	mux.GET("/subnet/v4/*v4", serveV4Subnet(template4, errorChannel))
-	mux.GET("/subnet/v6/*v6", serveV6Subnet(template6, errorChannel))
+	mux.GET("/subnet/:subnet", serveSubnet(template4, template6, errorChannel))
+	mux.GET("/subnet/:subnet/*rest", routeSubnet(template4, template6, errorChannel))
+	mux.POST("/subnet/aggregate", serveAggregate(errorChannel))
+	mux.POST("/subnet/free/*free", serveFreeSpace(errorChannel))
 
 	usage.Store(module, []string{
+		"/subnet/192.168.0.1/24",
+		"/subnet/10.0.0.1/255.255.252.0",
+		"/subnet/10.0.0.1/0.0.3.255",
+		"/subnet/2606:4700:a560::1",
 		"/subnet/v4/192.168.0.1/24",
 		"/subnet/v4/10.10.100.0/22",
+		"/subnet/v4/10.10.100.0/22?format=json",
 		"/subnet/v6/fdd8:0c61:bf60:590f::/64",
 		"/subnet/v6/2606:4700:a560::/48",
+		"/subnet/split/10.0.0.0/22/24",
+		"/subnet/split/2001:db8::/48/64?page=2&limit=16",
+		"/subnet/vlsm/10.0.0.0/24?hosts=100,50,20",
+		"/subnet/contains/10.0.0.0/8/10.20.30.40",
+		"/subnet/overlap/10.0.0.0/22/10.0.2.0/23",
+		"/subnet/exclude/10.0.0.0/24/10.0.0.64/26",
+		"/subnet/intersect/2001:db8::/32/2001:db8:1::/48",
+		"/subnet/range/10.0.0.5-10.0.0.100",
+		"/subnet/reverse/10.20.0.0/22",
+		"/subnet/reverse/192.0.2.64/26",
+		"/subnet/reverse/2001:db8::/47",
+		"/subnet/inspect/fe80::21b:63ff:fe84:a1b2",
+		"/subnet/inspect/2001:0:4136:e378:8000:63bf:3fff:fdd2",
 	})
 }
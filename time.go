@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/language"
 )
 
 var timeFormats = map[string]string{
@@ -630,93 +631,197 @@ func getTimeAbbrevations() *sync.Map {
 	return &retVal
 }
 
+func timeFormat(r *http.Request) string {
+	requestedFormat := r.URL.Query().Get("format")
+	if requestedFormat == "" {
+		requestedFormat = "RFC822"
+	}
+
+	for k, v := range timeFormats {
+		if strings.EqualFold(requestedFormat, k) {
+			return v
+		}
+	}
+
+	return timeFormats["RFC822"]
+}
+
+func lookupZones(location string, timeAbbrevations *sync.Map) ([]*time.Location, error) {
+	abbrev, exists := timeAbbrevations.Load(location)
+	if exists {
+		return abbrev.([]*time.Location), nil
+	}
+
+	tz, err := time.LoadLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*time.Location{tz}, nil
+}
+
+func formatTime(t time.Time, zones []*time.Location, format string, lang language.Tag) string {
+	var output strings.Builder
+
+	for i := 0; i < len(zones); i++ {
+		output.WriteString(localizeTime(t.In(zones[i]), format, lang) + "\n")
+	}
+
+	return output.String()
+}
+
 func serveTime(timeAbbrevations *sync.Map, errorChannel chan<- Error) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		startTime := time.Now()
 
-		var format string = ""
+		format := timeFormat(r)
 
-		requestedFormat := r.URL.Query().Get("format")
-		if requestedFormat == "" {
-			requestedFormat = "RFC822"
-		}
+		location := strings.TrimPrefix(p.ByName("time"), "/") + p.ByName("rest")
+
+		zones, err := lookupZones(location, timeAbbrevations)
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
-		for k, v := range timeFormats {
-			if strings.EqualFold(requestedFormat, k) {
-				format = v
+			w.WriteHeader(http.StatusBadRequest)
 
-				break
+			_, err = w.Write([]byte(localizer(r).Sprintf("Invalid timezone requested") + "\n"))
+			if err != nil {
+				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 			}
+
+			return
 		}
 
-		if format == "" {
-			format = timeFormats["RFC822"]
+		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+
+		securityHeaders(w)
+
+		if verbose {
+			fmt.Printf("%s | %s => %s\n",
+				startTime.Format(timeFormats["RFC3339"]),
+				realIP(r, true),
+				r.RequestURI)
 		}
 
-		adjustedStartTime := startTime
+		_, err = w.Write([]byte(formatTime(startTime, zones, format, requestLanguage(r))))
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 
-		location := strings.TrimPrefix(p.ByName("time"), "/") + p.ByName("rest")
+			return
+		}
+	}
+}
+
+func serveTimeStream(timeAbbrevations *sync.Map, errorChannel chan<- Error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		startTime := time.Now()
+
+		format := timeFormat(r)
 
-		zones := []*time.Location{}
+		lang := requestLanguage(r)
 
-		abbrev, exists := timeAbbrevations.Load(location)
-		if exists {
-			val := abbrev.([]*time.Location)
+		location := strings.TrimPrefix(p.ByName("time"), "/") + strings.TrimSuffix(p.ByName("rest"), "/stream")
 
-			zones = append(zones, val...)
-		} else {
-			tz, err := time.LoadLocation(location)
+		zones, err := lookupZones(location, timeAbbrevations)
+		if err != nil {
+			errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+
+			w.WriteHeader(http.StatusBadRequest)
+
+			_, err = w.Write([]byte(localizer(r).Sprintf("Invalid timezone requested") + "\n"))
 			if err != nil {
 				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+			}
+
+			return
+		}
+
+		interval := time.Second
 
+		if i := r.URL.Query().Get("interval"); i != "" {
+			interval, err = time.ParseDuration(i)
+			if err != nil || interval < time.Second || interval > time.Hour {
 				w.WriteHeader(http.StatusBadRequest)
 
-				_, err = w.Write([]byte("Invalid timezone requested\n"))
+				_, err = w.Write([]byte(localizer(r).Sprintf("Interval must be between 1s and 1h") + "\n"))
 				if err != nil {
 					errorChannel <- Error{err, realIP(r, true), r.URL.Path}
 				}
 
 				return
 			}
-
-			zones = append(zones, tz)
 		}
 
-		w.Header().Set("Content-Type", "text/plain;charset=UTF-8")
+		stream, done, err := openStream(w)
+		if err != nil {
+			serveStreamError(w, r, err, errorChannel)
 
-		securityHeaders(w)
+			return
+		}
+		defer done()
 
 		if verbose {
-			fmt.Printf("%s | %s => %s\n",
+			fmt.Printf("%s | %s => %s (stream opened)\n",
 				startTime.Format(timeFormats["RFC3339"]),
 				realIP(r, true),
 				r.RequestURI)
 		}
 
-		for i := 0; i < len(zones); i++ {
-			adjustedStartTime = adjustedStartTime.In(zones[i])
+		// Ticks are not replayed on reconnect, as a clock has nothing to
+		// catch up on, but event IDs are Unix timestamps so clients can
+		// tell how long they were disconnected.
+		tick := func(t time.Time) error {
+			return stream.Event(fmt.Sprintf("%d", t.Unix()), "tick", formatTime(t, zones, format, lang))
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
 
-			_, err := w.Write([]byte(adjustedStartTime.Format(format) + "\n"))
-			if err != nil {
-				errorChannel <- Error{err, realIP(r, true), r.URL.Path}
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		err = tick(time.Now())
 
+		for err == nil {
+			select {
+			case <-r.Context().Done():
 				return
+			case t := <-ticker.C:
+				err = tick(t)
+			case <-heartbeat.C:
+				err = stream.Heartbeat()
 			}
 		}
 	}
 }
 
+func routeTime(timeAbbrevations *sync.Map, errorChannel chan<- Error) httprouter.Handle {
+	clock := serveTime(timeAbbrevations, errorChannel)
+	stream := serveTimeStream(timeAbbrevations, errorChannel)
+
+	return func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		if strings.HasSuffix(p.ByName("rest"), "/stream") {
+			stream(w, r, p)
+
+			return
+		}
+
+		clock(w, r, p)
+	}
+}
+
 func registerTime(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
 	const module = "time"
 
 	timeAbbreviations := getTimeAbbrevations()
 
 	mux.GET("/time/:time", serveTime(timeAbbreviations, errorChannel))
-	mux.GET("/time/:time/*rest", serveTime(timeAbbreviations, errorChannel))
+	mux.GET("/time/:time/*rest", routeTime(timeAbbreviations, errorChannel))
 	mux.GET("/time/", serveUsage(module, usage, errorChannel))
 
 	usage.Store(module, []string{
 		"/time/America/Chicago",
+		"/time/America/Chicago/stream?format=kitchen",
 		"/time/EST",
 		"/time/UTC?format=kitchen",
 	})
@@ -0,0 +1,507 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	strategyFailover = "failover"
+	strategyFastest  = "fastest"
+)
+
+var (
+	ErrInvalidUpstream    = errors.New("invalid DNS resolver")
+	ErrInvalidDNSStrategy = errors.New("DNS strategy must be either failover or fastest")
+)
+
+// upstream is a single DNS server, reached over plain DNS, DNS-over-TLS or
+// DNS-over-HTTPS.
+type upstream struct {
+	// name is the upstream as it was configured, and is used to report
+	// which upstream answered a query.
+	name     string
+	protocol string
+
+	// address is the host and port dialed for plain DNS and DNS-over-TLS,
+	// and url the endpoint used for DNS-over-HTTPS.
+	address   string
+	url       string
+	tlsConfig *tls.Config
+	client    *http.Client
+}
+
+// splitUpstreamHost splits an optional port from a host, which may be an IPv6
+// address in brackets.
+func splitUpstreamHost(hostport, port string) (string, string) {
+	host, p, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return strings.Trim(hostport, "[]"), port
+	}
+
+	return host, p
+}
+
+// parseUpstream accepts an upstream as host:port for plain DNS, as
+// tls://host:port for DNS-over-TLS, or as an https:// URL for DNS-over-HTTPS.
+// Ports default to 53 and 853 respectively, and the DNS-over-HTTPS path to
+// /dns-query.
+func parseUpstream(s string) (*upstream, error) {
+	switch {
+	case strings.HasPrefix(s, "tls://"):
+		host, port := splitUpstreamHost(strings.TrimPrefix(s, "tls://"), "853")
+		if host == "" {
+			return nil, fmt.Errorf("%w %q", ErrInvalidUpstream, s)
+		}
+
+		// The certificate is checked against the host name, or against the
+		// address itself if an IP is given.
+		return &upstream{
+			name:     s,
+			protocol: "TLS",
+			address:  net.JoinHostPort(host, port),
+			tlsConfig: &tls.Config{
+				ServerName: host,
+				MinVersion: tls.VersionTLS12,
+			},
+		}, nil
+	case strings.HasPrefix(s, "https://"):
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("%w %q", ErrInvalidUpstream, s)
+		}
+
+		if u.Path == "" {
+			u.Path = "/dns-query"
+		}
+
+		return &upstream{
+			name:     s,
+			protocol: "HTTPS",
+			url:      u.String(),
+			client:   &http.Client{},
+		}, nil
+	case strings.Contains(s, "://"):
+		return nil, fmt.Errorf("%w %q", ErrInvalidUpstream, s)
+	}
+
+	host, port := splitUpstreamHost(s, "53")
+	if host == "" {
+		return nil, fmt.Errorf("%w %q", ErrInvalidUpstream, s)
+	}
+
+	return &upstream{
+		name:     s,
+		protocol: "UDP",
+		address:  net.JoinHostPort(host, port),
+	}, nil
+}
+
+// exchangeStream sends a query over a stream connection, using the two-byte
+// length prefix shared by TCP and DNS-over-TLS.
+func exchangeStream(conn net.Conn, query []byte, id uint16) (*dnsmessage.Message, error) {
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+
+	_, err := conn.Write(append(framed, query...))
+	if err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+
+	_, err = io.ReadFull(conn, length[:])
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg dnsmessage.Message
+
+	err = msg.Unpack(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.ID != id || !msg.Response {
+		return nil, ErrDNSResponse
+	}
+
+	return &msg, nil
+}
+
+func exchangeTLS(ctx context.Context, server string, config *tls.Config, query []byte, id uint16) (*dnsmessage.Message, error) {
+	d := tls.Dialer{Config: config}
+
+	conn, err := d.DialContext(ctx, "tcp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	return exchangeStream(conn, query, id)
+}
+
+func exchangeHTTPS(ctx context.Context, client *http.Client, endpoint string, query []byte, id uint16) (*dnsmessage.Message, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", dohMessageType)
+	req.Header.Set("Accept", dohMessageType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP status %d", ErrDNSResponse, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDoHMessageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var msg dnsmessage.Message
+
+	err = msg.Unpack(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.ID != id || !msg.Response {
+		return nil, ErrDNSResponse
+	}
+
+	return &msg, nil
+}
+
+// exchange sends a query to the upstream, returning the response and the
+// protocol it was received over. Plain DNS queries are sent over UDP unless
+// tcp is set, and repeated over TCP if the response was truncated.
+func (u *upstream) exchange(ctx context.Context, query []byte, id uint16, tcp bool) (*dnsmessage.Message, string, error) {
+	switch u.protocol {
+	case "TLS":
+		msg, err := exchangeTLS(ctx, u.address, u.tlsConfig, query, id)
+
+		return msg, u.protocol, err
+	case "HTTPS":
+		msg, err := exchangeHTTPS(ctx, u.client, u.url, query, id)
+
+		return msg, u.protocol, err
+	}
+
+	if !tcp {
+		msg, err := exchangeUDP(ctx, u.address, query, id)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !msg.Truncated {
+			return msg, "UDP", nil
+		}
+	}
+
+	msg, err := exchangeTCP(ctx, u.address, query, id)
+
+	return msg, "TCP", err
+}
+
+// upstreamSet holds the configured upstreams, and the strategy used to pick
+// between them.
+type upstreamSet struct {
+	upstreams []*upstream
+	strategy  string
+
+	// system is set when no upstreams were configured, and the first
+	// nameserver from the system configuration is used instead.
+	system bool
+}
+
+// newUpstreamSet parses the upstreams set with --dns-resolver, falling back
+// to the system's nameserver if there are none.
+func newUpstreamSet(addresses []string, strategy string) (*upstreamSet, error) {
+	set := &upstreamSet{strategy: strategy}
+
+	if len(addresses) == 0 {
+		set.system = true
+
+		addresses = []string{dnsServer()}
+	}
+
+	for _, address := range addresses {
+		u, err := parseUpstream(strings.TrimSpace(address))
+		if err != nil {
+			return nil, err
+		}
+
+		set.upstreams = append(set.upstreams, u)
+	}
+
+	return set, nil
+}
+
+func (s *upstreamSet) String() string {
+	names := make([]string, len(s.upstreams))
+
+	for i, u := range s.upstreams {
+		names[i] = u.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// upstreamReply is a response along with the upstream that sent it.
+type upstreamReply struct {
+	message  *dnsmessage.Message
+	protocol string
+	server   string
+	err      error
+}
+
+// exchange sends a query using the configured strategy. With failover, each
+// upstream is tried in order until one responds. With fastest, the query is
+// sent to every upstream at once and the first response is used.
+func (s *upstreamSet) exchange(ctx context.Context, query []byte, id uint16, tcp bool) upstreamReply {
+	var reply upstreamReply
+
+	if s.strategy == strategyFastest && len(s.upstreams) > 1 {
+		reply = s.fastest(ctx, query, id, tcp)
+	} else {
+		reply = s.failover(ctx, query, id, tcp)
+	}
+
+	if reply.err == nil {
+		recordUpstream(ctx, reply.server)
+	}
+
+	return reply
+}
+
+func (s *upstreamSet) failover(ctx context.Context, query []byte, id uint16, tcp bool) upstreamReply {
+	var errs []error
+
+	for i, u := range s.upstreams {
+		// The remaining time is shared between the upstreams not yet tried,
+		// so that one which never responds can't hold up the rest.
+		remaining := dnsQueryTimeout
+
+		if deadline, ok := ctx.Deadline(); ok {
+			remaining = time.Until(deadline)
+		}
+
+		attempt, cancel := context.WithTimeout(ctx, remaining/time.Duration(len(s.upstreams)-i))
+
+		msg, protocol, err := u.exchange(attempt, query, id, tcp)
+
+		cancel()
+
+		if err == nil {
+			return upstreamReply{msg, protocol, u.name, nil}
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", u.name, err))
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return upstreamReply{err: errors.Join(errs...)}
+}
+
+func (s *upstreamSet) fastest(ctx context.Context, query []byte, id uint16, tcp bool) upstreamReply {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	replies := make(chan upstreamReply, len(s.upstreams))
+
+	for _, u := range s.upstreams {
+		go func() {
+			msg, protocol, err := u.exchange(ctx, query, id, tcp)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", u.name, err)
+			}
+
+			replies <- upstreamReply{msg, protocol, u.name, err}
+		}()
+	}
+
+	var errs []error
+
+	for range s.upstreams {
+		reply := <-replies
+		if reply.err == nil {
+			return reply
+		}
+
+		errs = append(errs, reply.err)
+	}
+
+	return upstreamReply{err: errors.Join(errs...)}
+}
+
+type answeredByKey struct{}
+
+// answeredBy records which upstreams answered the queries made while
+// handling a request, so that they can be included in the verbose log.
+type answeredBy struct {
+	mu      sync.Mutex
+	servers []string
+}
+
+// withAnsweredBy returns a context in which the upstreams answering queries
+// are recorded.
+func withAnsweredBy(ctx context.Context) (context.Context, *answeredBy) {
+	a := &answeredBy{}
+
+	return context.WithValue(ctx, answeredByKey{}, a), a
+}
+
+func recordUpstream(ctx context.Context, server string) {
+	a, ok := ctx.Value(answeredByKey{}).(*answeredBy)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !slices.Contains(a.servers, server) {
+		a.servers = append(a.servers, server)
+	}
+}
+
+// String lists the upstreams as a suffix for the verbose log, or returns
+// nothing if no query was answered by one.
+func (a *answeredBy) String() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.servers) == 0 {
+		return ""
+	}
+
+	return " (via " + strings.Join(a.servers, ", ") + ")"
+}
+
+// upstreamConn lets the standard library's resolver send its queries through
+// an upstreamSet. It presents itself as a stream connection, so each query
+// arrives with a length prefix and the response is returned with one.
+type upstreamConn struct {
+	ctx      context.Context
+	set      *upstreamSet
+	mu       sync.Mutex
+	deadline time.Time
+	query    []byte
+	response bytes.Reader
+	err      error
+}
+
+func (c *upstreamConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.query = append(c.query, b...)
+
+	if len(c.query) < 2 || len(c.query) < 2+int(binary.BigEndian.Uint16(c.query)) {
+		return len(b), nil
+	}
+
+	query := c.query[2:]
+
+	c.query = nil
+
+	if len(query) < 2 {
+		return 0, ErrDNSResponse
+	}
+
+	ctx := c.ctx
+
+	if !c.deadline.IsZero() {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+
+	reply := c.set.exchange(ctx, query, binary.BigEndian.Uint16(query), false)
+	if reply.err != nil {
+		c.err = reply.err
+
+		return len(b), nil
+	}
+
+	packed, err := reply.message.Pack()
+	if err != nil {
+		c.err = err
+
+		return len(b), nil
+	}
+
+	c.response.Reset(append(binary.BigEndian.AppendUint16(nil, uint16(len(packed))), packed...))
+
+	return len(b), nil
+}
+
+func (c *upstreamConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.response.Read(b)
+}
+
+func (c *upstreamConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+
+	return nil
+}
+
+func (c *upstreamConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *upstreamConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+func (c *upstreamConn) Close() error                       { return nil }
+func (c *upstreamConn) LocalAddr() net.Addr                { return upstreamAddr{} }
+func (c *upstreamConn) RemoteAddr() net.Addr               { return upstreamAddr{} }
+
+type upstreamAddr struct{}
+
+func (upstreamAddr) Network() string { return "dns" }
+func (upstreamAddr) String() string  { return "upstream" }
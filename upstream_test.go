@@ -0,0 +1,191 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// closedAddress returns a local UDP address with nothing listening on it.
+func closedAddress(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := conn.LocalAddr().String()
+
+	conn.Close()
+
+	return addr
+}
+
+func TestParseUpstream(t *testing.T) {
+	tests := []struct {
+		upstream string
+		protocol string
+		address  string
+		url      string
+	}{
+		{"192.0.2.1", "UDP", "192.0.2.1:53", ""},
+		{"192.0.2.1:5353", "UDP", "192.0.2.1:5353", ""},
+		{"[2001:db8::1]", "UDP", "[2001:db8::1]:53", ""},
+		{"2001:db8::1", "UDP", "[2001:db8::1]:53", ""},
+		{"tls://dns.example", "TLS", "dns.example:853", ""},
+		{"tls://[2001:db8::1]:8853", "TLS", "[2001:db8::1]:8853", ""},
+		{"https://dns.example", "HTTPS", "", "https://dns.example/dns-query"},
+		{"https://dns.example/resolve", "HTTPS", "", "https://dns.example/resolve"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.upstream, func(t *testing.T) {
+			u, err := parseUpstream(test.upstream)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u.name != test.upstream || u.protocol != test.protocol || u.address != test.address || u.url != test.url {
+				t.Errorf("got %s %s %q %q, want %s %s %q %q",
+					u.name, u.protocol, u.address, u.url,
+					test.upstream, test.protocol, test.address, test.url)
+			}
+		})
+	}
+
+	for _, upstream := range []string{"", "tls://", "https://", "quic://dns.example"} {
+		t.Run(upstream, func(t *testing.T) {
+			_, err := parseUpstream(upstream)
+			if !errors.Is(err, ErrInvalidUpstream) {
+				t.Errorf("got %v, want %v", err, ErrInvalidUpstream)
+			}
+		})
+	}
+}
+
+func TestUpstreamSetExchange(t *testing.T) {
+	for _, strategy := range []string{strategyFailover, strategyFastest} {
+		t.Run(strategy, func(t *testing.T) {
+			stub := newStubServer(t, false)
+
+			req := dnsRequest{
+				server: newTestUpstreams(t, strategy, closedAddress(t), stub.addr),
+				name:   "example.com",
+				qtype:  dnsmessage.TypeA,
+			}
+
+			reply, err := req.exchange(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if reply.server != stub.addr {
+				t.Errorf("server = %s, want %s", reply.server, stub.addr)
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		closed := closedAddress(t)
+
+		req := dnsRequest{
+			server: newTestUpstreams(t, strategyFailover, closed),
+			name:   "example.com",
+			qtype:  dnsmessage.TypeA,
+		}
+
+		_, err := req.exchange(context.Background())
+		if err == nil {
+			t.Fatalf("exchange with %s succeeded", closed)
+		}
+	})
+}
+
+func TestResolver(t *testing.T) {
+	for _, truncate := range []bool{false, true} {
+		stub := newStubServer(t, truncate)
+
+		resolver := newResolver(newTestUpstreams(t, strategyFailover, stub.addr))
+
+		ctx, answered := withAnsweredBy(context.Background())
+
+		addrs, err := resolver.LookupHost(ctx, "example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(addrs, []string{"192.0.2.1"}) {
+			t.Errorf("truncate %t: got %v, want [192.0.2.1]", truncate, addrs)
+		}
+
+		if got, want := answered.String(), " (via "+stub.addr+")"; got != want {
+			t.Errorf("answered by %q, want %q", got, want)
+		}
+
+		if truncate && stub.tcp.Load() == 0 {
+			t.Error("truncated responses were not retried over TCP")
+		}
+	}
+}
+
+// TestUpstreamConnPartialWrites checks that a query written in pieces is
+// only sent once it is complete.
+func TestUpstreamConnPartialWrites(t *testing.T) {
+	stub := newStubServer(t, false)
+
+	query, id, err := newQuery(dnsRequest{name: "example.com", qtype: dnsmessage.TypeA})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := &upstreamConn{ctx: context.Background(), set: newTestUpstreams(t, strategyFailover, stub.addr)}
+
+	framed := append(binary.BigEndian.AppendUint16(nil, uint16(len(query))), query...)
+
+	for _, part := range [][]byte{framed[:1], framed[1:5], framed[5:]} {
+		n, err := conn.Write(part)
+		if err != nil || n != len(part) {
+			t.Fatalf("Write = %d, %v, want %d", n, err, len(part))
+		}
+	}
+
+	if got := stub.udp.Load(); got != 1 {
+		t.Errorf("server received %d queries, want 1", got)
+	}
+
+	var length [2]byte
+
+	_, err = io.ReadFull(conn, length[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+
+	_, err = io.ReadFull(conn, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg dnsmessage.Message
+
+	err = msg.Unpack(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.ID != id || len(msg.Answers) != 1 {
+		t.Errorf("got ID %d with %d answers, want ID %d with 1 answer", msg.ID, len(msg.Answers), id)
+	}
+}
@@ -6,16 +6,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/text/message"
 )
 
 type Error struct {
@@ -35,6 +39,59 @@ func securityHeaders(w http.ResponseWriter) {
 	w.Header().Set("X-Xss-Protection", "1; mode=block")
 }
 
+// outputFormat returns the format requested via the ?format= query parameter,
+// falling back to the Accept header, and then to HTML for browsers and plain
+// text for everything else.
+func outputFormat(r *http.Request) string {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "json":
+		return "json"
+	case "text", "txt":
+		return "text"
+	case "html":
+		return "html"
+	}
+
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	case strings.Contains(r.Header.Get("User-Agent"), "Mozilla/"):
+		return "html"
+	default:
+		return "text"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(v)
+}
+
+// formatFields renders label/value pairs as aligned lines of plain text,
+// translating each label for the client.
+func formatFields(pr *message.Printer, fields [][2]string) string {
+	labels := make([]string, len(fields))
+
+	width := 0
+
+	for i, field := range fields {
+		labels[i] = pr.Sprintf(field[0]) + ":"
+
+		width = max(width, utf8.RuneCountInString(labels[i]))
+	}
+
+	var output strings.Builder
+
+	for i, field := range fields {
+		output.WriteString(fmt.Sprintf("%s%s %s\n", labels[i], strings.Repeat(" ", width-utf8.RuneCountInString(labels[i])), field[1]))
+	}
+
+	return output.String()
+}
+
 func serverError(w http.ResponseWriter, r *http.Request, i any) {
 	if verbose {
 		fmt.Printf("%s | %s => %s (Invalid request)\n",
@@ -81,9 +138,11 @@ func servePage() error {
 
 	mux.PanicHandler = serverErrorHandler()
 
+	modules := &Modules{}
+
 	srv := &http.Server{
 		Addr:         net.JoinHostPort(bind, strconv.Itoa(int(port))),
-		Handler:      mux,
+		Handler:      modules.Gate(mux),
 		IdleTimeout:  10 * time.Minute,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 5 * time.Minute,
@@ -113,60 +172,90 @@ func servePage() error {
 		}
 	}()
 
-	usage := sync.Map{}
-
-	if dns || all {
-		registerDNS(mux, &usage, errorChannel)
+	err := initializeLimits()
+	if err != nil {
+		return err
 	}
 
-	if hashing || all {
-		registerHash(mux, &usage, errorChannel)
+	asnBackend, err := newASNBackend(errorChannel)
+	if err != nil {
+		return err
 	}
 
-	if httpStatus || all {
-		registerHTTPStatus(mux, &usage, errorChannel)
+	upstreams, err := newUpstreamSet(dnsResolvers, dnsStrategy)
+	if err != nil {
+		return err
 	}
 
-	if ip || all {
-		registerIP(mux, &usage, errorChannel)
+	comparedResolvers, err := parseComparedResolvers(dnsCompare)
+	if err != nil {
+		return err
 	}
 
-	if mac || all {
-		registerMAC(mux, &usage, errorChannel)
+	rootHints, err := parseRootHints(dnsRootHints)
+	if err != nil {
+		return err
 	}
 
-	if profile {
-		registerProfile(mux, &usage)
+	trustAnchors, err := loadTrustAnchors(dnsAnchors)
+	if err != nil {
+		return err
 	}
 
-	if qr || all {
-		registerQR(mux, &usage, errorChannel)
-	}
+	usage := sync.Map{}
 
-	if roll || all {
-		registerRoll(mux, &usage, errorChannel)
+	for _, module := range []struct {
+		name     string
+		enabled  bool
+		register func(*httprouter.Router, *sync.Map, chan<- Error)
+	}{
+		{"asn", asnLookups, func(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
+			registerASN(mux, usage, asnBackend, errorChannel)
+		}},
+		{"dns", dns, func(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
+			registerDNS(mux, usage, asnBackend, upstreams, comparedResolvers, rootHints, trustAnchors, errorChannel)
+		}},
+		{"hash", hashing, registerHash},
+		{"http", httpStatus, registerHTTPStatus},
+		{"ip", ip, func(mux *httprouter.Router, usage *sync.Map, errorChannel chan<- Error) {
+			registerIP(mux, usage, asnBackend, upstreams, modules, errorChannel)
+		}},
+		{"mac", mac, registerMAC},
+		{"qr", qr, registerQR},
+		{"roll", roll, registerRoll},
+		{"subnet", subnet, registerSubnetting},
+		{"time", timezones, registerTime},
+		{"whoami", whoami, registerWhoAmI},
+	} {
+		// With the admin API available, every module is registered so
+		// that it can be toggled at runtime without a restart.
+		if module.enabled || all || adminToken != "" {
+			module.register(mux, &usage, errorChannel)
+
+			modules.Register(module.name, module.enabled || all)
+		}
 	}
 
-	if subnet || all {
-		registerSubnetting(mux, &usage, errorChannel)
+	if batch || all || adminToken != "" {
+		registerBatch(mux, srv.Handler, modules, errorChannel)
+
+		modules.Register("batch", batch || all)
 	}
 
-	if timezones || all {
-		registerTime(mux, &usage, errorChannel)
+	if profile {
+		registerProfile(mux, &usage)
 	}
 
-	if whoami || all {
-		registerWhoAmI(mux, &usage, errorChannel)
+	if adminToken != "" {
+		registerAdmin(mux, modules, errorChannel)
 	}
 
 	registerVersion(mux, &usage, errorChannel)
 
-	registerHelp(mux, &usage, errorChannel)
+	registerHelp(mux, &usage, modules, errorChannel)
 
 	registerCss(mux, errorChannel)
 
-	var err error
-
 	if verbose {
 		if tlsKey != "" && tlsCert != "" {
 			fmt.Printf("%s | Listening on https://%s/\n",